@@ -0,0 +1,285 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// RotateKeySetsRequest activates the keysets of DerivationPath
+type RotateKeySetsRequest struct {
+	DerivationPath string `json:"derivation_path"`
+}
+
+// ResolveMeltRequest resolves an interrupted melt. Paid must be the status of the payment at the lightning backend.
+type ResolveMeltRequest struct {
+	Paid *bool `json:"paid"`
+}
+
+// BalanceResponse is the liquidity of the lightning backend in satoshi
+type BalanceResponse struct {
+	Inbound  uint64 `json:"inbound"`
+	Outbound uint64 `json:"outbound"`
+}
+
+// ReloadResponse lists the changed settings, which are only applied after a restart
+type ReloadResponse struct {
+	RestartRequired []string `json:"restart_required"`
+}
+
+// appendAdminHandler will append the routes for mint operators to the router
+func appendAdminHandler(router *mux.Router, a *Api) {
+	// route to get the liability and reserve audit report
+	router.HandleFunc("/admin/audit", Use(a.getAudit, AdminMiddleware, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	// routes to list and rotate keysets
+	router.HandleFunc("/admin/keysets", Use(a.getAdminKeySets, AdminMiddleware, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/keysets/rotate", Use(a.rotateKeySets, AdminMiddleware, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
+	// routes to list and resolve pending melts
+	router.HandleFunc("/admin/melts", Use(a.getPendingMelts, AdminMiddleware, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/melts/{id}/resolve", Use(a.resolveMelt, AdminMiddleware, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
+	// route to get the balance of the lightning backend
+	router.HandleFunc("/admin/balance", Use(a.getBalance, AdminMiddleware, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	// route to reload the configuration file
+	router.HandleFunc("/admin/reload", Use(a.reload, AdminMiddleware, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
+	// route to scrape prometheus metrics. metrics reveal the volume of the mint and require the admin token as well.
+	router.HandleFunc("/metrics", Use(promhttp.Handler().ServeHTTP, AdminMiddleware, RecoveryMiddleware)).Methods(http.MethodGet)
+}
+
+// AdminMiddleware will only pass requests with the configured admin token as bearer token
+func AdminMiddleware(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		adminToken := currentConfig().Mint.AdminToken
+		if adminToken == "" || token == authorization ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			responseError(w, cashu.NewErrorResponse(cashu.NewError(cashu.ErrCodeUnauthorized, "invalid admin token")))
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+}
+
+// responseJson writes v as json response
+func responseJson(w http.ResponseWriter, v interface{}) {
+	res, err := json.Marshal(v)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.Write(res)
+}
+
+// getAudit is the http handler function for GET /admin/audit
+// @Summary Audit
+// @Description Reports the outstanding ecash of every keyset and compares it with the lightning backend balance.
+// @Produce  json
+// @Success 200 {object} mint.AuditReport
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/audit [get]
+// @Tags ADMIN
+func (api Api) getAudit(w http.ResponseWriter, r *http.Request) {
+	report, err := api.Mint.Audit()
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	responseJson(w, report)
+}
+
+// getAdminKeySets is the http handler function for GET /admin/keysets
+// @Summary Keysets
+// @Description Lists all keysets of the mint including retired keysets.
+// @Produce  json
+// @Success 200 {array} mint.KeySetStatus
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/keysets [get]
+// @Tags ADMIN
+func (api Api) getAdminKeySets(w http.ResponseWriter, r *http.Request) {
+	keySets, err := api.Mint.KeySets()
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	responseJson(w, keySets)
+}
+
+// rotateKeySets is the http handler function for POST /admin/keysets/rotate
+// @Summary Rotate keysets
+// @Description Activates the keysets of the derivation path for all configured units. The previous keysets are retired on restart and expire after the grace period.
+// @Description The derivation path has to be configured, to keep the keysets active after a restart.
+// @Produce  json
+// @Success 200 {array} mint.KeySetStatus
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/keysets/rotate [post]
+// @Param RotateKeySetsRequest body RotateKeySetsRequest true "Model containing the new derivation path"
+// @Tags ADMIN
+func (api Api) rotateKeySets(w http.ResponseWriter, r *http.Request) {
+	payload := RotateKeySetsRequest{}
+	if err := decodeRequest(w, r, &payload); err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	if payload.DerivationPath == "" {
+		responseError(w, cashu.NewErrorResponse(cashu.NewError(cashu.ErrCodeBadRequest, "derivation path is required")))
+		return
+	}
+	if err := api.rotateConfiguredKeySets(payload.DerivationPath); err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	api.getAdminKeySets(w, r)
+}
+
+// rotateConfiguredKeySets rotates the keysets of the configured units and updates the configured derivation path
+func (api Api) rotateConfiguredKeySets(derivationPath string) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if err := api.Mint.RotateKeySets(derivationPath, Config.Mint.Units...); err != nil {
+		return err
+	}
+	if Config.Mint.DerivationPath != derivationPath {
+		log.WithField("derivation_path", derivationPath).
+			Warn("keysets were rotated. configure the derivation path to keep the keysets active after a restart")
+		Config.Mint.DerivationPath = derivationPath
+	}
+	return nil
+}
+
+// getPendingMelts is the http handler function for GET /admin/melts
+// @Summary Pending melts
+// @Description Lists all pending melts. Melts, which are not in flight, were interrupted and have to be resolved.
+// @Produce  json
+// @Success 200 {array} cashu.PendingMelt
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/melts [get]
+// @Tags ADMIN
+func (api Api) getPendingMelts(w http.ResponseWriter, r *http.Request) {
+	melts, err := api.Mint.PendingMelts()
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	responseJson(w, melts)
+}
+
+// resolveMelt is the http handler function for POST /admin/melts/{id}/resolve
+// @Summary Resolve melt
+// @Description Spends the proofs of an interrupted melt, if its payment was paid. Otherwise, the proofs are released.
+// @Produce  json
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/melts/{id}/resolve [post]
+// @Param ResolveMeltRequest body ResolveMeltRequest true "Model containing the payment status"
+// @Tags ADMIN
+func (api Api) resolveMelt(w http.ResponseWriter, r *http.Request) {
+	payload := ResolveMeltRequest{}
+	if err := decodeRequest(w, r, &payload); err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	if payload.Paid == nil {
+		responseError(w, cashu.NewErrorResponse(cashu.NewError(cashu.ErrCodeBadRequest, "payment status is required")))
+		return
+	}
+	if err := api.Mint.ResolveMelt(mux.Vars(r)["id"], *payload.Paid); err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// getBalance is the http handler function for GET /admin/balance
+// @Summary Balance
+// @Description Get the liquidity of the lightning backend in satoshi.
+// @Produce  json
+// @Success 200 {object} BalanceResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /admin/balance [get]
+// @Tags ADMIN
+func (api Api) getBalance(w http.ResponseWriter, r *http.Request) {
+	liquidity, err := api.Mint.Liquidity()
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	responseJson(w, BalanceResponse{Inbound: liquidity.Inbound / 1000, Outbound: liquidity.Outbound / 1000})
+}
+
+// reload is the http handler function for POST /admin/reload
+// @Summary Reload configuration
+// @Description Applies the configuration file to the running mint and lists the settings, which require a restart.
+// @Produce  json
+// @Success 200 {object} ReloadResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/reload [post]
+// @Tags ADMIN
+func (api Api) reload(w http.ResponseWriter, r *http.Request) {
+	restart, err := api.Reload()
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	responseJson(w, ReloadResponse{RestartRequired: restart})
+}
+
+// Reload applies the configuration file to the running mint.
+// Keysets, exchange rates and the admin token are applied. The changed settings, which require a restart, are returned.
+// Changes of the lightning, database and onchain sections are only applied after a restart.
+func (api Api) Reload() ([]string, error) {
+	c, err := loadConfigurationFile()
+	if err != nil {
+		return nil, err
+	}
+	restart, err := changedSections()
+	if err != nil {
+		return nil, err
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	if c.Mint.DerivationPath != Config.Mint.DerivationPath || !reflect.DeepEqual(c.Mint.Units, Config.Mint.Units) {
+		if err = api.Mint.RotateKeySets(c.Mint.DerivationPath, c.Mint.Units...); err != nil {
+			return nil, err
+		}
+	}
+	for setting, changed := range map[string]bool{
+		"private_key":       c.Mint.PrivateKey != Config.Mint.PrivateKey,
+		"host":              c.Mint.Host != Config.Mint.Host,
+		"port":              c.Mint.Port != Config.Mint.Port,
+		"tls":               c.Mint.Tls != Config.Mint.Tls,
+		"log_level":         c.LogLevel != Config.LogLevel,
+		"archive_interval":  c.Mint.ArchiveInterval != Config.Mint.ArchiveInterval,
+		"lightning_address": c.Mint.LightningAddress != Config.Mint.LightningAddress,
+		// the grace period is applied to keysets, which are retired on startup
+		"keyset_grace_period": c.Mint.KeySetGracePeriod != Config.Mint.KeySetGracePeriod,
+	} {
+		if changed {
+			restart = append(restart, setting)
+		}
+	}
+	sort.Strings(restart)
+	// settings, which require a restart, keep their current value
+	c.Mint.PrivateKey = Config.Mint.PrivateKey
+	c.Mint.Host, c.Mint.Port, c.Mint.Tls = Config.Mint.Host, Config.Mint.Port, Config.Mint.Tls
+	c.LogLevel, c.Mint.ArchiveInterval, c.Mint.LightningAddress = Config.LogLevel, Config.Mint.ArchiveInterval, Config.Mint.LightningAddress
+	c.Mint.KeySetGracePeriod = Config.Mint.KeySetGracePeriod
+	ratesChanged := !reflect.DeepEqual(c.Mint.ExchangeRate, Config.Mint.ExchangeRate)
+	Config = c
+	if ratesChanged {
+		api.Mint.SetRateProvider(newRateProvider())
+	}
+	log.WithField("restart_required", restart).Info("reloaded configuration")
+	return restart, nil
+}
@@ -5,9 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/onchain"
 	"github.com/jinzhu/configor"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"reflect"
+	"sync"
 )
 
 type ServerConfiguration struct {
@@ -29,11 +34,43 @@ type Configuration struct {
 			KeyFile  string `json:"key_path" yaml:"key_path"`
 			CertFile string `json:"cert_path" yaml:"cert_path"`
 		} `json:"tls" yaml:"tls"`
+		// Units are additional keyset units (e.g. msat or usd). The sat keyset is always active.
+		Units []string `json:"units" yaml:"units"`
+		// ExchangeRate prices mints and melts of fiat units
+		ExchangeRate struct {
+			// Provider of exchange rates (fixed or kraken)
+			Provider string `json:"provider" yaml:"provider"`
+			// Rates in satoshi per unit for the fixed provider
+			Rates map[string]float64 `json:"rates" yaml:"rates"`
+		} `json:"exchange_rate" yaml:"exchange_rate"`
+		// ArchiveInterval in seconds. Spent proofs of expired keysets are archived in this interval (0 disables the archiver).
+		ArchiveInterval  int `json:"archive_interval" yaml:"archive_interval"`
+		LightningAddress struct {
+			Enabled bool   `json:"enabled" yaml:"enabled"`
+			Domain  string `json:"domain" yaml:"domain"`
+		} `json:"lightning_address" yaml:"lightning_address"`
+		// AdminToken authenticates requests to the admin endpoints (Authorization: Bearer <token>).
+		// The admin endpoints and /metrics are disabled, if no token is configured.
+		AdminToken string `json:"-" yaml:"admin_token" env:"MINT_ADMIN_TOKEN"`
+		// ShutdownTimeout in seconds. On shutdown, the mint waits for requests in progress until the timeout (default 30).
+		ShutdownTimeout int `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+		// KeySetGracePeriod in seconds. Proofs of retired keysets can be redeemed until the grace period is over (default 604800, one week).
+		KeySetGracePeriod int `json:"keyset_grace_period" yaml:"keyset_grace_period"`
 	} `json:"mint" yaml:"mint"`
 }
 
 var Config Configuration
 
+// configMu guards Config, which is replaced by reloads while requests read it
+var configMu sync.RWMutex
+
+// currentConfig returns the current configuration. Requests must read the configuration with currentConfig.
+func currentConfig() Configuration {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return Config
+}
+
 const name = "config.yaml"
 
 func (c Configuration) Load() error {
@@ -71,6 +108,40 @@ func (c Configuration) Load() error {
 	}
 	return nil
 }
+
+// loadConfigurationFile reads the configuration file without changing the current configuration
+func loadConfigurationFile() (Configuration, error) {
+	c := Configuration{}
+	if _, err := os.Stat(name); err != nil {
+		return c, err
+	}
+	err := configor.New(&configor.Config{Silent: true}).Load(&c, name)
+	return c, err
+}
+
+// changedSections returns the sections of the configuration file, which are loaded by other packages on startup
+// and differ from the running configuration (lightning, database and onchain).
+func changedSections() ([]string, error) {
+	lightningConfig, databaseConfig, onchainConfig := lightning.Configuration{}, db.Configuration{}, onchain.Configuration{}
+	c := configor.New(&configor.Config{Silent: true})
+	for _, section := range []interface{}{&lightningConfig, &databaseConfig, &onchainConfig} {
+		if err := c.Load(section, name); err != nil {
+			return nil, err
+		}
+	}
+	changed := make([]string, 0)
+	if !reflect.DeepEqual(lightningConfig, lightning.Config) {
+		changed = append(changed, "lightning")
+	}
+	if !reflect.DeepEqual(databaseConfig, db.Config) {
+		changed = append(changed, "database")
+	}
+	if !reflect.DeepEqual(onchainConfig, onchain.Config) {
+		changed = append(changed, "onchain")
+	}
+	return changed, nil
+}
+
 func init() {
 
 }
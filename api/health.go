@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+)
+
+// LivenessResponse is the status of the mint process
+type LivenessResponse struct {
+	Status string `json:"status"`
+}
+
+// getLiveness is the http handler function for GET /health/live
+// @Summary Liveness
+// @Description Reports that the mint process is running and serves requests.
+// @Produce  json
+// @Success 200 {object} LivenessResponse
+// @Router /health/live [get]
+// @Tags HEALTH
+func (api Api) getLiveness(w http.ResponseWriter, r *http.Request) {
+	responseJson(w, LivenessResponse{Status: "ok"})
+}
+
+// getReadiness is the http handler function for GET /health/ready
+// @Summary Readiness
+// @Description Reports, if the mint accepts requests. The mint is not ready, while it is shutting down or the database or lightning backend is unavailable.
+// @Produce  json
+// @Success 200 {object} mint.Health
+// @Failure 503 {object} mint.Health
+// @Router /health/ready [get]
+// @Tags HEALTH
+func (api Api) getReadiness(w http.ResponseWriter, r *http.Request) {
+	health := api.Mint.Health()
+	if !health.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	responseJson(w, health)
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/lnurl"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// lightningAddressMinSendable is the minimum amount in milli satoshi a lightning address can receive
+	lightningAddressMinSendable = 1000
+	// lightningAddressMaxSendable is the maximum amount in milli satoshi a lightning address can receive
+	lightningAddressMaxSendable = 1000000000
+)
+
+// appendLightningAddressHandler will append the lnurl-pay routes for lightning addresses to the router
+func appendLightningAddressHandler(router *mux.Router, a *Api) {
+	// route to resolve the lightning address name@domain (LUD-16)
+	router.HandleFunc("/.well-known/lnurlp/{name}", Use(a.getLnurlPayParams, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	// route to create the invoice for a lightning address payment
+	router.HandleFunc("/lnurlp/{name}/callback", Use(a.getLnurlPayInvoice, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	// route to register a lightning address for a public key
+	router.HandleFunc("/lnurlp/register", Use(a.registerLightningAddress, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
+	// route to check the claimable amount of a lightning address
+	router.HandleFunc("/lnurlp/{name}/claimable", Use(a.getLightningAddressClaimable, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	// route to claim the payments of a lightning address
+	router.HandleFunc("/lnurlp/{name}/claim", Use(a.claimLightningAddress, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
+}
+
+// lightningAddressDomain returns the configured lightning address domain or the requested host.
+func lightningAddressDomain(r *http.Request) string {
+	if domain := currentConfig().Mint.LightningAddress.Domain; domain != "" {
+		return domain
+	}
+	return r.Host
+}
+
+// lightningAddressMetadata returns the lnurl-pay metadata for the lightning address name.
+// The invoice description hash commits to this exact string.
+func lightningAddressMetadata(name, domain string) (string, error) {
+	identifier := fmt.Sprintf("%s@%s", name, domain)
+	metadata, err := json.Marshal([][]string{
+		{"text/plain", fmt.Sprintf("Payment to %s", identifier)},
+		{"text/identifier", identifier},
+	})
+	return string(metadata), err
+}
+
+// responseLnurlError will write err as lnurl error response (LUD-06)
+func responseLnurlError(w http.ResponseWriter, err error) {
+	log.WithFields(log.Fields{"error.message": err.Error()}).Error(err)
+	res, marshalError := json.Marshal(lnurl.NewError(err))
+	if marshalError != nil {
+		log.WithFields(log.Fields{"error.message": marshalError.Error()}).Error(marshalError)
+		return
+	}
+	w.Write(res)
+}
+
+// getLnurlPayParams is the http handler function for GET /.well-known/lnurlp/{name}
+func (api Api) getLnurlPayParams(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(mux.Vars(r)["name"])
+	if _, err := api.Mint.LightningAddress(name); err != nil {
+		responseLnurlError(w, err)
+		return
+	}
+	domain := lightningAddressDomain(r)
+	metadata, err := lightningAddressMetadata(name, domain)
+	if err != nil {
+		responseLnurlError(w, err)
+		return
+	}
+	scheme := "https"
+	if strings.HasSuffix(domain, ".onion") {
+		scheme = "http"
+	}
+	res, err := json.Marshal(lnurl.PayParams{
+		Tag:         lnurl.TagPayRequest,
+		Callback:    fmt.Sprintf("%s://%s/lnurlp/%s/callback", scheme, domain, name),
+		MinSendable: lightningAddressMinSendable,
+		MaxSendable: lightningAddressMaxSendable,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		responseLnurlError(w, err)
+		return
+	}
+	w.Write(res)
+}
+
+// getLnurlPayInvoice is the http handler function for GET /lnurlp/{name}/callback
+func (api Api) getLnurlPayInvoice(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(mux.Vars(r)["name"])
+	amount, err := strconv.ParseUint(r.URL.Query().Get("amount"), 10, 64)
+	if err != nil {
+		responseLnurlError(w, fmt.Errorf("invalid amount"))
+		return
+	}
+	if amount < lightningAddressMinSendable || amount > lightningAddressMaxSendable || amount%1000 != 0 {
+		responseLnurlError(w, fmt.Errorf("amount %d msat not sendable", amount))
+		return
+	}
+	metadata, err := lightningAddressMetadata(name, lightningAddressDomain(r))
+	if err != nil {
+		responseLnurlError(w, err)
+		return
+	}
+	invoice, err := api.Mint.RequestLightningAddressMint(name, amount/1000, metadata)
+	if err != nil {
+		responseLnurlError(w, err)
+		return
+	}
+	res, err := json.Marshal(lnurl.PayResponse{Pr: invoice.GetPaymentRequest(), Routes: make([]interface{}, 0)})
+	if err != nil {
+		responseLnurlError(w, err)
+		return
+	}
+	w.Write(res)
+}
+
+// registerLightningAddress is the http handler function for POST /lnurlp/register
+func (api Api) registerLightningAddress(w http.ResponseWriter, r *http.Request) {
+	payload := cashu.RegisterLightningAddressRequest{}
+	err := decodeRequest(w, r, &payload)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	err = api.Mint.RegisterLightningAddress(strings.ToLower(payload.Name), payload.PublicKey, payload.Signature)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	res, err := json.Marshal(cashu.LightningAddress{Name: strings.ToLower(payload.Name), PublicKey: payload.PublicKey})
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.Write(res)
+}
+
+// getLightningAddressClaimable is the http handler function for GET /lnurlp/{name}/claimable
+func (api Api) getLightningAddressClaimable(w http.ResponseWriter, r *http.Request) {
+	amount, err := api.Mint.LightningAddressClaimable(strings.ToLower(mux.Vars(r)["name"]))
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	res, err := json.Marshal(cashu.ClaimableResponse{Amount: amount})
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.Write(res)
+}
+
+// claimLightningAddress is the http handler function for POST /lnurlp/{name}/claim
+func (api Api) claimLightningAddress(w http.ResponseWriter, r *http.Request) {
+	payload := cashu.ClaimLightningAddressRequest{Outputs: make(cashu.BlindedMessages, 0)}
+	err := decodeRequest(w, r, &payload)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	promises, err := api.Mint.ClaimLightningAddress(strings.ToLower(mux.Vars(r)["name"]), payload.Outputs, payload.Signature)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	res, err := json.Marshal(cashu.MintResponse{Promises: promises})
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.Write(res)
+}
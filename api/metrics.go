@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// requestsTotal counts the requests by route, method and http status
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feni",
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	// requestDuration is the latency of requests by route and method
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "feni",
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// statusRecorder records the status code written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// MetricsMiddleware will count all requests and observe their latency.
+// Requests are labeled with the path template of the route (e.g. /keys/{id}).
+func MetricsMiddleware(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		h.ServeHTTP(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+	}
+}
@@ -2,32 +2,51 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/cashubtc/cashu-feni/cashu"
 	"github.com/cashubtc/cashu-feni/crypto"
 	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/exchange"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/lnbits"
 	"github.com/cashubtc/cashu-feni/mint"
+	"github.com/cashubtc/cashu-feni/onchain"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 const (
 	ResourceSwaggerPathPrefix = "/swagger/"
+	// maxRequestBodySize is the maximum size of request bodies in bytes
+	maxRequestBodySize = 1 << 20
+	// defaultShutdownTimeout is the time to wait for requests in progress on shutdown
+	defaultShutdownTimeout = 30 * time.Second
+	// defaultKeySetGracePeriod is the time, in which proofs of retired keysets can still be redeemed
+	defaultKeySetGracePeriod = 7 * 24 * time.Hour
 )
 
 // todo -- this responses are currently not used.
 type Api struct {
 	HttpServer *http.Server
 	Mint       *mint.Mint
+	// stopWatchers stops the invoice watcher and the proof archiver
+	stopWatchers context.CancelFunc
 }
 
 func New() *Api {
@@ -35,52 +54,126 @@ func New() *Api {
 	if err != nil {
 		panic(err)
 	}
-	// currently using sql storage only.
-	// this should be extensible for future versions.
-	sqlStorage := db.NewSqlDatabase()
-	err = sqlStorage.Migrate(cashu.Proof{})
+	srv := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", Config.Mint.Host, Config.Mint.Port),
+		WriteTimeout: 90 * time.Second,
+		ReadTimeout:  90 * time.Second,
+	}
+	lnBitsClient, err := mint.NewLightningClient()
 	if err != nil {
 		panic(err)
 	}
-	err = sqlStorage.Migrate(cashu.Promise{})
+	ledger, err := newMint(lnBitsClient)
 	if err != nil {
 		panic(err)
 	}
-	err = sqlStorage.Migrate(crypto.KeySet{})
+	ctx, stopWatchers := context.WithCancel(context.Background())
+	m := &Api{
+		HttpServer:   srv,
+		Mint:         ledger,
+		stopWatchers: stopWatchers,
+	}
+	if interval := lightning.Config.Lightning.InvoiceWatcherInterval; lnBitsClient != nil && interval > 0 {
+		go m.Mint.WatchInvoices(ctx, time.Duration(interval)*time.Second)
+	}
+	if interval := Config.Mint.ArchiveInterval; interval > 0 {
+		go m.Mint.WatchExpiredKeySets(ctx, time.Duration(interval)*time.Second)
+	}
+
+	prometheus.MustRegister(m.Mint.OutstandingEcashCollector())
+	m.HttpServer.Handler = newRouter(m)
+	log.Trace("created mint server")
+	return m
+}
+
+// newMint creates the mint of the configuration using the lightning client
+func newMint(client lightning.Client) (*mint.Mint, error) {
+	storage, err := openStorage()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	err = sqlStorage.Migrate(cashu.CreateInvoice())
+	onchainClient, err := mint.NewOnchainClient()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	srv := &http.Server{
-		Addr:         fmt.Sprintf("%s:%s", Config.Mint.Host, Config.Mint.Port),
-		WriteTimeout: 90 * time.Second,
-		ReadTimeout:  90 * time.Second,
+	return mint.New(Config.Mint.PrivateKey,
+		mint.WithClient(client),
+		mint.WithOnchainClient(onchainClient),
+		mint.WithStorage(storage),
+		mint.WithInitialKeySet(Config.Mint.DerivationPath, Config.Mint.Units...),
+		mint.WithKeySetGracePeriod(keySetGracePeriod()),
+		mint.WithRateProvider(newRateProvider()),
+	), nil
+}
+
+// Audit will create the audit report of the configured mint.
+// The database is only read. Keysets are not stored, proofs are not archived and migrations are not applied.
+func Audit() (mint.AuditReport, error) {
+	if err := Config.Load(); err != nil {
+		return mint.AuditReport{}, err
+	}
+	client, err := mint.NewLightningClient()
+	if err != nil {
+		return mint.AuditReport{}, err
+	}
+	onchainClient, err := mint.NewOnchainClient()
+	if err != nil {
+		return mint.AuditReport{}, err
+	}
+	storage, err := db.NewStorage()
+	if err != nil {
+		return mint.AuditReport{}, err
+	}
+	defer storage.Close()
+	version, err := storage.SchemaVersion()
+	if err != nil {
+		return mint.AuditReport{}, err
+	}
+	if latest := db.MintMigrations[len(db.MintMigrations)-1].Version; version < latest {
+		return mint.AuditReport{}, fmt.Errorf("database schema version %d is outdated. please migrate the database to version %d", version, latest)
 	}
+	return mint.AuditStorage(storage, client, onchainClient, newRateProvider())
+}
 
-	lnBitsClient, err := mint.NewLightningClient()
+// openStorage will open the configured database and apply all pending schema migrations.
+func openStorage() (db.MintStorage, error) {
+	storage, err := db.NewStorage()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	m := &Api{
-		HttpServer: srv,
-		Mint: mint.New(Config.Mint.PrivateKey,
-			mint.WithClient(lnBitsClient),
-			mint.WithStorage(sqlStorage),
-			mint.WithInitialKeySet(Config.Mint.DerivationPath),
-		),
+	return storage, storage.MigrateSchema(db.MintMigrations)
+}
+
+// Migrate will apply all pending schema migrations to the mint database and return the resulting schema version.
+func Migrate() (uint, error) {
+	err := Config.Load()
+	if err != nil {
+		return 0, err
 	}
+	storage, err := openStorage()
+	if err != nil {
+		return 0, err
+	}
+	return storage.SchemaVersion()
+}
 
-	m.HttpServer.Handler = newRouter(m)
-	log.Trace("created mint server")
-	return m
+// newRateProvider will create the configured exchange rate provider
+func newRateProvider() exchange.RateProvider {
+	switch Config.Mint.ExchangeRate.Provider {
+	case "kraken":
+		return exchange.NewKraken()
+	case "fixed":
+		return exchange.FixedRates(Config.Mint.ExchangeRate.Rates)
+	}
+	return nil
 }
+
+// responseError writes the error response with the http status of its code
 func responseError(w http.ResponseWriter, err cashu.ErrorResponse) {
 	log.WithFields(log.Fields{"error.message": err.Error(), "code": err.Code}).Error(err)
-	response := err.String()
-	_, writeError := fmt.Fprintf(w, response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(cashu.StatusCode(err.Code))
+	_, writeError := w.Write([]byte(err.String()))
 	if writeError != nil {
 		log.WithFields(log.Fields{"error.message": writeError.Error()}).Error(writeError)
 	}
@@ -96,36 +189,164 @@ func Use(h http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFu
 // LoggingMiddleware will log all incoming requests
 func LoggingMiddleware(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.WithFields(log.Fields{"resource": r.URL.String(), "ip": r.RemoteAddr}).Infof("incoming request")
+		// the query is not logged, because it may contain secrets (e.g. the webhook token)
+		log.WithFields(log.Fields{"resource": r.URL.Path, "ip": r.RemoteAddr}).Infof("incoming request")
+		h.ServeHTTP(w, r)
+	}
+}
+
+// RecoveryMiddleware will recover from panics of the handler and respond with an internal error
+func RecoveryMiddleware(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			log.WithFields(log.Fields{"resource": r.URL.Path, "panic": rec}).Errorf("recovered from panic\n%s", debug.Stack())
+			responseError(w, cashu.NewErrorResponse(cashu.ErrInternal))
+		}()
 		h.ServeHTTP(w, r)
 	}
 }
 
-func (api Api) StartServer() {
-	if Config.Mint.Tls.Enabled {
-		log.Println(api.HttpServer.ListenAndServeTLS(Config.Mint.Tls.CertFile, Config.Mint.Tls.KeyFile))
-	} else {
-		log.Println(api.HttpServer.ListenAndServe())
+// decodeRequest will decode the json request body into v.
+// Bodies larger than maxRequestBodySize, unknown fields and trailing data are rejected.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := decodeJson(http.MaxBytesReader(w, r.Body, maxRequestBodySize), v); err != nil {
+		return requestBodyError(err)
+	}
+	return nil
+}
+
+// decodeJson will decode the json body into v. Errors of the decoder are returned unchanged.
+func decodeJson(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after json object")
+	}
+	return nil
+}
+
+// requestBodyError replaces errors of reading and decoding the request body with a bad request error
+func requestBodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return cashu.NewError(cashu.ErrCodeBadRequest, "request body too large. maximum size is %d bytes", tooLarge.Limit)
+	}
+	return cashu.NewError(cashu.ErrCodeBadRequest, "invalid request body: %v", err)
+}
+
+// StartServer serves the mint until SIGINT or SIGTERM is received. The mint is shut down gracefully afterwards.
+func (api Api) StartServer() error {
+	served := make(chan error, 1)
+	go func() {
+		if Config.Mint.Tls.Enabled {
+			served <- api.HttpServer.ListenAndServeTLS(Config.Mint.Tls.CertFile, Config.Mint.Tls.KeyFile)
+		} else {
+			served <- api.HttpServer.ListenAndServe()
+		}
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case err := <-served:
+		// the server could not be started (e.g. the port is in use)
+		api.stop()
+		if closeErr := api.Mint.Close(context.Background()); closeErr != nil {
+			log.WithFields(log.Fields{"error.message": closeErr.Error()}).Error("could not close mint")
+		}
+		return err
+	case s := <-signals:
+		log.WithField("signal", s.String()).Info("received signal. shutting down mint server")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancel()
+	return api.Shutdown(ctx)
+}
+
+// Shutdown stops accepting new requests and waits until the requests in progress are finished or ctx is done.
+// The watchers are stopped and the mint database is closed afterwards.
+func (api Api) Shutdown(ctx context.Context) error {
+	api.stop()
+	err := api.HttpServer.Shutdown(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{"error.message": err.Error()}).Warn("could not finish all requests in progress")
+	}
+	if closeErr := api.Mint.Close(ctx); closeErr != nil && !errors.Is(closeErr, ctx.Err()) {
+		return closeErr
+	}
+	if err == nil {
+		log.Info("mint server stopped")
+	}
+	return err
+}
+
+// stop stops the watchers of the mint
+func (api Api) stop() {
+	if api.stopWatchers != nil {
+		api.stopWatchers()
+	}
+}
+
+// shutdownTimeout returns the configured shutdown timeout. The default timeout is 30 seconds.
+func shutdownTimeout() time.Duration {
+	if timeout := currentConfig().Mint.ShutdownTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
 	}
+	return defaultShutdownTimeout
 }
+
+// keySetGracePeriod returns the configured grace period of retired keysets. The default grace period is one week.
+func keySetGracePeriod() time.Duration {
+	if Config.Mint.KeySetGracePeriod > 0 {
+		return time.Duration(Config.Mint.KeySetGracePeriod) * time.Second
+	}
+	return defaultKeySetGracePeriod
+}
+
 func newRouter(a *Api) *mux.Router {
 	router := mux.NewRouter()
 	// route to receive mint public keys
-	router.HandleFunc("/keys", Use(a.getKeys, LoggingMiddleware)).Methods(http.MethodGet)
-	router.HandleFunc("/keys/{id}", Use(a.getKeysByKeySet, LoggingMiddleware)).Methods(http.MethodGet)
-	router.HandleFunc("/keysets", Use(a.getKeySets, LoggingMiddleware)).Methods(http.MethodGet)
+	router.HandleFunc("/keys", Use(a.getKeys, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	router.HandleFunc("/keys/{id}", Use(a.getKeysByKeySet, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	router.HandleFunc("/keysets", Use(a.getKeySets, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	// route to get the signed proof of liabilities report of a keyset
+	router.HandleFunc("/liabilities/{id}", Use(a.getLiabilityReport, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
 	// route to get mint (create tokens)
-	router.HandleFunc("/mint", Use(a.getMint, LoggingMiddleware)).Methods(http.MethodGet)
+	router.HandleFunc("/mint", Use(a.getMint, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
 	// route to real mint (with LIGHTNING enabled)
-	router.HandleFunc("/mint", Use(a.mint, LoggingMiddleware)).Methods(http.MethodPost)
+	router.HandleFunc("/mint", Use(a.mint, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
+	// route to check if the invoice of a mint was paid
+	router.HandleFunc("/invoice/{hash}", Use(a.getInvoiceStatus, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet)
+	// route for the lnbits payment webhook
+	router.HandleFunc("/lnbits/webhook", Use(a.lnbitsWebhook, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
 	// route to burn / melt a tx
-	router.HandleFunc("/melt", Use(a.melt, LoggingMiddleware)).Methods(http.MethodPost)
+	router.HandleFunc("/melt", Use(a.melt, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
 	// route to check spendable proofs
-	router.HandleFunc("/check", Use(a.check, LoggingMiddleware)).Methods(http.MethodPost)
+	router.HandleFunc("/check", Use(a.check, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
 	// route to check routing fees
-	router.HandleFunc("/checkfees", Use(a.checkFee, LoggingMiddleware)).Methods(http.MethodPost)
+	router.HandleFunc("/checkfees", Use(a.checkFee, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodPost)
 	// route to split proofs (send money)
-	router.HandleFunc("/split", Use(a.split, LoggingMiddleware)).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/split", Use(a.split, LoggingMiddleware, RecoveryMiddleware, MetricsMiddleware)).Methods(http.MethodGet, http.MethodPost)
+	// routes for liveness and readiness probes. probes are not logged, because they are requested frequently.
+	router.HandleFunc("/health/live", Use(a.getLiveness, RecoveryMiddleware)).Methods(http.MethodGet)
+	router.HandleFunc("/health/ready", Use(a.getReadiness, RecoveryMiddleware)).Methods(http.MethodGet)
+	if Config.Mint.AdminToken != "" {
+		appendAdminHandler(router, a)
+	}
+	if Config.Mint.LightningAddress.Enabled {
+		appendLightningAddressHandler(router, a)
+	}
 	appendSwaggoHandler(router)
 	return router
 }
@@ -148,18 +369,23 @@ func appendSwaggoHandler(router *mux.Router) {
 // @Tags POST
 func (api Api) checkFee(w http.ResponseWriter, r *http.Request) {
 	feesRequest := cashu.CheckFeesRequest{}
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&feesRequest)
+	err := decodeRequest(w, r, &feesRequest)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
 		return
 	}
-	fee, err := api.Mint.CheckFees(feesRequest.Pr)
+	fee, err := api.Mint.CheckMeltFees(feesRequest.Method, feesRequest.Pr, feesRequest.Amount)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
 		return
 	}
-	response := cashu.CheckFeesResponse{Fee: fee / 1000}
+	// fees of non satoshi units are converted with the exchange rate
+	unitFee, err := api.Mint.FromSat(fee/1000, feesRequest.Unit)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	response := cashu.CheckFeesResponse{Fee: unitFee}
 	res, err := json.Marshal(response)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
@@ -179,25 +405,44 @@ func (api Api) checkFee(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} ErrorResponse
 // @Router /mint [get]
 // @Param        amount    query     string  false  "amount of the mint"
+// @Param        method    query     string  false  "payment method of the mint (bolt11 or bitcoin)"
+// @Param        unit      query     string  false  "unit of the amount (default sat)"
 // @Tags GET
 func (api Api) getMint(w http.ResponseWriter, r *http.Request) {
 	amount := r.URL.Query().Get("amount")
 	ai, err := strconv.Atoi(amount)
-	if err != nil {
-		log.Errorf("error checking amount")
+	if err != nil || ai < 0 {
+		responseError(w, cashu.NewErrorResponse(cashu.NewError(cashu.ErrCodeInvalidAmount, "invalid amount: %s", amount)))
+		return
 	}
-	invoice, err := api.Mint.RequestMint(uint64(ai))
-	if err != nil {
-		responseError(w, cashu.NewErrorResponse(err))
+	var pr, paymentHash string
+	switch r.URL.Query().Get("method") {
+	case cashu.MethodBitcoin:
+		// the address is returned as payment request. the quote id is used as payment hash.
+		quote, err := api.Mint.RequestOnchainMint(uint64(ai))
+		if err != nil {
+			responseError(w, cashu.NewErrorResponse(err))
+			return
+		}
+		pr, paymentHash = quote.Address, quote.Id
+	case "", cashu.MethodBolt11:
+		invoice, err := api.Mint.RequestMintWithUnit(uint64(ai), r.URL.Query().Get("unit"))
+		if err != nil {
+			responseError(w, cashu.NewErrorResponse(err))
+			return
+		}
+		log.WithField("invoice", invoice).Infof("created lightning invoice")
+		pr, paymentHash = invoice.GetPaymentRequest(), invoice.GetHash()
+	default:
+		responseError(w, cashu.NewErrorResponse(cashu.NewError(cashu.ErrCodeMethodNotSupported, "unsupported mint method: %s", r.URL.Query().Get("method"))))
 		return
 	}
-	log.WithField("invoice", invoice).Infof("created lightning invoice")
-	hash, err := crypto.EncryptAESGCM([]byte(api.Mint.MasterSha526), []byte(invoice.GetHash()))
+	hash, err := crypto.EncryptAESGCM([]byte(api.Mint.MasterSha526), []byte(paymentHash))
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
 		return
 	}
-	_, err = fmt.Fprintf(w, `{"pr": "%s", "hash": "%x"}`, invoice.GetPaymentRequest(), hash)
+	_, err = fmt.Fprintf(w, `{"pr": "%s", "hash": "%x"}`, pr, hash)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
 		return
@@ -230,27 +475,22 @@ func (api Api) mint(w http.ResponseWriter, r *http.Request) {
 	if len(hash) == 0 {
 		hash = r.URL.Query().Get("payment_hash")
 		if len(hash) == 0 {
-			responseError(w, cashu.NewErrorResponse(fmt.Errorf("invalid hash parameter")))
+			responseError(w, cashu.NewErrorResponse(cashu.NewError(cashu.ErrCodeBadRequest, "invalid hash parameter")))
 			return
 		}
 	}
 
-	decodedHash, err := hex.DecodeString(hash)
-	if err != nil {
-		responseError(w, cashu.NewErrorResponse(err))
-		return
-	}
-	pr, err = crypto.DecryptAESGCM([]byte(api.Mint.MasterSha526), decodedHash)
+	pr, err = api.decryptPaymentHash(hash)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
 		return
 	}
 
 	mintRequest := cashu.MintRequest{Outputs: make(cashu.BlindedMessages, 0)}
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&mintRequest)
+	err = decodeRequest(w, r, &mintRequest)
 	if err != nil {
-		panic(err)
+		responseError(w, cashu.NewErrorResponse(err))
+		return
 	}
 
 	promises, err := api.Mint.MintWithoutKeySet(mintRequest.Outputs, string(pr))
@@ -271,9 +511,76 @@ func (api Api) mint(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// decryptPaymentHash will decrypt the hash that was handed out by GET /mint
+func (api Api) decryptPaymentHash(hash string) ([]byte, error) {
+	decodedHash, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "invalid hash parameter")
+	}
+	pr, err := crypto.DecryptAESGCM([]byte(api.Mint.MasterSha526), decodedHash)
+	if err != nil {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "invalid hash parameter")
+	}
+	return pr, nil
+}
+
+// getInvoiceStatus is the http handler function for GET /invoice/{hash}
+// @Summary Invoice status
+// @Description Returns the paid state of the lightning invoice belonging to a mint request.
+// @Description Wallets should poll this endpoint instead of repeatedly trying to mint.
+// @Produce  json
+// @Success 200 {object} InvoiceStatusResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /invoice/{hash} [get]
+// @Param        hash    path     string  true  "hash returned by GET /mint"
+// @Tags GET
+func (api Api) getInvoiceStatus(w http.ResponseWriter, r *http.Request) {
+	hash, err := api.decryptPaymentHash(mux.Vars(r)["hash"])
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	invoice, err := api.Mint.InvoiceStatus(string(hash))
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	res, err := json.Marshal(cashu.InvoiceStatusResponse{Paid: invoice.IsPaid(), Issued: invoice.IsIssued()})
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.Write(res)
+}
+
+// lnbitsWebhook is the http handler function for POST /lnbits/webhook
+// LNbits calls this webhook once an invoice created by the mint was paid.
+func (api Api) lnbitsWebhook(w http.ResponseWriter, r *http.Request) {
+	payment := lnbits.PaymentDetails{}
+	// LNbits sends more payment details than required. Unknown fields are ignored.
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	err := decoder.Decode(&payment)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(requestBodyError(err)))
+		return
+	}
+	err = api.Mint.SettleInvoice(payment.PaymentHash, r.URL.Query().Get("token"))
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	res, err := json.Marshal(cashu.InvoiceStatusResponse{Paid: true})
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.Write(res)
+}
+
 // melt is the http handler function for POST /melt
 // @Summary Melt
 // @Description Requests tokens to be destroyed and sent out via Lightning.
+// @Description The payment method (bolt11, bolt12 or bitcoin) defaults to bolt11. BOLT12 offers and bitcoin addresses require an amount.
 // @Produce  json
 // @Success 200 {object} MeltResponse
 // @Failure 500 {object} ErrorResponse
@@ -283,19 +590,25 @@ func (api Api) mint(w http.ResponseWriter, r *http.Request) {
 func (api Api) melt(w http.ResponseWriter, r *http.Request) {
 
 	payload := cashu.MeltRequest{}
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&payload)
+	err := decodeRequest(w, r, &payload)
 	if err != nil {
-		panic(err)
+		responseError(w, cashu.NewErrorResponse(err))
+		return
 	}
 
-	payment, err := api.Mint.Melt(payload.Proofs, payload.Pr)
+	payment, err := api.Mint.MeltWithMethod(payload.Proofs, payload.Method, payload.Pr, payload.Amount)
 	if err != nil {
-		log.WithFields(log.Fields{"error.message": err.Error()}).Errorf("error in melt")
+		responseError(w, cashu.NewErrorResponse(err))
 		return
 	}
 	// TODO -- add Change
 	response := cashu.MeltResponse{Paid: payment.IsPaid(), Preimage: payment.GetPreimage()}
+	if offerPayment, ok := payment.(lightning.OfferPayment); ok {
+		response.Invoice = offerPayment.GetInvoice()
+	}
+	if onchainPayment, ok := payment.(onchain.Payment); ok {
+		response.Txid = onchainPayment.GetTxid()
+	}
 	res, err := json.Marshal(response)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
@@ -362,7 +675,7 @@ func (api Api) getKeysByKeySet(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api Api) getKeySets(w http.ResponseWriter, r *http.Request) {
-	response := cashu.GetKeySetsResponse{KeySets: api.Mint.GetKeySetIds()}
+	response := cashu.GetKeySetsResponse{KeySets: api.Mint.GetKeySetIds(), Units: api.Mint.GetKeySetUnits()}
 	res, err := json.Marshal(response)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
@@ -371,6 +684,30 @@ func (api Api) getKeySets(w http.ResponseWriter, r *http.Request) {
 	w.Write(res)
 }
 
+// getLiabilityReport is the http handler function for GET /liabilities/{keyset_id}
+// @Summary Liabilities
+// @Description Get the signed list of all blinded signatures issued and all secrets spent by the keyset
+// @Produce  json
+// @Success 200 {object} cashu.LiabilityReport
+// @Failure 404 {object} ErrorResponse
+// @Router /liabilities/{keyset_id} [get]
+// @Tags GET
+func (api Api) getLiabilityReport(w http.ResponseWriter, r *http.Request) {
+	keysetId := mux.Vars(r)["id"]
+	keysetId = strings.ReplaceAll(strings.ReplaceAll(keysetId, "_", "/"), "-", "+")
+	report, err := api.Mint.LiabilityReport(keysetId)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	res, err := json.Marshal(report)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	w.Write(res)
+}
+
 // check is the http handler function for POST /check
 // @Summary Check spendable
 // @Description Get currently available public keys
@@ -382,10 +719,10 @@ func (api Api) getKeySets(w http.ResponseWriter, r *http.Request) {
 // @Tags POST
 func (api Api) check(w http.ResponseWriter, r *http.Request) {
 	payload := cashu.CheckSpendableRequest{}
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&payload)
+	err := decodeRequest(w, r, &payload)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
+		return
 	}
 	spendable := api.Mint.CheckSpendables(payload.Proofs)
 	res, err := json.Marshal(spendable)
@@ -412,11 +749,13 @@ func (api Api) check(w http.ResponseWriter, r *http.Request) {
 func (api Api) split(w http.ResponseWriter, r *http.Request) {
 
 	payload := cashu.SplitRequest{}
-	buf, _ := io.ReadAll(r.Body)
-	body := io.NopCloser(bytes.NewBuffer(buf))
+	buf, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(requestBodyError(err)))
+		return
+	}
 	bodyInvalidAmount := io.NopCloser(bytes.NewBuffer(buf))
-	decoder := json.NewDecoder(body)
-	err := decoder.Decode(&payload)
+	err = decodeJson(bytes.NewReader(buf), &payload)
 	if err != nil {
 		switch err.(type) {
 		case *json.UnmarshalTypeError:
@@ -426,18 +765,24 @@ func (api Api) split(w http.ResponseWriter, r *http.Request) {
 			decoder := json.NewDecoder(bodyInvalidAmount)
 			err := decoder.Decode(&amt)
 			if err == nil {
-				err = fmt.Errorf("invalid split amount: %d", amt.Amount)
+				err = cashu.NewError(cashu.ErrCodeInvalidAmount, "invalid split amount: %d", amt.Amount)
 				responseError(w, cashu.NewErrorResponse(err))
 				return
 			}
 		}
-		responseError(w, cashu.NewErrorResponse(err))
+		responseError(w, cashu.NewErrorResponse(requestBodyError(err)))
 		return
 	}
 	proofs := payload.Proofs
 	amount := payload.Amount
 	outputs := payload.Outputs
-	keyset, err := api.Mint.LoadKeySet(api.Mint.KeySetId)
+	// outputs are signed with the active keyset of the proofs unit
+	unit, err := api.Mint.ProofsUnit(proofs)
+	if err != nil {
+		responseError(w, cashu.NewErrorResponse(err))
+		return
+	}
+	keyset, err := api.Mint.ActiveKeySet(unit)
 	if err != nil {
 		responseError(w, cashu.NewErrorResponse(err))
 		return
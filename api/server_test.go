@@ -0,0 +1,367 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/mint"
+	"github.com/cashubtc/cashu-feni/onchain"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type testRoute struct {
+	method string
+	path   string
+}
+
+// newTestRouter creates the router of a mint without lightning and onchain backend.
+// All routes of the router are returned with their path variables replaced.
+func newTestRouter(t testing.TB) (*mux.Router, []testRoute) {
+	Config.Mint.LightningAddress.Enabled = true
+	t.Cleanup(func() { Config.Mint.LightningAddress.Enabled = false })
+	m := mint.New("master", mint.WithStorage(db.NewMemoryDatabase()), mint.WithInitialKeySet("0/0/0/0"))
+	hash, err := crypto.EncryptAESGCM([]byte(m.MasterSha526), []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := strings.NewReplacer("{name}", "satoshi", "{hash}", hex.EncodeToString(hash), "{id}", "id")
+	router := newRouter(&Api{Mint: m})
+	routes := make([]testRoute, 0)
+	err = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			routes = append(routes, testRoute{method: method, path: vars.Replace(path)})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range routes {
+		if r.path == "/mint" && r.method == http.MethodPost {
+			routes[i].path = "/mint?hash=" + hex.EncodeToString(hash)
+		}
+	}
+	return router, routes
+}
+
+func TestRouter_invalidBody(t *testing.T) {
+	router, _ := newTestRouter(t)
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "meltInvalidJson", path: "/melt", body: "{", wantStatus: http.StatusBadRequest},
+		{name: "meltUnknownField", path: "/melt", body: `{"proofs":[],"unknown":1}`, wantStatus: http.StatusBadRequest},
+		{name: "meltTrailingData", path: "/melt", body: `{"proofs":[]} {}`, wantStatus: http.StatusBadRequest},
+		{name: "meltNoProofs", path: "/melt", body: `{"proofs":[]}`, wantStatus: http.StatusBadRequest},
+		{name: "checkInvalidJson", path: "/check", body: "[", wantStatus: http.StatusBadRequest},
+		{name: "splitTooLarge", path: "/split", body: `{"proofs":"` + strings.Repeat("a", maxRequestBodySize) + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "splitInvalidJson", path: "/split", body: "{", wantStatus: http.StatusBadRequest},
+		{name: "mintInvalidHash", path: "/mint?hash=invalid", body: `{"outputs":[]}`, wantStatus: http.StatusBadRequest},
+		{name: "mintWithoutHash", path: "/mint", body: `{"outputs":[]}`, wantStatus: http.StatusBadRequest},
+		{name: "checkEmpty", path: "/check", body: `{"proofs":[]}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d. body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !json.Valid(w.Body.Bytes()) {
+				t.Errorf("response is not json: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	handler := Use(func(w http.ResponseWriter, r *http.Request) { panic("handler") }, RecoveryMiddleware)
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.SetOutput(output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	handler := Use(func(w http.ResponseWriter, r *http.Request) {}, LoggingMiddleware)
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/lnbits/webhook?token=secret", nil))
+	if !strings.Contains(output.String(), "/lnbits/webhook") || strings.Contains(output.String(), "secret") {
+		t.Errorf("LoggingMiddleware() logged %q, want path without query", output.String())
+	}
+}
+
+func FuzzRouter(f *testing.F) {
+	f.Add([]byte(`{"proofs":[{"id":"id","amount":1,"secret":"secret","C":"02"}],"pr":"lnbc1"}`))
+	f.Add([]byte(`{"outputs":[{"amount":1,"B_":"02"}],"proofs":[],"amount":1}`))
+	f.Add([]byte(`{"amount":-1}`))
+	f.Add([]byte(`{"name":"satoshi","public_key":"02","signature":"00"}`))
+	f.Add([]byte(`{"pr":"00"}`))
+	f.Add([]byte(`{"proofs":[{"secret":"00"}]}`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`null`))
+	f.Add([]byte{})
+	router, routes := newTestRouter(f)
+	f.Fuzz(func(t *testing.T, body []byte) {
+		for _, route := range routes {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(route.method, route.path, bytes.NewReader(body)))
+			if w.Code == http.StatusInternalServerError {
+				t.Errorf("%s %s: internal error for body %q: %s", route.method, route.path, body, w.Body.String())
+			}
+		}
+	})
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	Config.Mint.AdminToken = "admin"
+	t.Cleanup(func() { Config.Mint.AdminToken = "" })
+	router, _ := newTestRouter(t)
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodPost, path: "/check", body: `{"proofs":[]}`},
+		{method: http.MethodPost, path: "/melt", body: "{"},
+		{method: http.MethodGet, path: "/keys/unknown"},
+	}
+	for _, r := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, strings.NewReader(r.body)))
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d without admin token", w.Code, http.StatusUnauthorized)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer admin")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "check", want: `feni_http_requests_total{method="POST",route="/check",status="200"}`},
+		{name: "melt", want: `feni_http_requests_total{method="POST",route="/melt",status="400"}`},
+		{name: "keys", want: `feni_http_requests_total{method="GET",route="/keys/{id}",status="404"}`},
+		{name: "duration", want: `feni_http_request_duration_seconds_count{method="POST",route="/check"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("metrics do not contain %s", tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	Config.Mint.AdminToken = "admin"
+	t.Cleanup(func() { Config.Mint.AdminToken = "" })
+	router, _ := newTestRouter(t)
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "missing", authorization: "", wantStatus: http.StatusUnauthorized},
+		{name: "invalid", authorization: "Bearer wrong", wantStatus: http.StatusUnauthorized},
+		{name: "noBearer", authorization: "admin", wantStatus: http.StatusUnauthorized},
+		{name: "valid", authorization: "Bearer admin", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/audit", nil)
+			r.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d. body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	config := Config
+	t.Cleanup(func() { Config = config })
+	Config.Mint.AdminToken = "admin"
+	router, _ := newTestRouter(t)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "keysets", method: http.MethodGet, path: "/admin/keysets", wantStatus: http.StatusOK},
+		{name: "rotateWithoutPath", method: http.MethodPost, path: "/admin/keysets/rotate", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "rotate", method: http.MethodPost, path: "/admin/keysets/rotate", body: `{"derivation_path":"0/0/0/1"}`, wantStatus: http.StatusOK},
+		{name: "melts", method: http.MethodGet, path: "/admin/melts", wantStatus: http.StatusOK},
+		{name: "resolveWithoutStatus", method: http.MethodPost, path: "/admin/melts/id/resolve", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "resolveUnknown", method: http.MethodPost, path: "/admin/melts/id/resolve", body: `{"paid":false}`, wantStatus: http.StatusNotFound},
+		{name: "balanceNotSupported", method: http.MethodGet, path: "/admin/balance", wantStatus: http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer admin")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d. body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+	if Config.Mint.DerivationPath != "0/0/0/1" {
+		t.Errorf("rotated derivation path = %s, want 0/0/0/1", Config.Mint.DerivationPath)
+	}
+}
+
+func TestApi_Reload(t *testing.T) {
+	config := Config
+	t.Cleanup(func() { Config = config })
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	file := "log_level: info\nmint:\n  private_key: master\n  derivation_path: 0/0/0/2\n  admin_token: rotated\n"
+	if err = os.WriteFile(name, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+	Config = Configuration{LogLevel: "trace"}
+	Config.Mint.PrivateKey = "master"
+	Config.Mint.DerivationPath = "0/0/0/0"
+	// the file has no lightning, database and onchain sections
+	lightningConfig, databaseConfig, onchainConfig := lightning.Config, db.Config, onchain.Config
+	t.Cleanup(func() { lightning.Config, db.Config, onchain.Config = lightningConfig, databaseConfig, onchainConfig })
+	lightning.Config, db.Config, onchain.Config = lightning.Configuration{}, db.Configuration{}, onchain.Configuration{}
+	api := Api{Mint: mint.New("master", mint.WithStorage(db.NewMemoryDatabase()), mint.WithInitialKeySet("0/0/0/0"))}
+	restart, err := api.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !reflect.DeepEqual(restart, []string{"log_level"}) {
+		t.Errorf("Reload() restart = %v, want [log_level]", restart)
+	}
+	if want := crypto.NewKeySet("master", "0/0/0/2").Id; api.Mint.KeySetId != want {
+		t.Errorf("Reload() keyset = %s, want %s", api.Mint.KeySetId, want)
+	}
+	if Config.Mint.AdminToken != "rotated" || Config.LogLevel != "trace" {
+		t.Errorf("Reload() admin token = %s, log level = %s, want rotated and trace", Config.Mint.AdminToken, Config.LogLevel)
+	}
+	// changes of the sections of other packages are reported, but not applied
+	if err = os.WriteFile(name, []byte(file+"lightning:\n  enabled: true\ndatabase:\n  memory: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	restart, err = api.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if want := []string{"database", "lightning", "log_level"}; !reflect.DeepEqual(restart, want) {
+		t.Errorf("Reload() restart = %v, want %v", restart, want)
+	}
+	if lightning.Config.Lightning.Enabled || db.Config.Database.Memory {
+		t.Errorf("Reload() applied lightning and database configuration")
+	}
+	// requests read the configuration, while it is reloaded
+	handler := AdminMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			r := httptest.NewRequest(http.MethodGet, "/admin/keysets", nil)
+			r.Header.Set("Authorization", "Bearer rotated")
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+		}
+	}()
+	if _, err = api.Reload(); err != nil {
+		t.Errorf("Reload() error = %v", err)
+	}
+	<-done
+}
+
+func TestHealthRoutes(t *testing.T) {
+	router, _ := newTestRouter(t)
+	m := mint.New("master", mint.WithStorage(db.NewMemoryDatabase()), mint.WithInitialKeySet("0/0/0/0"))
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		router     http.Handler
+		path       string
+		wantStatus int
+	}{
+		{name: "live", router: router, path: "/health/live", wantStatus: http.StatusOK},
+		{name: "ready", router: router, path: "/health/ready", wantStatus: http.StatusOK},
+		{name: "liveClosing", router: newRouter(&Api{Mint: m}), path: "/health/live", wantStatus: http.StatusOK},
+		{name: "readyClosing", router: newRouter(&Api{Mint: m}), path: "/health/ready", wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d. body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestApi_Shutdown(t *testing.T) {
+	m := mint.New("master", mint.WithStorage(db.NewMemoryDatabase()), mint.WithInitialKeySet("0/0/0/0"))
+	api := &Api{HttpServer: &http.Server{Addr: "127.0.0.1:0"}, Mint: m}
+	api.HttpServer.Handler = newRouter(api)
+	served := make(chan error, 1)
+	go func() {
+		served <- api.HttpServer.ListenAndServe()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := api.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("ListenAndServe() error = %v, want %v", err, http.ErrServerClosed)
+	}
+	if !m.Closing() {
+		t.Errorf("Shutdown() did not close the mint")
+	}
+}
@@ -12,7 +12,7 @@ import (
 
 type ProofsUsed struct {
 	Amount   uint64 `json:"amount"`
-	Secret   string `json:"secret" gorm:"primaryKey"`
+	Secret   string `json:"secret" gorm:"primaryKey;size:191"`
 	C        string `json:"C"`
 	TimeUsed time.Time
 }
@@ -27,7 +27,7 @@ const (
 type Proof struct {
 	Id           string      `json:"id"`
 	Amount       uint64      `json:"amount"`
-	Secret       string      `json:"secret" gorm:"primaryKey"`
+	Secret       string      `json:"secret" gorm:"primaryKey;size:191"` // 191 characters fit into the utf8mb4 index limit of MySQL
 	C            string      `json:"C"`
 	Status       ProofStatus `json:"-"`
 	Reserved     bool        `json:"-,omitempty"`
@@ -50,6 +50,47 @@ type P2SHScript struct {
 	Address   string `json:"address"`
 }
 
+// OnchainQuote is a mint request paid with an on-chain transaction to Address.
+type OnchainQuote struct {
+	Id              string    `json:"id" gorm:"primaryKey;size:64"`
+	Address         string    `json:"address" gorm:"uniqueIndex;size:128"`
+	DerivationIndex uint32    `json:"-"`
+	Amount          uint64    `json:"amount"`
+	Paid            bool      `json:"paid"`
+	Issued          bool      `json:"issued"`
+	TimeCreated     time.Time `json:"time_created"`
+	TimePaid        time.Time `json:"time_paid"`
+}
+
+// PendingMelt is a melt, whose payment is in flight. The proofs with Secrets are pending until the melt is finished.
+// Melts, which were interrupted (e.g. by a crash of the mint), stay pending until they are resolved by the mint operator.
+type PendingMelt struct {
+	Id          string    `json:"id" gorm:"primaryKey;size:64"`
+	Method      string    `json:"method"`
+	Request     string    `json:"request"`
+	Amount      uint64    `json:"amount"`
+	Unit        string    `json:"unit"`
+	Secrets     []string  `json:"secrets" gorm:"serializer:json"`
+	TimeCreated time.Time `json:"time_created"`
+	// InFlight is true, while the payment is sent by the running mint
+	InFlight bool `json:"in_flight" gorm:"-"`
+}
+
+// LightningAddress is a lnurl-pay address on the mint. Payments to this address can be claimed with the public key.
+type LightningAddress struct {
+	Name        string    `json:"name" gorm:"primaryKey;size:64"`
+	PublicKey   string    `json:"pubkey"`
+	TimeCreated time.Time `json:"-"`
+}
+
+// LightningAddressPayment links the invoice of a lightning address payment to the address.
+type LightningAddressPayment struct {
+	Hash    string `json:"payment_hash" gorm:"primaryKey;size:64"`
+	Name    string `json:"name" gorm:"index;size:64"`
+	Amount  uint64 `json:"amount"`
+	Claimed bool   `json:"claimed"`
+}
+
 func (p Proof) Decode() ([]byte, error) {
 	return hex.DecodeString(p.C)
 }
@@ -57,9 +98,10 @@ func (p Proof) Decode() ([]byte, error) {
 type Proofs []Proof
 
 type Promise struct {
-	B_b    string `json:"C_b" gorm:"primaryKey"`
+	B_b    string `json:"C_b" gorm:"primaryKey;size:66"`
 	C_c    string `json:"C_c"`
 	Amount uint64 `json:"amount"`
+	Id     string `json:"id" gorm:"index:idx_promises_id;size:64"` // Id of the keyset, that signed the promise
 }
 
 func (p Promise) Log() map[string]interface{} {
@@ -89,9 +131,12 @@ func WithCode(code int) ErrorOptions {
 		err.Code = code
 	}
 }
+
+// NewErrorResponse creates the response of err. The code is taken from the wrapped error of the catalog.
 func NewErrorResponse(err error, options ...ErrorOptions) ErrorResponse {
 	e := ErrorResponse{
-		Err: err.Error(),
+		Err:  err.Error(),
+		Code: ErrorCode(err),
 	}
 	for _, o := range options {
 		o(&e)
@@ -130,6 +175,10 @@ type MeltResponse struct {
 	Paid     bool               `json:"paid"`
 	Preimage string             `json:"preimage"`
 	Change   []BlindedSignature `json:"change,omitempty"`
+	// Invoice is the BOLT12 invoice, that was fetched from the offer and paid
+	Invoice string `json:"invoice,omitempty"`
+	// Txid is the transaction id of an on-chain payment
+	Txid string `json:"txid,omitempty"`
 }
 type GetKeysResponse map[int]string
 type SplitResponse struct {
@@ -138,16 +187,35 @@ type SplitResponse struct {
 }
 type GetKeySetsResponse struct {
 	KeySets []string `json:"keysets"`
+	// Units maps keyset ids to the unit of the keyset
+	Units map[string]string `json:"units,omitempty"`
 }
 type GetMintResponse struct {
 	Pr   string `json:"pr"`
 	Hash string `json:"hash"`
 }
 
+type InvoiceStatusResponse struct {
+	Paid   bool `json:"paid"`
+	Issued bool `json:"issued"`
+}
+
+// payment methods of melt requests
+const (
+	MethodBolt11  = "bolt11"  // MethodBolt11 pays a BOLT11 lightning payment request
+	MethodBolt12  = "bolt12"  // MethodBolt12 pays a BOLT12 lightning offer
+	MethodBitcoin = "bitcoin" // MethodBitcoin pays to an on-chain bitcoin address
+)
+
 type MeltRequest struct {
-	Proofs  Proofs           `json:"proofs"`
+	Proofs Proofs `json:"proofs"`
+	// Pr is the payment request of the melt method (e.g. a BOLT11 invoice or a BOLT12 offer)
 	Pr      string           `json:"pr"`
 	Outputs []BlindedMessage `json:"outputs,omitempty"`
+	// Method is the payment method of Pr. Defaults to bolt11.
+	Method string `json:"method,omitempty"`
+	// Amount in satoshi, for payment requests without amount (e.g. BOLT12 offers).
+	Amount uint64 `json:"amount,omitempty"`
 }
 type CheckSpendableRequest struct {
 	Proofs Proofs `json:"proofs"`
@@ -160,7 +228,24 @@ type CheckFeesResponse struct {
 	Fee uint64 `json:"fee"`
 }
 type CheckFeesRequest struct {
-	Pr string `json:"pr"`
+	Pr     string `json:"pr"`
+	Method string `json:"method,omitempty"`
+	Amount uint64 `json:"amount,omitempty"`
+	// Unit of the returned fee. Defaults to sat.
+	Unit string `json:"unit,omitempty"`
+}
+
+type RegisterLightningAddressRequest struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+type ClaimLightningAddressRequest struct {
+	Outputs   BlindedMessages `json:"outputs"`
+	Signature string          `json:"signature"`
+}
+type ClaimableResponse struct {
+	Amount uint64 `json:"amount"`
 }
 
 type SplitRequest struct {
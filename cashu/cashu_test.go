@@ -214,13 +214,14 @@ func TestPromise_Log(t *testing.T) {
 		B_b    string
 		C_c    string
 		Amount uint64
+		Id     string
 	}
 	tests := []struct {
 		name   string
 		fields fields
 		want   map[string]interface{}
 	}{
-		{name: "promiseLog", want: map[string]interface{}{"B_b": "1234a", "C_c": "1234", "Amount": uint64(1)}, fields: fields{Amount: 1, C_c: "1234", B_b: "1234a"}},
+		{name: "promiseLog", want: map[string]interface{}{"B_b": "1234a", "C_c": "1234", "Amount": uint64(1), "Id": "keyset"}, fields: fields{Amount: 1, C_c: "1234", B_b: "1234a", Id: "keyset"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -228,6 +229,7 @@ func TestPromise_Log(t *testing.T) {
 				B_b:    tt.fields.B_b,
 				C_c:    tt.fields.C_c,
 				Amount: tt.fields.Amount,
+				Id:     tt.fields.Id,
 			}
 			if got := p.Log(); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Log() = %v, want %v", got, tt.want)
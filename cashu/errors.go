@@ -0,0 +1,118 @@
+package cashu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error codes of error responses. Wallets should handle errors by their code instead of their message.
+const (
+	// ErrCodeBadRequest invalid request, which is not covered by a more specific code
+	ErrCodeBadRequest = 10000
+	// ErrCodeProofVerification proofs or their scripts could not be verified
+	ErrCodeProofVerification = 10001
+	// ErrCodeInvalidSecret secret of a proof is missing or too long
+	ErrCodeInvalidSecret = 10002
+	// ErrCodeTokenSpent proofs were already spent
+	ErrCodeTokenSpent = 11001
+	// ErrCodeTransactionUnbalanced inputs and outputs do not have the same amount
+	ErrCodeTransactionUnbalanced = 11002
+	// ErrCodeDuplicate proofs or outputs contain duplicates
+	ErrCodeDuplicate = 11003
+	// ErrCodeTokenPending proofs are pending in another transaction
+	ErrCodeTokenPending = 11004
+	// ErrCodeUnitNotSupported unit is not supported or proofs have different units
+	ErrCodeUnitNotSupported = 11005
+	// ErrCodeInvalidAmount amount is invalid or out of range
+	ErrCodeInvalidAmount = 11006
+	// ErrCodeKeySetUnknown keyset does not exist
+	ErrCodeKeySetUnknown = 12001
+	// ErrCodeQuoteNotPaid invoice or quote was not paid yet
+	ErrCodeQuoteNotPaid = 20001
+	// ErrCodeTokensIssued tokens for the invoice or quote were already issued
+	ErrCodeTokensIssued = 20002
+	// ErrCodeMethodNotSupported payment method or backend feature is not supported
+	ErrCodeMethodNotSupported = 20003
+	// ErrCodeInvoiceAlreadyPaid invoice was already paid
+	ErrCodeInvoiceAlreadyPaid = 20006
+	// ErrCodeNotFound requested resource (e.g. lightning address) does not exist
+	ErrCodeNotFound = 30001
+	// ErrCodeUnauthorized signature or token is invalid
+	ErrCodeUnauthorized = 30002
+	// ErrCodeInternal unexpected error of the mint. This is the code of all errors, which are not part of the catalog.
+	ErrCodeInternal = 50000
+	// ErrCodeUnavailable mint is shutting down
+	ErrCodeUnavailable = 50001
+)
+
+// errorStatus maps error codes to http status codes
+var errorStatus = map[int]int{
+	ErrCodeBadRequest:            http.StatusBadRequest,
+	ErrCodeProofVerification:     http.StatusBadRequest,
+	ErrCodeInvalidSecret:         http.StatusBadRequest,
+	ErrCodeTokenSpent:            http.StatusConflict,
+	ErrCodeTransactionUnbalanced: http.StatusBadRequest,
+	ErrCodeDuplicate:             http.StatusBadRequest,
+	ErrCodeTokenPending:          http.StatusConflict,
+	ErrCodeUnitNotSupported:      http.StatusBadRequest,
+	ErrCodeInvalidAmount:         http.StatusBadRequest,
+	ErrCodeKeySetUnknown:         http.StatusNotFound,
+	ErrCodeQuoteNotPaid:          http.StatusPaymentRequired,
+	ErrCodeTokensIssued:          http.StatusConflict,
+	ErrCodeMethodNotSupported:    http.StatusNotImplemented,
+	ErrCodeInvoiceAlreadyPaid:    http.StatusConflict,
+	ErrCodeNotFound:              http.StatusNotFound,
+	ErrCodeUnauthorized:          http.StatusUnauthorized,
+	ErrCodeInternal:              http.StatusInternalServerError,
+	ErrCodeUnavailable:           http.StatusServiceUnavailable,
+}
+
+// StatusCode returns the http status code of an error code. Unknown codes are internal server errors.
+func StatusCode(code int) int {
+	if status, ok := errorStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Errors of the catalog. Errors are wrapped to add details to the message,
+// e.g. fmt.Errorf("%w. Secret: %s", ErrTokenSpent, secret).
+var (
+	ErrProofVerification     = ErrorResponse{Err: "could not verify proofs.", Code: ErrCodeProofVerification}
+	ErrScriptVerification    = ErrorResponse{Err: "script verification failed.", Code: ErrCodeProofVerification}
+	ErrTokenSpent            = ErrorResponse{Err: "tokens already spent", Code: ErrCodeTokenSpent}
+	ErrTransactionUnbalanced = ErrorResponse{Err: "split amount is higher than the total sum.", Code: ErrCodeTransactionUnbalanced}
+	ErrDuplicateProofs       = ErrorResponse{Err: "duplicate proofs.", Code: ErrCodeDuplicate}
+	ErrDuplicateOutputs      = ErrorResponse{Err: "duplicate outputs.", Code: ErrCodeDuplicate}
+	ErrTokenPending          = ErrorResponse{Err: "proofs already pending.", Code: ErrCodeTokenPending}
+	ErrUnitNotSupported      = ErrorResponse{Err: "unit not supported", Code: ErrCodeUnitNotSupported}
+	ErrKeySetUnknown         = ErrorResponse{Err: "keyset does not exist", Code: ErrCodeKeySetUnknown}
+	ErrQuoteNotPaid          = ErrorResponse{Err: "Lightning invoice not paid yet.", Code: ErrCodeQuoteNotPaid}
+	ErrTokensIssued          = ErrorResponse{Err: "tokens already issued", Code: ErrCodeTokensIssued}
+	ErrInvoiceAlreadyPaid    = ErrorResponse{Err: "invoice already paid.", Code: ErrCodeInvoiceAlreadyPaid}
+	ErrInternal              = ErrorResponse{Err: "internal server error", Code: ErrCodeInternal}
+	ErrShuttingDown          = ErrorResponse{Err: "mint is shutting down", Code: ErrCodeUnavailable}
+)
+
+// NewError creates an error of the catalog with a formatted message
+func NewError(code int, format string, a ...interface{}) ErrorResponse {
+	return ErrorResponse{Err: fmt.Sprintf(format, a...), Code: code}
+}
+
+// ErrorCode returns the code of err. Errors without code are unexpected errors of the mint.
+// Invalid requests must be reported with a code, e.g. NewError(ErrCodeBadRequest, "invalid hash").
+func ErrorCode(err error) int {
+	var e ErrorResponse
+	if errors.As(err, &e) && e.Code != 0 {
+		return e.Code
+	}
+	return ErrCodeInternal
+}
+
+// Is matches error responses by their code, so that errors.Is(err, ErrTokenSpent) is true
+// for every error response with the code of ErrTokenSpent.
+func (e ErrorResponse) Is(target error) bool {
+	t, ok := target.(ErrorResponse)
+	return ok && t.Code != 0 && t.Code == e.Code
+}
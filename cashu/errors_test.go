@@ -0,0 +1,66 @@
+package cashu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		want int
+	}{
+		{name: "badRequest", code: ErrCodeBadRequest, want: http.StatusBadRequest},
+		{name: "tokenSpent", code: ErrCodeTokenSpent, want: http.StatusConflict},
+		{name: "quoteNotPaid", code: ErrCodeQuoteNotPaid, want: http.StatusPaymentRequired},
+		{name: "keySetUnknown", code: ErrCodeKeySetUnknown, want: http.StatusNotFound},
+		{name: "methodNotSupported", code: ErrCodeMethodNotSupported, want: http.StatusNotImplemented},
+		{name: "internal", code: ErrCodeInternal, want: http.StatusInternalServerError},
+		{name: "unavailable", code: ErrCodeUnavailable, want: http.StatusServiceUnavailable},
+		{name: "unknown", code: 99999, want: http.StatusInternalServerError},
+		{name: "none", code: 0, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatusCode(tt.code); got != tt.want {
+				t.Errorf("StatusCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		want   int
+		target error
+	}{
+		{name: "catalog", err: ErrTokenSpent, want: ErrCodeTokenSpent, target: ErrTokenSpent},
+		{name: "wrapped", err: fmt.Errorf("%w. Secret: %s", ErrTokenSpent, "secret"), want: ErrCodeTokenSpent, target: ErrTokenSpent},
+		{name: "sameCode", err: ErrDuplicateOutputs, want: ErrCodeDuplicate, target: ErrDuplicateProofs},
+		{name: "newError", err: NewError(ErrCodeInvalidAmount, "invalid split amount: %d", 3), want: ErrCodeInvalidAmount},
+		{name: "badRequest", err: NewError(ErrCodeBadRequest, "invalid hash"), want: ErrCodeBadRequest},
+		{name: "plain", err: errors.New("plain"), want: ErrCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode() = %v, want %v", got, tt.want)
+			}
+			response := NewErrorResponse(tt.err)
+			if response.Code != tt.want || response.Err != tt.err.Error() {
+				t.Errorf("NewErrorResponse() = %v, want code %v", response, tt.want)
+			}
+			if tt.target != nil && !errors.Is(tt.err, tt.target) {
+				t.Errorf("errors.Is(%v, %v) = false", tt.err, tt.target)
+			}
+			if errors.Is(tt.err, ErrQuoteNotPaid) {
+				t.Errorf("errors.Is(%v, %v) = true", tt.err, ErrQuoteNotPaid)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package cashu
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// IssuedSignature is a blinded signature, which the mint issued for the blinded message B_.
+type IssuedSignature struct {
+	B_     string `json:"B_"`
+	C_     string `json:"C_"`
+	Amount uint64 `json:"amount"`
+}
+
+// SpentSecret is the secret of a proof, which was redeemed at the mint.
+type SpentSecret struct {
+	Secret string `json:"secret"`
+	Amount uint64 `json:"amount"`
+}
+
+// LiabilityReport lists all blinded signatures issued and all secrets spent in an epoch.
+// An epoch is the lifetime of a keyset, so the report of a retired keyset does not change anymore.
+// Users check, that their own signatures are issued and their unspent secrets are not spent in the report.
+// The signature only binds the report to the liability key of the mint. It is evidence against the mint,
+// if the key is known to belong to the mint, e.g. because the wallet pinned the key of earlier reports.
+type LiabilityReport struct {
+	KeySetId string    `json:"keyset_id"`
+	Unit     string    `json:"unit"`
+	Time     time.Time `json:"time"`
+	// Issued signatures ordered by B_
+	Issued []IssuedSignature `json:"issued"`
+	// Spent secrets ordered by secret
+	Spent          []SpentSecret `json:"spent"`
+	IssuedAmount   uint64        `json:"issued_amount"`
+	RedeemedAmount uint64        `json:"redeemed_amount"`
+	// Outstanding is the liability claimed by the mint in the unit of the keyset
+	Outstanding int64 `json:"outstanding"`
+	// PublicKey is the x-only public key of the mint, which signed the report
+	PublicKey string `json:"pubkey"`
+	// Signature is the BIP-340 signature of sha256(Message())
+	Signature string `json:"signature"`
+}
+
+// Message returns the signed message of the report, which is the json encoded report without signature.
+func (r LiabilityReport) Message() ([]byte, error) {
+	r.Signature = ""
+	return json.Marshal(r)
+}
+
+// Verify checks, that the report is signed by publicKey and that the claimed amounts are the sums of the listed amounts.
+// publicKey must be a trusted liability key of the mint. The key of the report itself is not trusted.
+func (r LiabilityReport) Verify(publicKey string) error {
+	if r.PublicKey != publicKey {
+		return fmt.Errorf("report is signed by %s instead of %s", r.PublicKey, publicKey)
+	}
+	message, err := r.Message()
+	if err != nil {
+		return err
+	}
+	if err = VerifySchnorrSignature(publicKey, r.Signature, message); err != nil {
+		return err
+	}
+	var issued, redeemed uint64
+	signatures := make(map[string]struct{})
+	for _, s := range r.Issued {
+		if _, ok := signatures[s.B_]; ok {
+			return fmt.Errorf("blinded message %s is issued twice", s.B_)
+		}
+		signatures[s.B_] = struct{}{}
+		issued += s.Amount
+	}
+	secrets := make(map[string]struct{})
+	for _, s := range r.Spent {
+		if _, ok := secrets[s.Secret]; ok {
+			return fmt.Errorf("secret %s is spent twice", s.Secret)
+		}
+		secrets[s.Secret] = struct{}{}
+		redeemed += s.Amount
+	}
+	if issued != r.IssuedAmount {
+		return fmt.Errorf("issued amount %d does not match the issued signatures %d", r.IssuedAmount, issued)
+	}
+	if redeemed != r.RedeemedAmount {
+		return fmt.Errorf("redeemed amount %d does not match the spent secrets %d", r.RedeemedAmount, redeemed)
+	}
+	if r.Outstanding != int64(issued)-int64(redeemed) {
+		return fmt.Errorf("outstanding amount %d does not match issued minus redeemed amount %d", r.Outstanding, int64(issued)-int64(redeemed))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package cashu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// VerifySchnorrSignature verifies the BIP-340 signature of sha256(message) for the x-only public key pubkey.
+func VerifySchnorrSignature(pubkey, signature string, message []byte) error {
+	key, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return NewError(ErrCodeBadRequest, "invalid public key: %v", err)
+	}
+	publicKey, err := schnorr.ParsePubKey(key)
+	if err != nil {
+		return NewError(ErrCodeBadRequest, "invalid public key: %v", err)
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return NewError(ErrCodeBadRequest, "invalid signature: %v", err)
+	}
+	s, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return NewError(ErrCodeBadRequest, "invalid signature: %v", err)
+	}
+	hash := sha256.Sum256(message)
+	if !s.Verify(hash[:], publicKey) {
+		return NewError(ErrCodeUnauthorized, "invalid signature")
+	}
+	return nil
+}
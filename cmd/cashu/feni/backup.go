@@ -0,0 +1,318 @@
+package feni
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// backupVersion is the version of the backup file format.
+// Backups with a newer version can not be imported.
+const backupVersion = 1
+
+// backupFile is the content of a backup file. Data is the encrypted walletBackup.
+type backupFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Data    []byte `json:"data"`
+}
+
+// walletBackup contains all data of a wallet, which can not be restored from the mint
+type walletBackup struct {
+	Wallet      string             `json:"wallet"`
+	TimeCreated time.Time          `json:"time_created"`
+	Proofs      []backupProof      `json:"proofs"`
+	KeySets     []backupKeySet     `json:"keysets"`
+	Locks       []cashu.P2SHScript `json:"locks"`
+	Invoices    []invoice.Invoice  `json:"invoices"`
+}
+
+// backupProof is a proof including its reservation, which is not part of the proof json
+type backupProof struct {
+	Id           string            `json:"id"`
+	Amount       uint64            `json:"amount"`
+	Secret       string            `json:"secret"`
+	C            string            `json:"C"`
+	Script       *cashu.P2SHScript `json:"script,omitempty"`
+	Reserved     bool              `json:"reserved"`
+	SendId       uuid.UUID         `json:"send_id"`
+	TimeCreated  time.Time         `json:"time_created"`
+	TimeReserved time.Time         `json:"time_reserved"`
+}
+
+type backupKeySet struct {
+	Id        string    `json:"id"`
+	MintUrl   string    `json:"mint_url"`
+	Unit      string    `json:"unit"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+var backupCommand = &cobra.Command{
+	Use:   "backup <file>",
+	Short: "Export the wallet into an encrypted backup file",
+	Long: `Exports all unspent proofs, pending tokens, locks, keysets and invoices
+into a single file, which is encrypted with a passphrase.`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: PreRunFeni,
+	Run:    backup,
+}
+
+var importCommand = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import an encrypted backup file into the wallet",
+	Long: `Merges a backup file into the wallet. Proofs, which are already in the wallet
+or were spent, are skipped.`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: PreRunFeni,
+	Run:    importBackupCmd,
+}
+
+func init() {
+	RootCmd.AddCommand(backupCommand)
+	RootCmd.AddCommand(importCommand)
+}
+
+func backup(cmd *cobra.Command, args []string) {
+	b, err := newWalletBackup()
+	if err != nil {
+		log.Fatal(err)
+	}
+	passphrase, err := readPassphrase("Backup passphrase: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	confirmation, err := readPassphrase("Repeat backup passphrase: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if passphrase != confirmation {
+		log.Fatal("passphrases do not match")
+	}
+	data, err := encryptBackup(b, passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile(args[0], data, 0600); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Exported %d proofs, %d keysets, %d locks and %d invoices to %s\n",
+		len(b.Proofs), len(b.KeySets), len(b.Locks), len(b.Invoices), args[0])
+}
+
+func importBackupCmd(cmd *cobra.Command, args []string) {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	passphrase, err := readPassphrase("Backup passphrase: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := decryptBackup(data, passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	imported, err := importBackup(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Imported %d of %d proofs from %s\n", imported, len(b.Proofs), args[0])
+}
+
+// newWalletBackup collects all data of the wallet
+func newWalletBackup() (walletBackup, error) {
+	b := walletBackup{Wallet: WalletUsed, TimeCreated: time.Now()}
+	proofs, err := storage.GetProofs()
+	if err != nil {
+		return b, err
+	}
+	for _, p := range proofs {
+		b.Proofs = append(b.Proofs, backupProof{Id: p.Id, Amount: p.Amount, Secret: p.Secret, C: p.C, Script: p.Script,
+			Reserved: p.Reserved, SendId: p.SendId, TimeCreated: p.TimeCreated, TimeReserved: p.TimeReserved})
+	}
+	keySets, err := storage.GetKeySet()
+	if err != nil {
+		return b, err
+	}
+	for _, k := range keySets {
+		b.KeySets = append(b.KeySets, backupKeySet{Id: k.Id, MintUrl: k.MintUrl, Unit: k.Unit, FirstSeen: k.FirstSeen})
+	}
+	if b.Locks, err = storage.GetLocks(""); err != nil {
+		return b, err
+	}
+	for _, paid := range []bool{false, true} {
+		invoices, err := storage.GetInvoices(paid)
+		if err != nil {
+			return b, err
+		}
+		b.Invoices = append(b.Invoices, invoices...)
+	}
+	return b, nil
+}
+
+// encryptBackup encrypts the backup with a key derived from passphrase
+func encryptBackup(b walletBackup, passphrase string) ([]byte, error) {
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	file := backupFile{Version: backupVersion, Salt: make([]byte, 32),
+		N: crypto.DefaultScryptParameters.N, R: crypto.DefaultScryptParameters.R, P: crypto.DefaultScryptParameters.P}
+	if _, err = rand.Read(file.Salt); err != nil {
+		return nil, err
+	}
+	key, err := crypto.DeriveKeyScrypt([]byte(passphrase), file.Salt, crypto.DefaultScryptParameters)
+	if err != nil {
+		return nil, err
+	}
+	if file.Data, err = crypto.EncryptAESGCM(key, plaintext); err != nil {
+		return nil, err
+	}
+	return json.Marshal(file)
+}
+
+// decryptBackup decrypts the backup file with passphrase
+func decryptBackup(data []byte, passphrase string) (walletBackup, error) {
+	b := walletBackup{}
+	file := backupFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return b, fmt.Errorf("invalid backup file: %w", err)
+	}
+	if file.Version < 1 || file.Version > backupVersion {
+		return b, fmt.Errorf("unsupported backup version %d", file.Version)
+	}
+	key, err := crypto.DeriveKeyScrypt([]byte(passphrase), file.Salt, crypto.ScryptParameters{N: file.N, R: file.R, P: file.P})
+	if err != nil {
+		return b, err
+	}
+	plaintext, err := crypto.DecryptAESGCM(key, file.Data)
+	if err != nil {
+		return b, db.ErrWrongPassphrase
+	}
+	err = json.Unmarshal(plaintext, &b)
+	return b, err
+}
+
+// importBackup merges the backup into the wallet and returns the number of imported proofs.
+// Proofs, which are already stored, were spent or are not spendable at their mint are skipped.
+func importBackup(b walletBackup) (int, error) {
+	for _, k := range b.KeySets {
+		existing, err := storage.GetKeySet(db.KeySetWithId(k.Id))
+		if err != nil {
+			return 0, err
+		}
+		if len(existing) == 0 {
+			err = storage.StoreKeySet(crypto.KeySet{Id: k.Id, MintUrl: k.MintUrl, Unit: k.Unit, FirstSeen: k.FirstSeen})
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	for _, lock := range b.Locks {
+		existing, err := storage.GetLocks(lock.Address)
+		if err != nil {
+			return 0, err
+		}
+		if len(existing) == 0 {
+			if err = storage.StoreLock(lock); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := importInvoices(b.Invoices); err != nil {
+		return 0, err
+	}
+	return importProofs(b.Proofs)
+}
+
+func importInvoices(invoices []invoice.Invoice) error {
+	existing := make(map[string]bool)
+	for _, paid := range []bool{false, true} {
+		stored, err := storage.GetInvoices(paid)
+		if err != nil {
+			return err
+		}
+		for _, i := range stored {
+			existing[i.Hash] = true
+		}
+	}
+	for i := range invoices {
+		if existing[invoices[i].Hash] {
+			continue
+		}
+		if err := storage.StoreInvoice(&invoices[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importProofs stores all new proofs, which are spendable at the mint of their keyset
+func importProofs(proofs []backupProof) (int, error) {
+	secrets := make([]string, 0)
+	for _, p := range proofs {
+		secrets = append(secrets, p.Secret)
+	}
+	known := make(map[string]bool)
+	stored, err := storage.GetProofs(db.ProofsWithSecret(secrets...))
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range stored {
+		known[p.Secret] = true
+	}
+	spent, err := storage.GetSpentProofs(secrets...)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range spent {
+		known[p.Secret] = true
+	}
+	// proofs must be checked at the mint of their keyset
+	mints := make(map[string][]cashu.Proof)
+	for _, b := range proofs {
+		if known[b.Secret] {
+			continue
+		}
+		known[b.Secret] = true
+		p := cashu.Proof{Id: b.Id, Amount: b.Amount, Secret: b.Secret, C: b.C, Script: b.Script,
+			Reserved: b.Reserved, SendId: b.SendId, TimeCreated: b.TimeCreated, TimeReserved: b.TimeReserved}
+		mintUrl := Wallet.Client.Url
+		if ks, err := storage.GetKeySet(db.KeySetWithId(p.Id)); err == nil && len(ks) > 0 {
+			mintUrl = ks[0].MintUrl
+		}
+		mints[mintUrl] = append(mints[mintUrl], p)
+	}
+	imported := 0
+	for mintUrl, mintProofs := range mints {
+		resp, err := Client{Url: mintUrl}.Check(cashu.CheckSpendableRequest{Proofs: mintProofs})
+		if err != nil {
+			return imported, err
+		}
+		spendable := make([]cashu.Proof, 0)
+		for i, ok := range resp.Spendable {
+			if ok && i < len(mintProofs) {
+				spendable = append(spendable, mintProofs[i])
+			}
+		}
+		log.Infof("importing %d of %d proofs of mint %s", len(spendable), len(mintProofs), mintUrl)
+		if err = storeProofs(spendable); err != nil {
+			return imported, err
+		}
+		imported += len(spendable)
+	}
+	return imported, nil
+}
@@ -0,0 +1,167 @@
+package feni
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/db"
+	"github.com/google/uuid"
+)
+
+func Test_decryptBackup(t *testing.T) {
+	b := walletBackup{
+		Wallet:  "wallet",
+		Proofs:  []backupProof{{Id: "keyset", Amount: 8, Secret: "secret", C: "C", Reserved: true, SendId: uuid.New()}},
+		KeySets: []backupKeySet{{Id: "keyset", MintUrl: "https://mint.example", Unit: "sat"}},
+		Locks:   []cashu.P2SHScript{{Script: "script", Signature: "signature", Address: "address"}},
+	}
+	data, err := encryptBackup(b, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newerVersion := backupFile{}
+	if err = json.Unmarshal(data, &newerVersion); err != nil {
+		t.Fatal(err)
+	}
+	newerVersion.Version = backupVersion + 1
+	newer, err := json.Marshal(newerVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    bool
+	}{
+		{name: "decrypt", data: data, passphrase: "passphrase"},
+		{name: "wrongPassphrase", data: data, passphrase: "wrong", wantErr: true},
+		{name: "newerVersion", data: newer, passphrase: "passphrase", wantErr: true},
+		{name: "invalidFile", data: []byte("proofs"), passphrase: "passphrase", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptBackup(tt.data, tt.passphrase)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decryptBackup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got.TimeCreated = b.TimeCreated
+			if !reflect.DeepEqual(got, b) {
+				t.Errorf("decryptBackup() = %v, want %v", got, b)
+			}
+		})
+	}
+	if _, err = decryptBackup(data, "wrong"); !errors.Is(err, db.ErrWrongPassphrase) {
+		t.Errorf("decryptBackup() error = %v, want %v", err, db.ErrWrongPassphrase)
+	}
+}
+
+// newTestMint returns a mint, which reports the proofs with secrets in spendable as spendable.
+// The secrets of all checked proofs are recorded in checked.
+func newTestMint(t *testing.T, spendable map[string]bool, checked *[]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/check" {
+			http.NotFound(w, r)
+			return
+		}
+		request := cashu.CheckSpendableRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response := cashu.CheckSpendableResponse{Spendable: make([]bool, 0)}
+		for _, p := range request.Proofs {
+			*checked = append(*checked, p.Secret)
+			response.Spendable = append(response.Spendable, spendable[p.Secret])
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// useTestStorage replaces the storage of the wallet with a migrated sqlite database until the test finished
+func useTestStorage(t *testing.T) {
+	database, err := db.NewSqliteWalletDatabase(&db.SqliteConfig{Path: t.TempDir(), FileName: "wallet.sqlite3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.MigrateSchema(db.WalletMigrations); err != nil {
+		t.Fatal(err)
+	}
+	previousStorage := storage
+	storage = database
+	t.Cleanup(func() {
+		storage = previousStorage
+	})
+}
+
+func Test_importProofs(t *testing.T) {
+	checkedA, checkedB := make([]string, 0), make([]string, 0)
+	mintA := newTestMint(t, map[string]bool{"new": true, "duplicate": true, "unknownKeySet": true}, &checkedA)
+	mintB := newTestMint(t, map[string]bool{"otherMint": true}, &checkedB)
+	useTestStorage(t)
+	previousWallet := Wallet
+	Wallet = MintWallet{Client: &Client{Url: mintA.URL}}
+	t.Cleanup(func() {
+		Wallet = previousWallet
+	})
+	var err error
+	for _, k := range []crypto.KeySet{{Id: "a", MintUrl: mintA.URL}, {Id: "b", MintUrl: mintB.URL}} {
+		if err = storage.StoreKeySet(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = storage.StoreProofs(cashu.Proof{Id: "a", Amount: 1, Secret: "stored", C: "C"}, cashu.Proof{Id: "a", Amount: 2, Secret: "spent", C: "C"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.InvalidateProofs(cashu.Proof{Id: "a", Amount: 2, Secret: "spent", C: "C"}); err != nil {
+		t.Fatal(err)
+	}
+	proofs := []backupProof{
+		{Id: "a", Amount: 1, Secret: "stored", C: "C"},
+		{Id: "a", Amount: 2, Secret: "spent", C: "C"},
+		{Id: "a", Amount: 4, Secret: "notSpendable", C: "C"},
+		{Id: "a", Amount: 8, Secret: "new", C: "C"},
+		{Id: "a", Amount: 16, Secret: "duplicate", C: "C"},
+		{Id: "a", Amount: 16, Secret: "duplicate", C: "C"},
+		{Id: "b", Amount: 32, Secret: "otherMint", C: "C"},
+		{Id: "c", Amount: 64, Secret: "unknownKeySet", C: "C"},
+	}
+	imported, err := importProofs(proofs)
+	if err != nil {
+		t.Fatalf("importProofs() error = %v", err)
+	}
+	if imported != 4 {
+		t.Errorf("importProofs() = %d, want 4", imported)
+	}
+	// known proofs and duplicates are not checked, proofs are checked at the mint of their keyset
+	if want := []string{"notSpendable", "new", "duplicate", "unknownKeySet"}; !reflect.DeepEqual(checkedA, want) {
+		t.Errorf("checked proofs of mint a = %v, want %v", checkedA, want)
+	}
+	if want := []string{"otherMint"}; !reflect.DeepEqual(checkedB, want) {
+		t.Errorf("checked proofs of mint b = %v, want %v", checkedB, want)
+	}
+	stored, err := storage.GetProofs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := make([]string, 0)
+	for _, p := range stored {
+		secrets = append(secrets, p.Secret)
+	}
+	sort.Strings(secrets)
+	if want := []string{"duplicate", "new", "otherMint", "stored", "unknownKeySet"}; !reflect.DeepEqual(secrets, want) {
+		t.Errorf("stored proofs = %v, want %v", secrets, want)
+	}
+}
@@ -2,6 +2,9 @@ package feni
 
 import (
 	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 )
 
@@ -23,7 +26,20 @@ func balance(cmd *cobra.Command, args []string) {
 		panic(err)
 	}
 	fmt.Printf("You have balances in %d keysets:\n", len(balances))
-	for _, setBalance := range balances {
-		fmt.Printf("Keysets: %v Balance: %d sat URL: %s\n", setBalance.Mint.Ks, setBalance.Available, setBalance.Mint.URL)
+	// group balances by unit
+	units := lo.GroupBy[*Balance, string](balances, func(b *Balance) string {
+		return b.Unit
+	})
+	unitNames := lo.Keys[string, []*Balance](units)
+	sort.Strings(unitNames)
+	for _, unit := range unitNames {
+		unitBalances := units[unit]
+		total := lo.SumBy[*Balance, uint64](unitBalances, func(b *Balance) uint64 {
+			return b.Available
+		})
+		fmt.Printf("Balance: %d %s\n", total, unit)
+		for _, setBalance := range unitBalances {
+			fmt.Printf("  Keysets: %v Balance: %d %s URL: %s\n", setBalance.Mint.Ks, setBalance.Available, unit, setBalance.Mint.URL)
+		}
 	}
 }
@@ -17,17 +17,23 @@ type Client struct {
 
 //var WalletClient *Client
 
+// checkError returns the error response of the mint. Error responses can be matched with the errors
+// of the catalog, e.g. errors.Is(err, cashu.ErrTokenSpent).
+// Mints, which do not send http status codes, are supported by checking the error field of the response.
 func checkError(resp *req.Resp) error {
-	if resp.Response().StatusCode >= 300 {
-		var reqErr cashu.ErrorResponse
-		err := resp.ToJSON(&reqErr)
-		if err != nil {
-			return nil
-		}
-		err = reqErr
-		return err
+	var reqErr cashu.ErrorResponse
+	err := resp.ToJSON(&reqErr)
+	status := resp.Response().StatusCode
+	if status < 300 && (err != nil || reqErr.Err == "") {
+		return nil
+	}
+	if err != nil || reqErr.Err == "" {
+		return fmt.Errorf("mint returned http status %d", status)
 	}
-	return nil
+	if reqErr.Code == 0 {
+		reqErr.Code = cashu.ErrCodeBadRequest
+	}
+	return reqErr
 }
 
 func parseKeys(resp *req.Resp, err error) (map[uint64]*secp256k1.PublicKey, error) {
@@ -74,6 +80,21 @@ func (c Client) KeySets() (*cashu.GetKeySetsResponse, error) {
 	return &keySets, nil
 }
 
+// LiabilityReport returns the signed liability report of the keyset with kid
+func (c Client) LiabilityReport(kid string) (*cashu.LiabilityReport, error) {
+	kid = strings.ReplaceAll(strings.ReplaceAll(kid, "/", "_"), "+", "-")
+	resp, err := req.Get(fmt.Sprintf("%s/liabilities/%s", c.Url, kid))
+	if err != nil {
+		return nil, err
+	}
+	if err = checkError(resp); err != nil {
+		return nil, err
+	}
+	report := cashu.LiabilityReport{}
+	err = resp.ToJSON(&report)
+	return &report, err
+}
+
 func (c Client) Check(data cashu.CheckSpendableRequest) (cashu.CheckSpendableResponse, error) {
 	check := cashu.CheckSpendableResponse{}
 	resp, err := req.Post(fmt.Sprintf("%s/check", c.Url), req.BodyJSON(data))
@@ -146,6 +167,19 @@ func (c Client) GetMint(amount int64) (lightning.Invoicer, error) {
 	return invoice, nil
 }
 
+func (c Client) InvoiceStatus(paymentHash string) (*cashu.InvoiceStatusResponse, error) {
+	resp, err := req.Get(fmt.Sprintf("%s/invoice/%s", c.Url, paymentHash))
+	if err != nil {
+		return nil, err
+	}
+	if err = checkError(resp); err != nil {
+		return nil, err
+	}
+	status := cashu.InvoiceStatusResponse{}
+	err = resp.ToJSON(&status)
+	return &status, err
+}
+
 func (c Client) CheckFee(CheckFeesRequest cashu.CheckFeesRequest) (*cashu.CheckFeesResponse, error) {
 	resp, err := req.Post(fmt.Sprintf("%s/checkfees", c.Url), req.BodyJSON(CheckFeesRequest))
 	if err != nil {
@@ -25,6 +25,8 @@ type WalletConfig struct {
 	MintServerHost string `env:"MINT_HOST"`
 	MintServerPort string `env:"MINT_PORT"`
 	Wallet         string `env:"WALLET"`
+	// Passphrase unlocks encrypted wallets without prompting. Only use it for automation.
+	Passphrase string `env:"WALLET_PASSPHRASE"`
 }
 
 func defaultConfig() {
@@ -111,7 +113,7 @@ func (w *MintWallet) loadDefaultMint() {
 		if _, found := lo.Find[crypto.KeySet](w.keySets, func(k crypto.KeySet) bool {
 			return set == k.Id
 		}); !found {
-			err = w.checkAndPersistKeySet(set)
+			err = w.checkAndPersistKeySet(set, k.Units[set])
 			if err != nil {
 				panic(err)
 			}
@@ -124,10 +126,10 @@ func (w *MintWallet) persistCurrentKeysSet() (crypto.KeySet, error) {
 	if err != nil {
 		panic(err)
 	}
-	return w.persistKeysSet(activeKeys)
+	return w.persistKeysSet(activeKeys, crypto.UnitSat)
 }
-func (w *MintWallet) persistKeysSet(keys map[uint64]*secp256k1.PublicKey) (crypto.KeySet, error) {
-	keySet := crypto.KeySet{MintUrl: w.Client.Url, FirstSeen: time.Now(), PublicKeys: crypto.PublicKeyList{}}
+func (w *MintWallet) persistKeysSet(keys map[uint64]*secp256k1.PublicKey, unit string) (crypto.KeySet, error) {
+	keySet := crypto.KeySet{MintUrl: w.Client.Url, FirstSeen: time.Now(), PublicKeys: crypto.PublicKeyList{}, Unit: unit}
 	keySet.SetPublicKeyList(keys)
 	keySet.DeriveKeySetId()
 	err := storage.StoreKeySet(keySet)
@@ -136,7 +138,9 @@ func (w *MintWallet) persistKeysSet(keys map[uint64]*secp256k1.PublicKey) (crypt
 	}
 	return keySet, nil
 }
-func (w *MintWallet) checkAndPersistKeySet(id string) error {
+
+// checkAndPersistKeySet will load the keyset with id and unit from the mint, if it is not stored yet.
+func (w *MintWallet) checkAndPersistKeySet(id, unit string) error {
 	var ks []crypto.KeySet
 	var err error
 	if ks, err = storage.GetKeySet(db.KeySetWithId(id)); err != nil || len(ks) == 0 {
@@ -144,7 +148,7 @@ func (w *MintWallet) checkAndPersistKeySet(id string) error {
 		if err != nil {
 			return err
 		}
-		k, err := w.persistKeysSet(keys)
+		k, err := w.persistKeysSet(keys, unit)
 		ks = append(ks, k)
 		if err != nil {
 			return err
@@ -159,29 +163,15 @@ func InitializeDatabase(wallet string) {
 		log.Fatal(err)
 	}
 	walletPath := path.Join(dirname, ".cashu", wallet)
-	db.Config.Database.Sqlite = &db.SqliteConfig{Path: walletPath, FileName: "wallet.sqlite3"}
 	err = env.Parse(&Config)
 	if err != nil {
 		panic(err)
 	}
-	storage = db.NewSqlDatabase()
-	err = storage.Migrate(cashu.Proof{})
-	if err != nil {
-		panic(err)
-	}
-	err = storage.Migrate(cashu.ProofsUsed{})
-	if err != nil {
-		panic(err)
-	}
-	err = storage.Migrate(crypto.KeySet{})
-	if err != nil {
-		panic(err)
-	}
-	err = storage.Migrate(cashu.P2SHScript{})
+	storage, err = db.NewSqliteWalletDatabase(&db.SqliteConfig{Path: walletPath, FileName: "wallet.sqlite3"})
 	if err != nil {
 		panic(err)
 	}
-	err = storage.Migrate(cashu.CreateInvoice())
+	err = storage.MigrateSchema(db.WalletMigrations)
 	if err != nil {
 		panic(err)
 	}
@@ -14,7 +14,7 @@ import (
 var WalletUsed string
 var Host string
 
-var storage db.MintStorage
+var storage db.WalletStorage
 
 const getWalletsAnnotationValue = "GetWallets"
 
@@ -42,8 +42,9 @@ func PreRunFeni(cmd *cobra.Command, args []string) {
 	// Do not initialize default wallet again
 	InitializeDatabase(WalletUsed)
 	if storage != nil {
+		unlockWallet(WalletUsed)
 		var err error
-		Wallet.proofs, err = storage.GetUsedProofs()
+		Wallet.proofs, err = storage.GetProofs()
 		if err != nil {
 			panic(err)
 		}
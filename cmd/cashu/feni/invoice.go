@@ -5,6 +5,7 @@ import (
 	"github.com/cashubtc/cashu-feni/cashu"
 	"github.com/cashubtc/cashu-feni/db"
 	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lnurl"
 	"github.com/cashubtc/cashu-feni/mint"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
@@ -21,9 +22,11 @@ var invoiceCommand = &cobra.Command{
 	Run:    mintCmd,
 }
 var hash string
+var lnurlWithdraw string
 
 func init() {
 	invoiceCommand.PersistentFlags().StringVarP(&hash, "hash", "", "", "the hash of the mint you want to claim")
+	invoiceCommand.PersistentFlags().StringVarP(&lnurlWithdraw, "lnurlw", "", "", "pay the invoice using a lnurl-withdraw link")
 	RootCmd.AddCommand(invoiceCommand)
 }
 func mintCmd(cmd *cobra.Command, args []string) {
@@ -40,22 +43,44 @@ func mintCmd(cmd *cobra.Command, args []string) {
 			return
 		}
 		if hash == "" {
+			var withdraw *lnurl.WithdrawParams
+			if lnurlWithdraw != "" {
+				withdraw, err = lnurl.FetchWithdrawParams(lnurlWithdraw)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if err = withdraw.CheckAmount(uint64(amount)); err != nil {
+					log.Fatal(err)
+				}
+			}
 			var invoice lightning.Invoicer
 			invoice, err = Wallet.Client.GetMint(int64(amount))
 			if err != nil {
 				panic(err)
 			}
-			err = storage.StoreLightningInvoice(invoice)
+			err = storage.StoreInvoice(invoice)
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			fmt.Printf("Pay invoice to mint %d sat:\n", amount)
-			fmt.Printf("Invoice: %s\n", invoice.GetPaymentRequest())
+			if withdraw != nil {
+				fmt.Printf("Withdrawing %d sat from lnurl ...\n", amount)
+				if err = withdraw.Withdraw(invoice.GetPaymentRequest()); err != nil {
+					log.Fatal(err)
+				}
+			} else {
+				fmt.Printf("Pay invoice to mint %d sat:\n", amount)
+				fmt.Printf("Invoice: %s\n", invoice.GetPaymentRequest())
+			}
 			fmt.Printf("Execute this command if you abort the check:\nfeni invoice {amount} --hash %s\n", invoice.GetHash())
 			fmt.Printf("Checking invoice ...")
 			for {
 				time.Sleep(time.Second * 3)
+				// mints without invoice status support are checked by trying to mint
+				if status, err := Wallet.Client.InvoiceStatus(invoice.GetHash()); err == nil && !status.Paid {
+					fmt.Print(".")
+					continue
+				}
 				proofs := Wallet.mint(splitAmount, invoice.GetHash())
 				if len(proofs) == 0 {
 					fmt.Print(".")
@@ -67,7 +92,7 @@ func mintCmd(cmd *cobra.Command, args []string) {
 					log.Error(err.Error())
 				}
 				fmt.Println("Invoice paid.")
-				err = storage.UpdateLightningInvoice(invoice.GetHash(), db.UpdateInvoicePaid(true))
+				err = storage.UpdateInvoice(invoice.GetHash(), db.UpdateInvoicePaid(true))
 				if err != nil {
 					log.Fatal(err)
 				}
@@ -107,26 +132,9 @@ func invalidate(proofs []cashu.Proof) error {
 	return nil
 }
 func invalidateProof(proof cashu.Proof) error {
-	err := storage.DeleteProof(proof)
-	if err != nil {
-		return err
-	}
-	return storage.StoreUsedProofs(
-		cashu.ProofsUsed{
-			Secret:   proof.Secret,
-			Amount:   proof.Amount,
-			C:        proof.C,
-			TimeUsed: time.Now(),
-		},
-	)
+	return storage.InvalidateProofs(proof)
 }
 func storeProofs(proofs []cashu.Proof) error {
-	for _, proof := range proofs {
-		Wallet.proofs = append(Wallet.proofs, proof)
-		err := storage.StoreProof(proof)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	Wallet.proofs = append(Wallet.proofs, proofs...)
+	return storage.StoreProofs(proofs...)
 }
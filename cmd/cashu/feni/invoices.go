@@ -21,7 +21,7 @@ func init() {
 
 func invoicesCmd(cmd *cobra.Command, args []string) {
 	invoices := make([]invoice.Invoice, 0)
-	invoices, err := storage.GetLightningInvoices(false)
+	invoices, err := storage.GetInvoices(false)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -44,7 +44,7 @@ func createP2SHLock() *cashu.P2SHScript {
 	txInRedeemScriptB64 := base64.URLEncoding.EncodeToString(txInRedeemScript)
 	txInSignatureB64 := base64.URLEncoding.EncodeToString(txInSignature.SignatureScript)
 	p2SHScript := cashu.P2SHScript{Script: txInRedeemScriptB64, Signature: txInSignatureB64, Address: txInP2SHAdress.EncodeAddress()}
-	err = storage.StoreScript(p2SHScript)
+	err = storage.StoreLock(p2SHScript)
 	if err != nil {
 		return nil
 	}
@@ -15,7 +15,7 @@ func init() {
 const getLocksAnnotationValue = "GetLocks"
 
 var GetLocksDynamic = func(annotationValue string) []prompt.Suggest {
-	scripts, err := storage.GetScripts("")
+	scripts, err := storage.GetLocks("")
 	if err != nil {
 		return nil
 	}
@@ -45,7 +45,7 @@ func locks(cmd *cobra.Command, args []string) {
 }
 
 func getP2SHLocks() []cashu.P2SHScript {
-	scripts, err := storage.GetScripts("")
+	scripts, err := storage.GetLocks("")
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,28 @@
+package feni
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(migrateCommand)
+}
+
+var migrateCommand = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the wallet database",
+	Long:  `Applies all pending schema migrations to the wallet database and prints the schema version.`,
+	Run:   migrate,
+}
+
+func migrate(cmd *cobra.Command, args []string) {
+	// migrations are applied, when the database is initialized
+	InitializeDatabase(WalletUsed)
+	version, err := storage.SchemaVersion()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Wallet %s database schema is at version %d\n", WalletUsed, version)
+}
@@ -0,0 +1,78 @@
+package feni
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// passphrases caches the passphrases of unlocked wallets, so that the prompt only asks once
+var passphrases = make(map[string]string)
+
+var passwdCommand = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the wallet passphrase",
+	Long: `Sets a new passphrase for the wallet. If the wallet is not encrypted yet,
+the secrets of all proofs will be encrypted with a key derived from the passphrase.`,
+	PreRun: PreRunFeni,
+	Run:    passwd,
+}
+
+func init() {
+	RootCmd.AddCommand(passwdCommand)
+}
+
+func passwd(cmd *cobra.Command, args []string) {
+	passphrase, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	confirmation, err := readPassphrase("Repeat new passphrase: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if passphrase != confirmation {
+		log.Fatal("passphrases do not match")
+	}
+	if err = storage.ChangePassphrase(passphrase); err != nil {
+		log.Fatal(err)
+	}
+	passphrases[WalletUsed] = passphrase
+	fmt.Printf("Passphrase of wallet %s changed.\n", WalletUsed)
+}
+
+// unlockWallet unlocks the wallet, if it is encrypted.
+// The passphrase is taken from the configuration or read from the terminal.
+func unlockWallet(wallet string) {
+	encrypted, err := storage.Encrypted()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !encrypted {
+		return
+	}
+	passphrase, ok := passphrases[wallet]
+	if !ok {
+		passphrase = Config.Passphrase
+	}
+	if passphrase == "" {
+		passphrase, err = readPassphrase(fmt.Sprintf("Passphrase of wallet %s: ", wallet))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err = storage.Unlock(passphrase); err != nil {
+		log.Fatal(err)
+	}
+	passphrases[wallet] = passphrase
+}
+
+func readPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	return string(passphrase), err
+}
@@ -3,10 +3,12 @@ package feni
 import (
 	"fmt"
 	"github.com/cashubtc/cashu-feni/cashu"
-	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lnurl"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"math"
+	"strconv"
 	"strings"
 )
 
@@ -16,9 +18,9 @@ func init() {
 }
 
 var payCommand = &cobra.Command{
-	Use:    "pay <invoice>",
+	Use:    "pay <invoice|offer|lnurl|address> [amount]",
 	Short:  "Pay lightning invoice",
-	Long:   `Pay a lightning invoice using cashu tokens.`,
+	Long:   `Pay a lightning invoice, BOLT12 offer, lnurl or lightning address using cashu tokens.`,
 	PreRun: PreRunFeni,
 	Run:    pay,
 }
@@ -51,21 +53,47 @@ func ask(cmd *cobra.Command) bool {
 	return false
 }
 func pay(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
+	if len(args) < 1 || len(args) > 2 {
 		cmd.Help()
 		return
 	}
 	invoice := args[0]
-	fee, err := Wallet.Client.CheckFee(cashu.CheckFeesRequest{Pr: invoice})
+	method := cashu.MethodBolt11
+	var offerAmount uint64
+	if lnurl.IsLnurl(invoice) || lnurl.IsLightningAddress(invoice) || isOffer(invoice) {
+		if len(args) != 2 {
+			cmd.Println("amount is required to pay a lnurl, lightning address or offer")
+			return
+		}
+		amount, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			cmd.Println("invalid amount")
+			return
+		}
+		if isOffer(invoice) {
+			method = cashu.MethodBolt12
+			offerAmount = amount
+		} else {
+			invoice, err = resolveLnurlPay(invoice, amount)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	fee, err := Wallet.Client.CheckFee(cashu.CheckFeesRequest{Pr: invoice, Method: method, Amount: offerAmount})
 	if err != nil {
 		log.Fatal(err)
 	}
-	bold, err := decodepay.Decodepay(invoice)
-	if err != nil {
-		cmd.Println("invalid invoice")
-		return
+	amountMsat := offerAmount * 1000
+	if method == cashu.MethodBolt11 {
+		bold, err := lightning.DecodePaymentRequest(invoice)
+		if err != nil {
+			cmd.Println("invalid invoice")
+			return
+		}
+		amountMsat = uint64(bold.MSatoshi)
 	}
-	amount := math.Ceil(float64((uint64(bold.MSatoshi) + fee.Fee*1000) / 1000))
+	amount := math.Ceil(float64((amountMsat + fee.Fee*1000) / 1000))
 	if amount < 0 {
 		log.Fatal("amount is not positive")
 	}
@@ -82,8 +110,14 @@ func pay(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Infof("Paying Lightning invoice ...")
-	changeProofs, err := Wallet.PayLightning(sendProofs, invoice)
+	var changeProofs []cashu.Proof
+	if method == cashu.MethodBolt12 {
+		log.Infof("Paying Lightning offer ...")
+		changeProofs, err = Wallet.PayOffer(sendProofs, invoice, offerAmount)
+	} else {
+		log.Infof("Paying Lightning invoice ...")
+		changeProofs, err = Wallet.PayLightning(sendProofs, invoice)
+	}
 	if changeProofs != nil {
 		err = storeProofs(changeProofs)
 		if err != nil {
@@ -94,3 +128,17 @@ func pay(cmd *cobra.Command, args []string) {
 		log.Fatal(err)
 	}
 }
+
+// isOffer returns true, if s is a BOLT12 offer
+func isOffer(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "lno1")
+}
+
+// resolveLnurlPay will request a lightning invoice for amount from a lnurl-pay service or lightning address.
+func resolveLnurlPay(target string, amount uint64) (string, error) {
+	params, err := lnurl.FetchPayParams(target)
+	if err != nil {
+		return "", err
+	}
+	return params.Invoice(amount)
+}
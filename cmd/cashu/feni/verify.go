@@ -0,0 +1,127 @@
+package feni
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var verifyMintCommand = &cobra.Command{
+	Use:   "verify-mint",
+	Short: "Verify the liability reports of the mint",
+	Long: `Downloads the signed liability report of every keyset of the mint and checks,
+that all signatures received by the wallet are issued and no unspent proof of the wallet is spent.`,
+	PreRun: PreRunFeni,
+	Run:    verifyMintCmd,
+}
+
+func init() {
+	RootCmd.AddCommand(verifyMintCommand)
+}
+
+func verifyMintCmd(cmd *cobra.Command, args []string) {
+	keySets, err := storage.GetKeySet(db.KeySetWithMintUrl(Wallet.Client.Url))
+	if err != nil {
+		log.Fatal(err)
+	}
+	publicKey := ""
+	valid := true
+	for _, keySet := range keySets {
+		report, err := Wallet.Client.LiabilityReport(keySet.Id)
+		if err != nil {
+			fmt.Printf("Keyset %s: could not get liability report: %v\n", keySet.Id, err)
+			valid = false
+			continue
+		}
+		promises, err := storage.GetPromises(keySet.Id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		proofs, err := storage.GetProofs(db.ProofsWithKeySetId(keySet.Id))
+		if err != nil {
+			log.Fatal(err)
+		}
+		publicKey, err = liabilityKey(Wallet.Client.Url, *report)
+		if err != nil {
+			log.Fatal(err)
+		}
+		problems := verifyLiabilityReport(*report, publicKey, promises, proofs)
+		fmt.Printf("Keyset %s: %d %s issued, %d %s redeemed, %d %s outstanding. Checked %d received signatures and %d unspent proofs.\n",
+			keySet.Id, report.IssuedAmount, report.Unit, report.RedeemedAmount, report.Unit, report.Outstanding, report.Unit,
+			len(promises), len(proofs))
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		valid = valid && len(problems) == 0
+	}
+	if publicKey != "" {
+		fmt.Printf("Reports are verified with the liability key %s\n", publicKey)
+	}
+	if !valid {
+		fmt.Println("The mint does not report all of your ecash as liabilities.")
+		os.Exit(1)
+	}
+	fmt.Println("The mint reports all of your ecash as liabilities.")
+}
+
+// liabilityKey returns the liability key of the mint, which is pinned in the wallet.
+// If no key is pinned yet, the key of the report is pinned, if the report is signed with it (trust on first use).
+func liabilityKey(mintUrl string, report cashu.LiabilityReport) (string, error) {
+	pinned, err := storage.GetLiabilityKey(mintUrl)
+	if err != nil {
+		return "", err
+	}
+	if pinned != nil {
+		return pinned.PublicKey, nil
+	}
+	// keys of reports with an invalid signature are not pinned. the report is verified with its own key and fails.
+	if err = report.Verify(report.PublicKey); err != nil {
+		return report.PublicKey, nil
+	}
+	err = storage.StoreLiabilityKey(db.LiabilityKey{MintUrl: mintUrl, PublicKey: report.PublicKey, TimeCreated: time.Now()})
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Pinned liability key %s of mint %s. Reports signed with another key will be reported.\n", report.PublicKey, mintUrl)
+	return report.PublicKey, nil
+}
+
+// verifyLiabilityReport checks, that the report is signed with the trusted publicKey of the mint and
+// that the report contains the received promises of the wallet.
+// Unspent proofs must not be spent in the report, unless they were reserved for sending.
+// It returns the problems found in the report.
+func verifyLiabilityReport(report cashu.LiabilityReport, publicKey string, promises []cashu.Promise, proofs []cashu.Proof) []string {
+	problems := make([]string, 0)
+	if err := report.Verify(publicKey); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid report: %v", err))
+	}
+	issued := make(map[string]cashu.IssuedSignature)
+	for _, s := range report.Issued {
+		issued[s.B_] = s
+	}
+	for _, p := range promises {
+		s, ok := issued[p.B_b]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("received signature of %d %s for %s is not issued", p.Amount, report.Unit, p.B_b))
+			continue
+		}
+		if s.C_ != p.C_c || s.Amount != p.Amount {
+			problems = append(problems, fmt.Sprintf("received signature for %s does not match the issued signature", p.B_b))
+		}
+	}
+	spent := make(map[string]struct{})
+	for _, s := range report.Spent {
+		spent[s.Secret] = struct{}{}
+	}
+	for _, p := range proofs {
+		if _, ok := spent[p.Secret]; ok && !p.Reserved {
+			problems = append(problems, fmt.Sprintf("unspent proof of %d %s is reported as spent", p.Amount, report.Unit))
+		}
+	}
+	return problems
+}
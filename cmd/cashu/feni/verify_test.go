@@ -0,0 +1,105 @@
+package feni
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/cashubtc/cashu-feni/cashu"
+)
+
+// newTestLiabilityReport returns a report of the issued signatures and spent secrets, which is signed with a new key
+func newTestLiabilityReport(t *testing.T, issued []cashu.IssuedSignature, spent []cashu.SpentSecret) cashu.LiabilityReport {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := cashu.LiabilityReport{KeySetId: "keyset", Unit: "sat", Issued: issued, Spent: spent,
+		PublicKey: hex.EncodeToString(schnorr.SerializePubKey(key.PubKey()))}
+	for _, s := range issued {
+		report.IssuedAmount += s.Amount
+	}
+	for _, s := range spent {
+		report.RedeemedAmount += s.Amount
+	}
+	report.Outstanding = int64(report.IssuedAmount) - int64(report.RedeemedAmount)
+	message, err := report.Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(message)
+	signature, err := schnorr.Sign(key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.Signature = hex.EncodeToString(signature.Serialize())
+	return report
+}
+
+func Test_verifyLiabilityReport(t *testing.T) {
+	report := newTestLiabilityReport(t,
+		[]cashu.IssuedSignature{{B_: "B1", C_: "C1", Amount: 1}, {B_: "B2", C_: "C2", Amount: 2}, {B_: "B3", C_: "C3", Amount: 4}},
+		[]cashu.SpentSecret{{Secret: "s1", Amount: 1}, {Secret: "s2", Amount: 2}})
+	invalid := report
+	invalid.Outstanding = 0
+	tests := []struct {
+		name         string
+		report       cashu.LiabilityReport
+		publicKey    string
+		promises     []cashu.Promise
+		proofs       []cashu.Proof
+		wantProblems int
+	}{
+		{name: "valid", report: report,
+			promises: []cashu.Promise{{B_b: "B1", C_c: "C1", Amount: 1}, {B_b: "B3", C_c: "C3", Amount: 4}},
+			proofs:   []cashu.Proof{{Secret: "s3", Amount: 4}}},
+		{name: "invalidSignature", report: invalid, wantProblems: 1},
+		{name: "otherKey", report: report, publicKey: newTestLiabilityReport(t, nil, nil).PublicKey, wantProblems: 1},
+		{name: "notIssued", report: report, promises: []cashu.Promise{{B_b: "B4", C_c: "C4", Amount: 8}}, wantProblems: 1},
+		{name: "otherSignature", report: report, promises: []cashu.Promise{{B_b: "B1", C_c: "C2", Amount: 1}}, wantProblems: 1},
+		{name: "unspentProofSpent", report: report, proofs: []cashu.Proof{{Secret: "s1", Amount: 1}}, wantProblems: 1},
+		{name: "reservedProofSpent", report: report, proofs: []cashu.Proof{{Secret: "s2", Amount: 2, Reserved: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey := tt.publicKey
+			if publicKey == "" {
+				publicKey = report.PublicKey
+			}
+			if got := verifyLiabilityReport(tt.report, publicKey, tt.promises, tt.proofs); len(got) != tt.wantProblems {
+				t.Errorf("verifyLiabilityReport() = %v, want %d problems", got, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func Test_liabilityKey(t *testing.T) {
+	useTestStorage(t)
+	first := newTestLiabilityReport(t, nil, nil)
+	invalid := newTestLiabilityReport(t, nil, nil)
+	invalid.Outstanding = 1
+	tests := []struct {
+		name    string
+		mintUrl string
+		report  cashu.LiabilityReport
+		want    string
+	}{
+		{name: "invalidSignatureNotPinned", mintUrl: "https://a.example", report: invalid, want: invalid.PublicKey},
+		{name: "pinFirstKey", mintUrl: "https://a.example", report: first, want: first.PublicKey},
+		{name: "pinnedKey", mintUrl: "https://a.example", report: newTestLiabilityReport(t, nil, nil), want: first.PublicKey},
+		{name: "otherMint", mintUrl: "https://b.example", report: invalid, want: invalid.PublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := liabilityKey(tt.mintUrl, tt.report)
+			if err != nil {
+				t.Fatalf("liabilityKey() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("liabilityKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
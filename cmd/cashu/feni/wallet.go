@@ -3,6 +3,7 @@ package feni
 import (
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/url"
@@ -74,8 +75,15 @@ func constructOutputs(amounts []uint64, secrets []string) (cashu.MintRequest, []
 }
 
 func (w MintWallet) checkUsedSecrets(amounts []uint64, secrets []string) error {
-	proofs := storage.ProofsUsed(secrets)
-	if len(proofs) > 0 {
+	proofs, err := storage.GetProofs(db.ProofsWithSecret(secrets...))
+	if err != nil {
+		return err
+	}
+	spent, err := storage.GetSpentProofs(secrets...)
+	if err != nil {
+		return err
+	}
+	if len(proofs) > 0 || len(spent) > 0 {
 		return fmt.Errorf("proofs already used")
 	}
 	return nil
@@ -96,7 +104,7 @@ func (w MintWallet) Mint(amount uint64, paymentHash string) ([]cashu.Proof, erro
 		return nil, err
 	}
 	if paymentHash != "" {
-		err = storage.UpdateLightningInvoice(
+		err = storage.UpdateInvoice(
 			hash,
 			db.UpdateInvoicePaid(true),
 			db.UpdateInvoiceTimePaid(time.Now()),
@@ -119,14 +127,24 @@ func (w MintWallet) mint(amounts []uint64, paymentHash string) []cashu.Proof {
 	}
 	req, privateKeys := constructOutputs(amounts, secrets)
 	blindedSignatures, err := w.Client.Mint(req, paymentHash)
+	if errors.Is(err, cashu.ErrQuoteNotPaid) {
+		return nil
+	}
 	if err != nil {
 		panic(err)
 	}
 	return w.constructProofs(blindedSignatures.Promises, secrets, privateKeys)
 }
 
+// constructProofs unblinds the promises to proofs. The promises are stored, to verify the liability reports of the mint.
 func (w MintWallet) constructProofs(promises []cashu.BlindedSignature, secrets []string, privateKeys []*secp256k1.PrivateKey) []cashu.Proof {
 	proofs := make([]cashu.Proof, 0)
+	received := make([]cashu.Promise, 0)
+	defer func() {
+		if err := storage.StorePromises(received...); err != nil {
+			log.Warnf("could not store received promises: %v", err)
+		}
+	}()
 	for i, promise := range promises {
 		h, err := hex.DecodeString(promise.C_)
 		if err != nil {
@@ -143,6 +161,13 @@ func (w MintWallet) constructProofs(promises []cashu.BlindedSignature, secrets [
 			C:      fmt.Sprintf("%x", C.SerializeCompressed()),
 			Secret: secrets[i],
 		})
+		B_, _ := crypto.FirstStepAlice(secrets[i], privateKeys[i])
+		received = append(received, cashu.Promise{
+			B_b:    fmt.Sprintf("%x", B_.SerializeCompressed()),
+			C_c:    promise.C_,
+			Amount: promise.Amount,
+			Id:     w.currentKeySet.Id,
+		})
 	}
 	return proofs
 }
@@ -150,7 +175,9 @@ func (w MintWallet) constructProofs(promises []cashu.BlindedSignature, secrets [
 type Balance struct {
 	Balance   uint64
 	Available uint64
-	Mint      Mint
+	// Unit of the balance
+	Unit string
+	Mint Mint
 }
 type Balances []*Balance
 
@@ -191,6 +218,9 @@ func (w MintWallet) balancePerKeySet() (Balances, error) {
 			}
 			proofBalance.Mint.URL = u.String()
 			proofBalance.Mint.Ks = []string{keySet.Id}
+			proofBalance.Unit = keySet.GetUnit()
+		} else {
+			proofBalance.Unit = crypto.UnitSat
 		}
 		if !foundBalance {
 			balances = append(balances, proofBalance)
@@ -221,7 +251,7 @@ func generateSecret() string {
 	return base64.RawURLEncoding.EncodeToString([]byte(RandStringRunes(16)))
 }
 func getUnusedLocks(addressSplit string) ([]cashu.P2SHScript, error) {
-	return storage.GetScripts(addressSplit)
+	return storage.GetLocks(addressSplit)
 }
 
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
@@ -235,13 +265,25 @@ func RandStringRunes(n int) string {
 }
 
 func (w MintWallet) PayLightning(proofs []cashu.Proof, invoice string) ([]cashu.Proof, error) {
+	return w.melt(proofs, cashu.MeltRequest{Pr: invoice})
+}
+
+// PayOffer will pay amount to the BOLT12 offer
+func (w MintWallet) PayOffer(proofs []cashu.Proof, offer string, amount uint64) ([]cashu.Proof, error) {
+	return w.melt(proofs, cashu.MeltRequest{Pr: offer, Method: cashu.MethodBolt12, Amount: amount})
+}
+
+// melt will melt proofs to pay the payment request of the melt request
+func (w MintWallet) melt(proofs []cashu.Proof, request cashu.MeltRequest) ([]cashu.Proof, error) {
 	secrets := make([]string, 0)
 	amounts := []uint64{0, 0, 0, 0}
 	for i := 0; i < 4; i++ {
 		secrets = append(secrets, generateSecret())
 	}
 	payloads, rs := constructOutputs(amounts, secrets)
-	res, err := w.Client.Melt(cashu.MeltRequest{Proofs: proofs, Pr: invoice, Outputs: payloads.Outputs})
+	request.Proofs = proofs
+	request.Outputs = payloads.Outputs
+	res, err := w.Client.Melt(request)
 	if err != nil {
 		return nil, err
 	}
@@ -306,16 +348,10 @@ func (w MintWallet) SplitToSend(amount uint64, scndSecret string, setReserved bo
 	return keepProofs, SendProofs, err
 }
 func (w MintWallet) setReserved(p []cashu.Proof, reserved bool) error {
-	for _, proof := range p {
-		proof.Reserved = reserved
-		proof.SendId = uuid.New()
-		proof.TimeReserved = time.Now()
-		err := storage.StoreProof(proof)
-		if err != nil {
-			return err
-		}
+	if reserved {
+		return storage.ReserveProofs(p, uuid.New())
 	}
-	return nil
+	return storage.UnreserveProofs(p)
 }
 func (w MintWallet) redeem(proofs []cashu.Proof, scndScript, scndSignature string) (keep []cashu.Proof, send []cashu.Proof, err error) {
 	if scndScript != "" && scndSignature != "" {
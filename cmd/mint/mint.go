@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/cashubtc/cashu-feni/api"
 	_ "github.com/cashubtc/cashu-feni/docs"
 	"github.com/cashubtc/cashu-feni/log"
 	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
 )
 
 // @title Cashu (Feni) golang mint
@@ -13,7 +18,34 @@ import (
 // @contact.url https://8333.space:3338
 func main() {
 	cashuLog.Configure(api.Config.LogLevel)
+	// cashu-feni migrate will only migrate the database schema
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		version, err := api.Migrate()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("database schema is at version %d", version)
+		return
+	}
+	// cashu-feni audit will print the liability and reserve audit report. The exit code is 2, if the mint is insolvent.
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		report, err := api.Audit()
+		if err != nil {
+			log.Fatal(err)
+		}
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(out))
+		if report.Insolvent {
+			os.Exit(2)
+		}
+		return
+	}
 	m := api.New()
 	log.Info("starting (feni) cashu mint server, listening on ", m.HttpServer.Addr)
-	m.StartServer()
+	if err := m.StartServer(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/samber/lo"
 	"math"
@@ -14,8 +15,15 @@ import (
 
 const MaxOrder = 64
 
+// units of keysets. amounts of proofs are denominated in the unit of their keyset.
+const (
+	UnitSat  = "sat"  // UnitSat is the default unit of all keysets
+	UnitMsat = "msat" // UnitMsat is a milli satoshi
+	UnitUsd  = "usd"  // UnitUsd is a us dollar cent
+)
+
 type KeySet struct {
-	Id             string `gorm:"primaryKey"`
+	Id             string `gorm:"primaryKey;size:64"`
 	DerivationPath string
 	PublicKeys     PublicKeyList  `gorm:"-"`
 	PrivateKeys    PrivateKeyList `gorm:"-"`
@@ -24,15 +32,31 @@ type KeySet struct {
 	ValidTo        time.Time
 	FirstSeen      time.Time
 	Active         bool
+	// Unit of all amounts signed by this keyset. Empty for sat keysets created before units were introduced.
+	Unit string
 }
 
 func NewKeySet(masterKey, derivationPath string) *KeySet {
-	ks := &KeySet{DerivationPath: derivationPath}
+	return NewKeySetWithUnit(masterKey, derivationPath, UnitSat)
+}
+
+// NewKeySetWithUnit derives the keyset for unit from the master key.
+// Keysets of other units than sat use a separate derivation path for every unit.
+func NewKeySetWithUnit(masterKey, derivationPath, unit string) *KeySet {
+	ks := &KeySet{DerivationPath: derivationPath, Unit: unit}
 	ks.DeriveKeys(masterKey)
 	ks.DerivePublicKeys()
 	ks.DeriveKeySetId()
 	return ks
 }
+
+// GetUnit returns the unit of the keyset. Keysets without unit are sat keysets.
+func (k KeySet) GetUnit() string {
+	if k.Unit == "" {
+		return UnitSat
+	}
+	return k.Unit
+}
 func (k *KeySet) SetPublicKeyList(keys map[uint64]*secp256k1.PublicKey) {
 	for amount, key := range keys {
 		k.PublicKeys = append(k.PublicKeys, PublicKey{Key: key, Amount: amount})
@@ -40,7 +64,11 @@ func (k *KeySet) SetPublicKeyList(keys map[uint64]*secp256k1.PublicKey) {
 	sort.Sort(k.PublicKeys)
 }
 func (k *KeySet) DeriveKeys(masterKey string) {
-	k.PrivateKeys = deriveKeys(masterKey, k.DerivationPath)
+	derivationPath := k.DerivationPath
+	if k.GetUnit() != UnitSat {
+		derivationPath = fmt.Sprintf("%s/%s", derivationPath, k.Unit)
+	}
+	k.PrivateKeys = deriveKeys(masterKey, derivationPath)
 }
 
 func (k *KeySet) DerivePublicKeys() {
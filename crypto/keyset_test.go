@@ -79,6 +79,33 @@ func TestNewKeySet(t *testing.T) {
 	}
 }
 
+func TestNewKeySetWithUnit(t *testing.T) {
+	type args struct {
+		unit string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantSatKeys bool
+	}{
+		{name: "sat", args: args{unit: UnitSat}, wantSatKeys: true},
+		{name: "msat", args: args{unit: UnitMsat}, wantSatKeys: false},
+		{name: "usd", args: args{unit: UnitUsd}, wantSatKeys: false},
+	}
+	sat := NewKeySet("master", "0/0/0/0")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewKeySetWithUnit("master", "0/0/0/0", tt.args.unit)
+			if got.GetUnit() != tt.args.unit {
+				t.Errorf("NewKeySetWithUnit() unit = %s, want %s", got.GetUnit(), tt.args.unit)
+			}
+			if (got.Id == sat.Id) != tt.wantSatKeys {
+				t.Errorf("NewKeySetWithUnit() id = %s, sat id = %s", got.Id, sat.Id)
+			}
+		})
+	}
+}
+
 func TestKeySet_DeriveKeys(t *testing.T) {
 
 	type args struct {
@@ -0,0 +1,45 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptParameters are the cost parameters of the scrypt key derivation.
+// They are stored together with the salt, so that they can be increased for new keys.
+type ScryptParameters struct {
+	N int
+	R int
+	P int
+}
+
+// DefaultScryptParameters are the recommended interactive login parameters of scrypt
+var DefaultScryptParameters = ScryptParameters{N: 32768, R: 8, P: 1}
+
+// DeriveKeyScrypt derives a 32 byte key from passphrase and salt
+func DeriveKeyScrypt(passphrase, salt []byte, parameters ScryptParameters) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, parameters.N, parameters.R, parameters.P, 32)
+}
+
+// EncryptAESGCMDeterministic encrypts the plaintext using AES-GCM with a nonce derived from key and plaintext.
+// The same plaintext always results in the same ciphertext, which allows lookups of encrypted values.
+// Only use it for unique plaintexts with high entropy (e.g. secrets), since equal plaintexts can be recognized.
+// The ciphertext can be decrypted using DecryptAESGCM.
+func EncryptAESGCMDeterministic(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key[:32])
+	mac.Write(plaintext)
+	nonce := mac.Sum(nil)[:aesgcm.NonceSize()]
+	return aesgcm.Seal(nonce, nonce, plaintext, nil), nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"gorm.io/gorm"
+)
+
+// ArchivedProof is a spent proof of an expired keyset.
+// Archived proofs are not loaded on startup, but are still checked before a proof of an unknown keyset is accepted.
+type ArchivedProof struct {
+	Secret       string `gorm:"primaryKey;size:191"`
+	Id           string `gorm:"index:idx_archived_proofs_id;size:64"`
+	Amount       uint64
+	C            string
+	TimeArchived time.Time
+}
+
+type UpdateKeySetOptions func(k *crypto.KeySet)
+
+func UpdateKeySetActive(active bool) UpdateKeySetOptions {
+	return func(k *crypto.KeySet) {
+		k.Active = active
+	}
+}
+
+func UpdateKeySetValidTo(t time.Time) UpdateKeySetOptions {
+	return func(k *crypto.KeySet) {
+		k.ValidTo = t
+	}
+}
+
+// UpdateKeySet applies options to the stored keyset with id
+func (s SqlDatabase) UpdateKeySet(id string, options ...UpdateKeySetOptions) error {
+	k := crypto.KeySet{}
+	if err := s.db.Where("id = ?", id).Take(&k).Error; err != nil {
+		return err
+	}
+	for _, option := range options {
+		option(&k)
+	}
+	return s.db.Save(&k).Error
+}
+
+// GetProofSecrets returns the secrets of all used proofs, except the proofs of the excluded keysets
+func (s SqlDatabase) GetProofSecrets(excludeKeySetIds ...string) ([]string, error) {
+	secrets := make([]string, 0)
+	tx := s.db.Model(&cashu.Proof{})
+	if len(excludeKeySetIds) > 0 {
+		tx = tx.Where("id NOT IN ?", excludeKeySetIds)
+	}
+	tx = tx.Pluck("secret", &secrets)
+	return secrets, tx.Error
+}
+
+// ArchiveProofs moves all spent proofs of the keysets into the archive and returns the number of archived proofs
+func (s SqlDatabase) ArchiveProofs(keySetIds ...string) (int64, error) {
+	if len(keySetIds) == 0 {
+		return 0, nil
+	}
+	var archived int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		insert := tx.Exec("INSERT INTO archived_proofs (secret, id, amount, c, time_archived) "+
+			"SELECT secret, id, amount, c, ? FROM proofs WHERE id IN ? AND status = ?",
+			time.Now(), keySetIds, cashu.ProofStatusSpent)
+		if insert.Error != nil {
+			return insert.Error
+		}
+		archived = insert.RowsAffected
+		return tx.Where("id IN ? AND status = ?", keySetIds, cashu.ProofStatusSpent).Delete(&cashu.Proof{}).Error
+	})
+	return archived, err
+}
+
+// GetArchivedProofs returns the archived proofs with secrets
+func (s SqlDatabase) GetArchivedProofs(secrets ...string) ([]ArchivedProof, error) {
+	proofs := make([]ArchivedProof, 0)
+	if len(secrets) == 0 {
+		return proofs, nil
+	}
+	tx := s.db.Where("secret IN ?", secrets).Find(&proofs)
+	return proofs, tx.Error
+}
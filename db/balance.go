@@ -0,0 +1,104 @@
+package db
+
+import (
+	"sort"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+)
+
+// KeySetBalance is the amount of issued promises and redeemed proofs of a keyset in the unit of the keyset.
+// Promises, which were stored before promises had a keyset id, are part of the balance with an empty id.
+type KeySetBalance struct {
+	Id       string `json:"id"`
+	Issued   uint64 `json:"issued"`
+	Redeemed uint64 `json:"redeemed"`
+}
+
+// keySetBalances collects the balances of keysets by id
+type keySetBalances map[string]*KeySetBalance
+
+func newKeySetBalances() keySetBalances {
+	return make(keySetBalances)
+}
+
+func (b keySetBalances) get(id string) *KeySetBalance {
+	if _, ok := b[id]; !ok {
+		b[id] = &KeySetBalance{Id: id}
+	}
+	return b[id]
+}
+
+// list returns the balances ordered by keyset id
+func (b keySetBalances) list() []KeySetBalance {
+	balances := make([]KeySetBalance, 0)
+	for _, balance := range b {
+		balances = append(balances, *balance)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Id < balances[j].Id
+	})
+	return balances
+}
+
+// keySetAmount is the sum of amounts of a keyset
+type keySetAmount struct {
+	Id     string
+	Amount uint64
+}
+
+// GetKeySetBalances returns the amount of issued promises and redeemed (spent and archived) proofs of every keyset
+func (s SqlDatabase) GetKeySetBalances() ([]KeySetBalance, error) {
+	balances := newKeySetBalances()
+	issued := make([]keySetAmount, 0)
+	err := s.db.Model(&cashu.Promise{}).Select("COALESCE(id, '') AS id, SUM(amount) AS amount").Group("id").Scan(&issued).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range issued {
+		balances.get(a.Id).Issued += a.Amount
+	}
+	spent := make([]keySetAmount, 0)
+	err = s.db.Model(&cashu.Proof{}).Where("status = ?", cashu.ProofStatusSpent).
+		Select("id, SUM(amount) AS amount").Group("id").Scan(&spent).Error
+	if err != nil {
+		return nil, err
+	}
+	archived := make([]keySetAmount, 0)
+	err = s.db.Model(&ArchivedProof{}).Select("id, SUM(amount) AS amount").Group("id").Scan(&archived).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range append(spent, archived...) {
+		balances.get(a.Id).Redeemed += a.Amount
+	}
+	return balances.list(), nil
+}
+
+// GetPromises returns the promises issued by the keyset
+func (s SqlDatabase) GetPromises(keySetId string) ([]cashu.Promise, error) {
+	promises := make([]cashu.Promise, 0)
+	tx := s.db.Where("id = ?", keySetId).Find(&promises)
+	return promises, tx.Error
+}
+
+// GetRedeemedProofs returns the spent and archived proofs of the keyset
+func (s SqlDatabase) GetRedeemedProofs(keySetId string) ([]cashu.Proof, error) {
+	proofs := make([]cashu.Proof, 0)
+	err := s.db.Where("id = ? AND status = ?", keySetId, cashu.ProofStatusSpent).Find(&proofs).Error
+	if err != nil {
+		return nil, err
+	}
+	archived := make([]ArchivedProof, 0)
+	if err = s.db.Where("id = ?", keySetId).Find(&archived).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range archived {
+		proofs = append(proofs, p.proof())
+	}
+	return proofs, nil
+}
+
+// proof returns the archived proof as spent proof
+func (p ArchivedProof) proof() cashu.Proof {
+	return cashu.Proof{Id: p.Id, Amount: p.Amount, Secret: p.Secret, C: p.C, Status: cashu.ProofStatusSpent}
+}
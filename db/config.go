@@ -4,6 +4,7 @@ package db
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/jinzhu/configor"
 	log "github.com/sirupsen/logrus"
 	"os"
@@ -11,8 +12,11 @@ import (
 
 type Configuration struct {
 	Database struct {
-		MySql  *MySqlConfig  `json:"mySql" yaml:"mySql"`
-		Sqlite *SqliteConfig `json:"sqlite" yaml:"sqlite"`
+		MySql    *MySqlConfig    `json:"mySql" yaml:"mySql"`
+		Sqlite   *SqliteConfig   `json:"sqlite" yaml:"sqlite"`
+		Postgres *PostgresConfig `json:"postgres" yaml:"postgres"`
+		// Memory keeps all data in memory. All data is lost, when the mint stops.
+		Memory bool `json:"memory" yaml:"memory"`
 	} `json:"database" yaml:"database"`
 }
 
@@ -22,8 +26,50 @@ type SqliteConfig struct {
 }
 
 type MySqlConfig struct {
-	Host string `json:"host" yaml:"host"`
-	Port string `json:"port" yaml:"port"`
+	Host     string `json:"host" yaml:"host"`
+	Port     string `json:"port" yaml:"port"`
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password" yaml:"password"`
+	Database string `json:"database" yaml:"database"`
+	// connection pool settings. zero values use the database/sql defaults.
+	MaxOpenConns    int `json:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int `json:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime int `json:"conn_max_lifetime" yaml:"conn_max_lifetime"` // in seconds
+}
+
+// DataSourceName returns the connection string of the mysql database.
+// Times are parsed into time.Time and all strings are stored as utf8mb4.
+func (c MySqlConfig) DataSourceName() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
+		c.User, c.Password, c.Host, c.Port, c.Database)
+}
+
+type PostgresConfig struct {
+	// Dsn is the full connection string. If set, all other connection settings are ignored.
+	Dsn      string `json:"dsn" yaml:"dsn"`
+	Host     string `json:"host" yaml:"host"`
+	Port     string `json:"port" yaml:"port"`
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password" yaml:"password"`
+	Database string `json:"database" yaml:"database"`
+	SslMode  string `json:"ssl_mode" yaml:"ssl_mode"`
+	// connection pool settings. zero values use the database/sql defaults.
+	MaxOpenConns    int `json:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int `json:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime int `json:"conn_max_lifetime" yaml:"conn_max_lifetime"` // in seconds
+}
+
+// DataSourceName returns the connection string of the postgres database
+func (c PostgresConfig) DataSourceName() string {
+	if c.Dsn != "" {
+		return c.Dsn
+	}
+	sslMode := c.SslMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Database, sslMode)
 }
 
 var Config Configuration
@@ -0,0 +1,558 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// MemoryDatabase is a thread safe MintStorage, that keeps all data in memory.
+// All data is lost, when the process exits. Use it for tests and ephemeral (e.g. regtest) mints.
+type MemoryDatabase struct {
+	mu                       sync.RWMutex
+	proofs                   map[string]cashu.Proof
+	proofsUsed               map[string]cashu.ProofsUsed
+	promises                 map[string]cashu.Promise
+	scripts                  []cashu.P2SHScript
+	invoices                 map[string]invoice.Invoice
+	lightningAddresses       map[string]cashu.LightningAddress
+	lightningAddressPayments map[string]cashu.LightningAddressPayment
+	onchainQuotes            map[string]cashu.OnchainQuote
+	keySets                  map[string]crypto.KeySet
+	archivedProofs           map[string]ArchivedProof
+	pendingMelts             map[string]cashu.PendingMelt
+	schemaVersion            uint
+}
+
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{
+		proofs:                   make(map[string]cashu.Proof),
+		proofsUsed:               make(map[string]cashu.ProofsUsed),
+		promises:                 make(map[string]cashu.Promise),
+		scripts:                  make([]cashu.P2SHScript, 0),
+		invoices:                 make(map[string]invoice.Invoice),
+		lightningAddresses:       make(map[string]cashu.LightningAddress),
+		lightningAddressPayments: make(map[string]cashu.LightningAddressPayment),
+		onchainQuotes:            make(map[string]cashu.OnchainQuote),
+		keySets:                  make(map[string]crypto.KeySet),
+		archivedProofs:           make(map[string]ArchivedProof),
+		pendingMelts:             make(map[string]cashu.PendingMelt),
+	}
+}
+
+// errDuplicate is returned, when a record with the same primary key already exists
+func errDuplicate(table, key string) error {
+	return fmt.Errorf("%s: duplicate primary key %s", table, key)
+}
+
+// GetUsedProofs returns all proofs or the proofs with secrets
+func (m *MemoryDatabase) GetUsedProofs(secrets ...string) ([]cashu.Proof, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	proofs := make([]cashu.Proof, 0)
+	if len(secrets) == 0 {
+		for _, p := range m.proofs {
+			proofs = append(proofs, p)
+		}
+		return proofs, nil
+	}
+	for _, secret := range secrets {
+		if p, ok := m.proofs[secret]; ok {
+			proofs = append(proofs, p)
+		}
+	}
+	return proofs, nil
+}
+
+func (m *MemoryDatabase) GetReservedProofs() ([]cashu.Proof, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	proofs := make([]cashu.Proof, 0)
+	for _, p := range m.proofs {
+		if p.Reserved {
+			proofs = append(proofs, p)
+		}
+	}
+	return proofs, nil
+}
+
+func (m *MemoryDatabase) ProofsUsed(in []string) []cashu.Proof {
+	if len(in) == 0 {
+		return make([]cashu.Proof, 0)
+	}
+	proofs, _ := m.GetUsedProofs(in...)
+	return proofs
+}
+
+// StoreProof will store proof. Existing proofs are updated with the status and reservation of p.
+func (m *MemoryDatabase) StoreProof(p cashu.Proof) error {
+	log.WithFields(p.Log()).Info("invalidating proof")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.proofs[p.Secret]; ok {
+		existing.Status = p.Status
+		existing.Reserved = p.Reserved
+		existing.SendId = p.SendId
+		existing.TimeReserved = p.TimeReserved
+		p = existing
+	}
+	// scripts are not stored with the proof
+	p.Script = nil
+	m.proofs[p.Secret] = p
+	return nil
+}
+
+func (m *MemoryDatabase) DeleteProof(proof cashu.Proof) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.proofs, proof.Secret)
+	return nil
+}
+
+func (m *MemoryDatabase) StoreUsedProofs(proof cashu.ProofsUsed) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.proofsUsed[proof.Secret]; ok {
+		return errDuplicate("proofs_useds", proof.Secret)
+	}
+	m.proofsUsed[proof.Secret] = proof
+	return nil
+}
+
+// StorePromise will store promise p
+func (m *MemoryDatabase) StorePromise(p cashu.Promise) error {
+	log.WithFields(p.Log()).Info("storing promise")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.promises[p.B_b]; ok {
+		return errDuplicate("promises", p.B_b)
+	}
+	m.promises[p.B_b] = p
+	return nil
+}
+
+func (m *MemoryDatabase) StoreScript(p cashu.P2SHScript) error {
+	log.Info("storing script")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scripts = append(m.scripts, p)
+	return nil
+}
+
+// GetScripts returns all scripts or the scripts of address
+func (m *MemoryDatabase) GetScripts(address string) ([]cashu.P2SHScript, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	scripts := make([]cashu.P2SHScript, 0)
+	for _, s := range m.scripts {
+		if address == "" || s.Address == address {
+			scripts = append(scripts, s)
+		}
+	}
+	return scripts, nil
+}
+
+// StoreLightningInvoice will store lightning invoice i. Only invoices of type *invoice.Invoice are supported.
+func (m *MemoryDatabase) StoreLightningInvoice(i lightning.Invoicer) error {
+	log.WithFields(i.Log()).Info("storing lightning invoice")
+	in, ok := i.(*invoice.Invoice)
+	if !ok {
+		return fmt.Errorf("unsupported invoice type %T", i)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok = m.invoices[in.Hash]; ok {
+		return errDuplicate("invoices", in.Hash)
+	}
+	m.invoices[in.Hash] = *in
+	return nil
+}
+
+func (m *MemoryDatabase) GetLightningInvoice(hash string) (lightning.Invoicer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i, ok := m.invoices[hash]
+	if !ok {
+		return &invoice.Invoice{Hash: hash}, gorm.ErrRecordNotFound
+	}
+	return &i, nil
+}
+
+func (m *MemoryDatabase) GetLightningInvoices(paid bool, createdAfter time.Time) ([]invoice.Invoice, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	invoices := make([]invoice.Invoice, 0)
+	for _, i := range m.invoices {
+		if i.Paid == paid && (createdAfter.IsZero() || i.Create.After(createdAfter)) {
+			invoices = append(invoices, i)
+		}
+	}
+	return invoices, nil
+}
+
+// UpdateLightningInvoice applies options to the stored invoice with hash
+func (m *MemoryDatabase) UpdateLightningInvoice(hash string, options ...UpdateInvoiceOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, ok := m.invoices[hash]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for _, option := range options {
+		option(&i)
+	}
+	m.invoices[hash] = i
+	return nil
+}
+
+// SetLightningInvoicePaid marks the unpaid invoice as paid. It returns false, if the invoice was paid already.
+func (m *MemoryDatabase) SetLightningInvoicePaid(hash string, timePaid time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, ok := m.invoices[hash]
+	if !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	if i.Paid {
+		return false, nil
+	}
+	i.Paid, i.TimePaid = true, timePaid
+	m.invoices[hash] = i
+	return true, nil
+}
+
+// IssueLightningInvoice marks the invoice as issued. It returns false, if the invoice was issued already.
+func (m *MemoryDatabase) IssueLightningInvoice(hash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, ok := m.invoices[hash]
+	if !ok || i.Issued {
+		return false, nil
+	}
+	i.Issued = true
+	m.invoices[hash] = i
+	return true, nil
+}
+
+func (m *MemoryDatabase) StoreLightningAddress(a cashu.LightningAddress) error {
+	log.WithField("name", a.Name).Info("storing lightning address")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lightningAddresses[a.Name]; ok {
+		return errDuplicate("lightning_addresses", a.Name)
+	}
+	m.lightningAddresses[a.Name] = a
+	return nil
+}
+
+func (m *MemoryDatabase) GetLightningAddress(name string) (cashu.LightningAddress, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.lightningAddresses[name]
+	if !ok {
+		return a, gorm.ErrRecordNotFound
+	}
+	return a, nil
+}
+
+func (m *MemoryDatabase) StoreLightningAddressPayment(p cashu.LightningAddressPayment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lightningAddressPayments[p.Hash] = p
+	return nil
+}
+
+func (m *MemoryDatabase) GetLightningAddressPayment(hash string) (cashu.LightningAddressPayment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.lightningAddressPayments[hash]
+	if !ok {
+		return p, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+// ClaimLightningAddressPayment marks the unclaimed payment as claimed. It returns false, if the payment was claimed already.
+func (m *MemoryDatabase) ClaimLightningAddressPayment(hash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.lightningAddressPayments[hash]
+	if !ok || p.Claimed {
+		return false, nil
+	}
+	p.Claimed = true
+	m.lightningAddressPayments[hash] = p
+	return true, nil
+}
+
+func (m *MemoryDatabase) GetLightningAddressPayments(name string, claimed bool) ([]cashu.LightningAddressPayment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	payments := make([]cashu.LightningAddressPayment, 0)
+	for _, p := range m.lightningAddressPayments {
+		if p.Name == name && p.Claimed == claimed {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+// StoreOnchainQuote will create or update the on-chain quote. Addresses must be unique.
+func (m *MemoryDatabase) StoreOnchainQuote(q cashu.OnchainQuote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, quote := range m.onchainQuotes {
+		if quote.Address == q.Address && quote.Id != q.Id {
+			return errDuplicate("onchain_quotes", q.Address)
+		}
+	}
+	m.onchainQuotes[q.Id] = q
+	return nil
+}
+
+func (m *MemoryDatabase) GetOnchainQuote(id string) (cashu.OnchainQuote, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	q, ok := m.onchainQuotes[id]
+	if !ok {
+		return q, gorm.ErrRecordNotFound
+	}
+	return q, nil
+}
+
+// IssueOnchainQuote marks the quote as paid and issued. It returns false, if the quote was issued already.
+func (m *MemoryDatabase) IssueOnchainQuote(id string, timePaid time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q, ok := m.onchainQuotes[id]
+	if !ok || q.Issued {
+		return false, nil
+	}
+	q.Paid, q.Issued, q.TimePaid = true, true, timePaid
+	m.onchainQuotes[id] = q
+	return true, nil
+}
+
+func (m *MemoryDatabase) StorePendingMelt(melt cashu.PendingMelt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.pendingMelts[melt.Id]; ok {
+		return errDuplicate("pending_melts", melt.Id)
+	}
+	m.pendingMelts[melt.Id] = melt
+	return nil
+}
+
+// GetPendingMelts returns all pending melts or the pending melts with ids ordered by creation time
+func (m *MemoryDatabase) GetPendingMelts(ids ...string) ([]cashu.PendingMelt, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	melts := make([]cashu.PendingMelt, 0)
+	for _, melt := range m.pendingMelts {
+		if len(ids) == 0 || lo.Contains(ids, melt.Id) {
+			melts = append(melts, melt)
+		}
+	}
+	sort.Slice(melts, func(i, j int) bool {
+		return melts[i].TimeCreated.Before(melts[j].TimeCreated)
+	})
+	return melts, nil
+}
+
+func (m *MemoryDatabase) DeletePendingMelt(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pendingMelts, id)
+	return nil
+}
+
+func (m *MemoryDatabase) NextOnchainDerivationIndex() (uint32, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.onchainQuotes) == 0 {
+		return 0, nil
+	}
+	var max uint32
+	for _, q := range m.onchainQuotes {
+		if q.DerivationIndex > max {
+			max = q.DerivationIndex
+		}
+	}
+	return max + 1, nil
+}
+
+// GetKeySet returns all keysets matching options, ordered by id
+func (m *MemoryDatabase) GetKeySet(options ...GetKeySetOptions) ([]crypto.KeySet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	query := newKeySetQuery(options...)
+	ks := make([]crypto.KeySet, 0)
+	for _, k := range m.keySets {
+		if query.matches(k) {
+			ks = append(ks, k)
+		}
+	}
+	sort.Slice(ks, func(i, j int) bool {
+		return ks[i].Id < ks[j].Id
+	})
+	return ks, nil
+}
+
+// StoreKeySet will store keyset k. Like the sql storage, keys are not stored.
+func (m *MemoryDatabase) StoreKeySet(k crypto.KeySet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keySets[k.Id]; ok {
+		return errDuplicate("key_sets", k.Id)
+	}
+	k.PublicKeys = nil
+	k.PrivateKeys = nil
+	m.keySets[k.Id] = k
+	return nil
+}
+
+// UpdateKeySet applies options to the stored keyset with id
+func (m *MemoryDatabase) UpdateKeySet(id string, options ...UpdateKeySetOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keySets[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for _, option := range options {
+		option(&k)
+	}
+	m.keySets[id] = k
+	return nil
+}
+
+// GetProofSecrets returns the secrets of all used proofs, except the proofs of the excluded keysets
+func (m *MemoryDatabase) GetProofSecrets(excludeKeySetIds ...string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	excluded := lo.SliceToMap[string, string, bool](excludeKeySetIds, func(id string) (string, bool) {
+		return id, true
+	})
+	secrets := make([]string, 0)
+	for _, p := range m.proofs {
+		if !excluded[p.Id] {
+			secrets = append(secrets, p.Secret)
+		}
+	}
+	return secrets, nil
+}
+
+// ArchiveProofs moves all spent proofs of the keysets into the archive and returns the number of archived proofs
+func (m *MemoryDatabase) ArchiveProofs(keySetIds ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var archived int64
+	for secret, p := range m.proofs {
+		if p.Status != cashu.ProofStatusSpent || !lo.Contains(keySetIds, p.Id) {
+			continue
+		}
+		m.archivedProofs[secret] = ArchivedProof{Secret: p.Secret, Id: p.Id, Amount: p.Amount, C: p.C, TimeArchived: time.Now()}
+		delete(m.proofs, secret)
+		archived++
+	}
+	return archived, nil
+}
+
+func (m *MemoryDatabase) GetArchivedProofs(secrets ...string) ([]ArchivedProof, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	proofs := make([]ArchivedProof, 0)
+	for _, secret := range secrets {
+		if p, ok := m.archivedProofs[secret]; ok {
+			proofs = append(proofs, p)
+		}
+	}
+	return proofs, nil
+}
+
+// GetKeySetBalances returns the amount of issued promises and redeemed (spent and archived) proofs of every keyset
+func (m *MemoryDatabase) GetKeySetBalances() ([]KeySetBalance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	balances := newKeySetBalances()
+	for _, p := range m.promises {
+		balances.get(p.Id).Issued += p.Amount
+	}
+	for _, p := range m.proofs {
+		if p.Status == cashu.ProofStatusSpent {
+			balances.get(p.Id).Redeemed += p.Amount
+		}
+	}
+	for _, p := range m.archivedProofs {
+		balances.get(p.Id).Redeemed += p.Amount
+	}
+	return balances.list(), nil
+}
+
+// GetPromises returns the promises issued by the keyset
+func (m *MemoryDatabase) GetPromises(keySetId string) ([]cashu.Promise, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	promises := make([]cashu.Promise, 0)
+	for _, p := range m.promises {
+		if p.Id == keySetId {
+			promises = append(promises, p)
+		}
+	}
+	return promises, nil
+}
+
+// GetRedeemedProofs returns the spent and archived proofs of the keyset
+func (m *MemoryDatabase) GetRedeemedProofs(keySetId string) ([]cashu.Proof, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	proofs := make([]cashu.Proof, 0)
+	for _, p := range m.proofs {
+		if p.Id == keySetId && p.Status == cashu.ProofStatusSpent {
+			proofs = append(proofs, p)
+		}
+	}
+	for _, p := range m.archivedProofs {
+		if p.Id == keySetId {
+			proofs = append(proofs, p.proof())
+		}
+	}
+	return proofs, nil
+}
+
+// MigrateSchema only records the latest migration version. The memory storage has no schema.
+func (m *MemoryDatabase) MigrateSchema(migrations []Migration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, migration := range migrations {
+		if migration.Version > m.schemaVersion {
+			m.schemaVersion = migration.Version
+		}
+	}
+	return nil
+}
+
+func (m *MemoryDatabase) SchemaVersion() (uint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.schemaVersion, nil
+}
+
+// Ping always succeeds, because the data is kept in memory
+func (m *MemoryDatabase) Ping() error {
+	return nil
+}
+
+// Close does nothing. The memory storage has nothing to flush.
+func (m *MemoryDatabase) Close() error {
+	return nil
+}
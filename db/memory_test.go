@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+)
+
+func TestMemoryDatabase_Concurrent(t *testing.T) {
+	database := NewMemoryDatabase()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			secret := fmt.Sprintf("secret%d", i)
+			if err := database.StoreProof(cashu.Proof{Amount: 1, Secret: secret, Status: cashu.ProofStatusPending}); err != nil {
+				t.Error(err)
+			}
+			if _, err := database.GetUsedProofs(secret); err != nil {
+				t.Error(err)
+			}
+			if err := database.StoreProof(cashu.Proof{Amount: 1, Secret: secret, Status: cashu.ProofStatusSpent}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	proofs, err := database.GetUsedProofs()
+	if err != nil || len(proofs) != 50 {
+		t.Errorf("GetUsedProofs() = %d proofs, error = %v, want 50", len(proofs), err)
+	}
+}
+
+func TestMemoryDatabase_GetKeySet(t *testing.T) {
+	database := NewMemoryDatabase()
+	for _, k := range []crypto.KeySet{{Id: "b", MintUrl: "https://mint"}, {Id: "a", MintUrl: "https://mint"}, {Id: "c", MintUrl: "https://other"}} {
+		if err := database.StoreKeySet(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.StoreKeySet(crypto.KeySet{Id: "a"}); err == nil {
+		t.Errorf("StoreKeySet() stored duplicate keyset")
+	}
+	tests := []struct {
+		name    string
+		options []GetKeySetOptions
+		want    []string
+	}{
+		{name: "all", want: []string{"a", "b", "c"}},
+		{name: "id", options: []GetKeySetOptions{KeySetWithId("b")}, want: []string{"b"}},
+		{name: "mintUrl", options: []GetKeySetOptions{KeySetWithMintUrl("https://mint")}, want: []string{"a", "b"}},
+		{name: "idAndMintUrl", options: []GetKeySetOptions{KeySetWithId("c"), KeySetWithMintUrl("https://mint")}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := database.GetKeySet(tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0)
+			for _, k := range ks {
+				ids = append(ids, k.Id)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("GetKeySet() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
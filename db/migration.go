@@ -0,0 +1,84 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Migration is a numbered schema change. Migrations are applied in order of their version
+// and every version is only applied once.
+type Migration struct {
+	Version     uint
+	Description string
+	Up          func(tx *gorm.DB) error
+}
+
+// SchemaVersion is a migration, that was applied to the database.
+type SchemaVersion struct {
+	Version     uint `gorm:"primaryKey"`
+	Description string
+	TimeApplied time.Time
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// SchemaVersion returns the version of the latest applied migration. Zero means no migration was applied.
+func (s SqlDatabase) SchemaVersion() (uint, error) {
+	return schemaVersion(s.db)
+}
+
+// MigrateSchema applies all migrations, that were not applied yet.
+// Every migration runs in its own transaction together with the update of the schema version.
+func (s SqlDatabase) MigrateSchema(migrations []Migration) error {
+	return migrateSchema(s.db, migrations)
+}
+
+func schemaVersion(db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable(&SchemaVersion{}) {
+		return 0, nil
+	}
+	var version uint
+	tx := db.Model(&SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version)
+	return version, tx.Error
+}
+
+func migrateSchema(db *gorm.DB, migrations []Migration) error {
+	if err := db.AutoMigrate(&SchemaVersion{}); err != nil {
+		return err
+	}
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	if len(sorted) > 0 && current > sorted[len(sorted)-1].Version {
+		return fmt.Errorf("database schema version %d is newer than the latest known migration %d", current, sorted[len(sorted)-1].Version)
+	}
+	for _, migration := range sorted {
+		if migration.Version <= current {
+			continue
+		}
+		log.WithField("version", migration.Version).Infof("migrating database: %s", migration.Description)
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaVersion{Version: migration.Version, Description: migration.Description, TimeApplied: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+		current = migration.Version
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"gorm.io/gorm"
+)
+
+func TestSqlDatabase_MigrateSchema(t *testing.T) {
+	// initial only contains the first mint migration
+	initial := MintMigrations[:1]
+	// addColumn is a later migration, which adds a column to the proofs table
+	addColumn := Migration{Version: 2, Description: "add proofs note", Up: func(tx *gorm.DB) error {
+		return tx.Exec("ALTER TABLE proofs ADD COLUMN note VARCHAR(64)").Error
+	}}
+	failing := Migration{Version: 3, Description: "failing", Up: func(tx *gorm.DB) error {
+		return fmt.Errorf("failed")
+	}}
+	tests := []struct {
+		name        string
+		migrations  []Migration
+		wantVersion uint
+		wantErr     bool
+	}{
+		{name: "initial", migrations: initial, wantVersion: 1},
+		{name: "again", migrations: initial, wantVersion: 1},
+		{name: "unordered", migrations: []Migration{addColumn, initial[0]}, wantVersion: 2},
+		{name: "failing", migrations: []Migration{initial[0], addColumn, failing}, wantVersion: 2, wantErr: true},
+		{name: "newerDatabase", migrations: initial, wantVersion: 2, wantErr: true},
+	}
+	for name, database := range newEmptyTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			version, err := database.SchemaVersion()
+			if err != nil || version != 0 {
+				t.Fatalf("SchemaVersion() = %d, error = %v, want 0", version, err)
+			}
+			for _, tt := range tests {
+				if err = database.MigrateSchema(tt.migrations); (err != nil) != tt.wantErr {
+					t.Errorf("%s: MigrateSchema() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				}
+				if version, err = database.SchemaVersion(); err != nil || version != tt.wantVersion {
+					t.Errorf("%s: SchemaVersion() = %d, error = %v, want %d", tt.name, version, err, tt.wantVersion)
+				}
+			}
+			if !database.db.Migrator().HasColumn("proofs", "note") {
+				t.Errorf("MigrateSchema() did not add column note")
+			}
+		})
+	}
+}
+
+func TestSqlDatabase_MigrateSchemaAutoMigrated(t *testing.T) {
+	// databases created with AutoMigrate before versioned migrations keep their data
+	for name, database := range newEmptyTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, model := range []interface{}{cashu.Proof{}, cashu.Promise{}, crypto.KeySet{}, invoice.Invoice{}} {
+				if err := database.db.AutoMigrate(model); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := database.StoreProof(cashu.Proof{Id: "keyset", Amount: 1, Secret: "secret", C: "C"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := database.MigrateSchema(MintMigrations); err != nil {
+				t.Fatalf("MigrateSchema() error = %v", err)
+			}
+			if proofs, err := database.GetUsedProofs("secret"); err != nil || len(proofs) != 1 {
+				t.Errorf("GetUsedProofs() = %v, error = %v, want migrated proof", proofs, err)
+			}
+			if !database.db.Migrator().HasTable(&cashu.OnchainQuote{}) {
+				t.Errorf("MigrateSchema() did not create missing tables")
+			}
+		})
+	}
+}
+
+func TestSqlDatabase_MigrateWallet(t *testing.T) {
+	for name, database := range newEmptyTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.MigrateSchema(WalletMigrations); err != nil {
+				t.Fatalf("MigrateSchema() error = %v", err)
+			}
+			for _, model := range []interface{}{cashu.Proof{}, cashu.ProofsUsed{}, crypto.KeySet{}, cashu.P2SHScript{}, invoice.Invoice{}, LiabilityKey{}} {
+				if !database.db.Migrator().HasTable(model) {
+					t.Errorf("MigrateSchema() did not create table of %T", model)
+				}
+			}
+			if database.db.Migrator().HasTable("wallet_counters") {
+				t.Errorf("MigrateSchema() created unused table wallet_counters")
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MintMigrations are all schema migrations of the mint database.
+// Every change of a stored model requires a new migration. Applied migrations must never be changed.
+var MintMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "initial mint schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&proofV1{}, &promiseV1{}, &keySetV1{}, &invoiceV1{},
+				&lightningAddressV1{}, &lightningAddressPaymentV1{}, &onchainQuoteV1{})
+		},
+	},
+	{
+		Version:     2,
+		Description: "add archived proofs",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&archivedProofV2{})
+		},
+	},
+	{
+		Version:     3,
+		Description: "add keyset id to promises",
+		Up: func(tx *gorm.DB) error {
+			// databases created by AutoMigrate may already have the column
+			if !tx.Migrator().HasColumn(&promiseV3{}, "Id") {
+				if err := tx.Migrator().AddColumn(&promiseV3{}, "Id"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasIndex(&promiseV3{}, "idx_promises_id") {
+				return nil
+			}
+			return tx.Migrator().CreateIndex(&promiseV3{}, "idx_promises_id")
+		},
+	},
+	{
+		Version:     4,
+		Description: "add pending melts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&pendingMeltV4{})
+		},
+	},
+}
+
+// WalletMigrations are all schema migrations of the wallet database.
+// Every change of a stored model requires a new migration. Applied migrations must never be changed.
+var WalletMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "initial wallet schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&proofV1{}, &proofsUsedV1{}, &keySetV1{}, &p2shScriptV1{}, &invoiceV1{})
+		},
+	},
+	{
+		Version:     2,
+		Description: "add wallet keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&walletKeyV2{})
+		},
+	},
+	{
+		Version:     3,
+		Description: "add received promises",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&promiseV3{})
+		},
+	},
+	{
+		Version:     4,
+		Description: "add pinned liability keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&liabilityKeyV4{})
+		},
+	},
+}
+
+// The models below are snapshots of the schema at the time of their migration.
+// The initial migration creates missing tables and columns of databases, which were created by AutoMigrate before.
+
+type proofV1 struct {
+	Id           string
+	Amount       uint64
+	Secret       string `gorm:"primaryKey;size:191"`
+	C            string
+	Status       int
+	Reserved     bool
+	SendId       uuid.UUID
+	TimeCreated  time.Time
+	TimeReserved time.Time
+}
+
+func (proofV1) TableName() string {
+	return "proofs"
+}
+
+type proofsUsedV1 struct {
+	Amount   uint64
+	Secret   string `gorm:"primaryKey;size:191"`
+	C        string
+	TimeUsed time.Time
+}
+
+func (proofsUsedV1) TableName() string {
+	return "proofs_useds"
+}
+
+type promiseV1 struct {
+	B_b    string `gorm:"primaryKey;size:66"`
+	C_c    string
+	Amount uint64
+}
+
+func (promiseV1) TableName() string {
+	return "promises"
+}
+
+type promiseV3 struct {
+	B_b    string `gorm:"primaryKey;size:66"`
+	C_c    string
+	Amount uint64
+	Id     string `gorm:"index:idx_promises_id;size:64"`
+}
+
+func (promiseV3) TableName() string {
+	return "promises"
+}
+
+type keySetV1 struct {
+	Id             string `gorm:"primaryKey;size:64"`
+	DerivationPath string
+	MintUrl        string
+	ValidFrom      time.Time
+	ValidTo        time.Time
+	FirstSeen      time.Time
+	Active         bool
+	Unit           string
+}
+
+func (keySetV1) TableName() string {
+	return "key_sets"
+}
+
+type invoiceV1 struct {
+	Amount       int64
+	Pr           string
+	Hash         string `gorm:"primaryKey;size:64"`
+	Issued       bool
+	Preimage     string
+	Paid         bool
+	Create       time.Time
+	TimePaid     time.Time
+	WebhookToken string
+	Unit         string
+	UnitAmount   uint64
+}
+
+func (invoiceV1) TableName() string {
+	return "invoices"
+}
+
+type p2shScriptV1 struct {
+	Script    string
+	Signature string
+	Address   string
+}
+
+func (p2shScriptV1) TableName() string {
+	return "p2_sh_scripts"
+}
+
+type archivedProofV2 struct {
+	Secret       string `gorm:"primaryKey;size:191"`
+	Id           string `gorm:"index:idx_archived_proofs_id;size:64"`
+	Amount       uint64
+	C            string
+	TimeArchived time.Time
+}
+
+func (archivedProofV2) TableName() string {
+	return "archived_proofs"
+}
+
+type walletKeyV2 struct {
+	Id           uint `gorm:"primaryKey"`
+	Salt         []byte
+	N            int
+	R            int
+	P            int
+	EncryptedKey []byte
+	TimeCreated  time.Time
+}
+
+func (walletKeyV2) TableName() string {
+	return "wallet_keys"
+}
+
+type liabilityKeyV4 struct {
+	MintUrl     string `gorm:"primaryKey;size:191"`
+	PublicKey   string `gorm:"size:64"`
+	TimeCreated time.Time
+}
+
+func (liabilityKeyV4) TableName() string {
+	return "liability_keys"
+}
+
+type pendingMeltV4 struct {
+	Id          string `gorm:"primaryKey;size:64"`
+	Method      string
+	Request     string
+	Amount      uint64
+	Unit        string
+	Secrets     []string `gorm:"serializer:json"`
+	TimeCreated time.Time
+}
+
+func (pendingMeltV4) TableName() string {
+	return "pending_melts"
+}
+
+type lightningAddressV1 struct {
+	Name        string `gorm:"primaryKey;size:64"`
+	PublicKey   string
+	TimeCreated time.Time
+}
+
+func (lightningAddressV1) TableName() string {
+	return "lightning_addresses"
+}
+
+type lightningAddressPaymentV1 struct {
+	Hash    string `gorm:"primaryKey;size:64"`
+	Name    string `gorm:"index:idx_lightning_address_payments_name;size:64"`
+	Amount  uint64
+	Claimed bool
+}
+
+func (lightningAddressPaymentV1) TableName() string {
+	return "lightning_address_payments"
+}
+
+type onchainQuoteV1 struct {
+	Id              string `gorm:"primaryKey;size:64"`
+	Address         string `gorm:"uniqueIndex:idx_onchain_quotes_address;size:128"`
+	DerivationIndex uint32
+	Amount          uint64
+	Paid            bool
+	Issued          bool
+	TimeCreated     time.Time
+	TimePaid        time.Time
+}
+
+func (onchainQuoteV1) TableName() string {
+	return "onchain_quotes"
+}
@@ -8,68 +8,132 @@ import (
 	"github.com/cashubtc/cashu-feni/lightning/invoice"
 	cashuLog "github.com/cashubtc/cashu-feni/log"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"os"
 	"path"
+	"time"
 )
 
 type SqlDatabase struct {
 	db *gorm.DB
 }
 
-func NewSqlDatabase() MintStorage {
+// ErrNoDatabase is returned, if no database backend is configured
+var ErrNoDatabase = errors.New("no database configured. please configure postgres, mySql or sqlite")
+
+// NewStorage returns the memory storage, if configured. Otherwise the configured sql database is used.
+func NewStorage() (MintStorage, error) {
+	if Config.Database.Memory {
+		log.Warn("using memory storage. all data is lost, when the mint stops")
+		return NewMemoryDatabase(), nil
+	}
+	return NewSqlDatabase()
+}
+
+func NewSqlDatabase() (MintStorage, error) {
+	if Config.Database.Postgres != nil {
+		return createPostgresDatabase(Config.Database.Postgres)
+	}
+	if Config.Database.MySql != nil {
+		return createMySqlDatabase(Config.Database.MySql)
+	}
 	if Config.Database.Sqlite != nil {
-		return createSqliteDatabase()
+		return createSqliteDatabase(Config.Database.Sqlite)
 	}
-	return nil
+	return nil, ErrNoDatabase
 }
 
-func createSqliteDatabase() MintStorage {
-	filePath := Config.Database.Sqlite.Path
+func createPostgresDatabase(config *PostgresConfig) (SqlDatabase, error) {
+	orm, err := open(postgres.Open(config.DataSourceName()))
+	if err != nil {
+		return SqlDatabase{}, err
+	}
+	err = setConnectionPool(orm, config.MaxOpenConns, config.MaxIdleConns, config.ConnMaxLifetime)
+	return SqlDatabase{db: orm}, err
+}
+
+func createMySqlDatabase(config *MySqlConfig) (SqlDatabase, error) {
+	orm, err := open(mysql.Open(config.DataSourceName()))
+	if err != nil {
+		return SqlDatabase{}, err
+	}
+	err = setConnectionPool(orm, config.MaxOpenConns, config.MaxIdleConns, config.ConnMaxLifetime)
+	return SqlDatabase{db: orm}, err
+}
+
+func createSqliteDatabase(config *SqliteConfig) (SqlDatabase, error) {
+	filePath := config.Path
 	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
 		err = os.MkdirAll(filePath, os.ModePerm)
 		if err != nil {
-			panic(err)
+			return SqlDatabase{}, err
 		}
 	}
+	orm, err := open(sqlite.Open(path.Join(filePath, config.FileName)))
+	if err != nil {
+		return SqlDatabase{}, err
+	}
+	return SqlDatabase{db: orm}, nil
+}
 
-	db := SqlDatabase{db: open(sqlite.Open(path.Join(filePath, Config.Database.Sqlite.FileName)))}
-
-	return db
+// setConnectionPool configures the connection pool of orm. zero values keep the database/sql defaults.
+func setConnectionPool(orm *gorm.DB, maxOpenConns, maxIdleConns, connMaxLifetime int) error {
+	sqlDB, err := orm.DB()
+	if err != nil {
+		return err
+	}
+	if maxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(maxOpenConns)
+	}
+	if maxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(maxIdleConns)
+	}
+	if connMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(time.Duration(connMaxLifetime) * time.Second)
+	}
+	return nil
 }
 
-func open(dialector gorm.Dialector) *gorm.DB {
-	orm, err := gorm.Open(dialector,
+func open(dialector gorm.Dialector) (*gorm.DB, error) {
+	return gorm.Open(dialector,
 		&gorm.Config{DisableForeignKeyConstraintWhenMigrating: true, FullSaveAssociations: true})
+}
+
+// Ping checks the connection to the database
+func (s SqlDatabase) Ping() error {
+	sqlDB, err := s.db.DB()
 	if err != nil {
-		panic(err)
+		return err
 	}
+	return sqlDB.Ping()
+}
 
-	return orm
+// Close closes all connections of the pool, after their queries are finished
+func (s SqlDatabase) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
 
 func (s SqlDatabase) StoreKeySet(k crypto.KeySet) error {
 	return s.db.Create(k).Error
 
 }
-func (s SqlDatabase) Migrate(object interface{}) error {
-	// do not migrate invoice, if lightning is not enabled
-	if object != nil {
-		err := s.db.AutoMigrate(object)
-		if err != nil {
-			panic(err)
-		}
-	}
-	return nil
-}
-
 func (s SqlDatabase) GetKeySet(options ...GetKeySetOptions) ([]crypto.KeySet, error) {
 	ks := make([]crypto.KeySet, 0)
+	query := newKeySetQuery(options...)
 	var tx = s.db
-	for _, o := range options {
-		tx = o(tx)
+	if query.Id != "" {
+		tx = tx.Where("id = ?", query.Id)
+	}
+	if query.MintUrl != "" {
+		tx = tx.Where("mint_url = ?", query.MintUrl)
 	}
 	tx = tx.Find(&ks)
 	return ks, tx.Error
@@ -83,7 +147,7 @@ func (s SqlDatabase) DeleteProof(proof cashu.Proof) error {
 }
 func (s SqlDatabase) ProofsUsed(in []string) []cashu.Proof {
 	proofs := make([]cashu.Proof, 0)
-	s.db.Where(in).Find(&proofs)
+	s.db.Where("secret IN ?", in).Find(&proofs)
 	return proofs
 }
 
@@ -99,19 +163,19 @@ func (s SqlDatabase) GetUsedProofs(secrets ...string) ([]cashu.Proof, error) {
 	proofs := make([]cashu.Proof, len(secrets))
 	var tx = s.db
 	if len(secrets) > 0 {
-		tx = s.db.Where("secret in ?", secrets)
+		tx = s.db.Where("secret IN ?", secrets)
 	}
 	tx = tx.Find(&proofs)
 	return proofs, tx.Error
 }
 
-// StoreProof will write proof to db
+// StoreProof will write proof to db. Existing proofs are updated with the status and reservation of p.
 func (s SqlDatabase) StoreProof(p cashu.Proof) error {
 	log.WithFields(p.Log()).Info("invalidating proof")
 	return s.db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "secret"}}, // key colume
-		DoUpdates: clause.AssignmentColumns([]string{"reserved", "send_id"}),
-	}).Save(&p).Error
+		DoUpdates: clause.AssignmentColumns([]string{"status", "reserved", "send_id", "time_reserved"}),
+	}).Create(&p).Error
 }
 
 func (s SqlDatabase) GetScripts(address string) ([]cashu.P2SHScript, error) {
@@ -140,10 +204,14 @@ func (s SqlDatabase) StoreLightningInvoice(i lightning.Invoicer) error {
 	return s.db.Create(i).Error
 }
 
-// GetLightningInvoices
-func (s SqlDatabase) GetLightningInvoices(paid bool) ([]invoice.Invoice, error) {
+// GetLightningInvoices reads the paid or unpaid lightning invoices, which were created after createdAfter, from db
+func (s SqlDatabase) GetLightningInvoices(paid bool, createdAfter time.Time) ([]invoice.Invoice, error) {
 	invoices := make([]invoice.Invoice, 0)
 	var tx = s.db.Where("paid = ?", paid)
+	if !createdAfter.IsZero() {
+		// create is a reserved word and has to be quoted
+		tx = tx.Where(clause.Gt{Column: clause.Column{Name: "create"}, Value: createdAfter})
+	}
 	tx = tx.Find(&invoices)
 	return invoices, tx.Error
 }
@@ -153,6 +221,9 @@ func (s SqlDatabase) GetLightningInvoice(hash string) (lightning.Invoicer, error
 	i := cashu.CreateInvoice()
 	i.SetHash(hash)
 	tx := s.db.Find(i)
+	if tx.Error == nil && tx.RowsAffected == 0 {
+		return i, gorm.ErrRecordNotFound
+	}
 	log.WithFields(cashuLog.WithLoggable(i, tx.Error)).Info("storing lightning invoice")
 	return i, tx.Error
 }
@@ -168,3 +239,109 @@ func (s SqlDatabase) UpdateLightningInvoice(hash string, options ...UpdateInvoic
 	}
 	return s.db.Save(i).Error
 }
+
+// SetLightningInvoicePaid marks the unpaid invoice as paid with a conditional update, so that concurrent payments can not both succeed
+func (s SqlDatabase) SetLightningInvoicePaid(hash string, timePaid time.Time) (bool, error) {
+	tx := s.db.Model(&invoice.Invoice{}).Where("hash = ? AND paid = ?", hash, false).
+		Updates(map[string]interface{}{"paid": true, "time_paid": timePaid})
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	if tx.RowsAffected == 1 {
+		return true, nil
+	}
+	// the invoice is either paid already or does not exist
+	_, err := s.GetLightningInvoice(hash)
+	return false, err
+}
+
+// IssueLightningInvoice marks the invoice as issued with a conditional update, so that a paid invoice can only be minted once
+func (s SqlDatabase) IssueLightningInvoice(hash string) (bool, error) {
+	tx := s.db.Model(&invoice.Invoice{}).Where("hash = ? AND issued = ?", hash, false).Update("issued", true)
+	return tx.RowsAffected == 1, tx.Error
+}
+
+// StoreLightningAddress will store a new lightning address in db
+func (s SqlDatabase) StoreLightningAddress(a cashu.LightningAddress) error {
+	log.WithField("name", a.Name).Info("storing lightning address")
+	return s.db.Create(&a).Error
+}
+
+// GetLightningAddress reads the lightning address with name from db
+func (s SqlDatabase) GetLightningAddress(name string) (cashu.LightningAddress, error) {
+	a := cashu.LightningAddress{}
+	tx := s.db.Where("name = ?", name).Take(&a)
+	return a, tx.Error
+}
+
+// StoreLightningAddressPayment will create or update the lightning address payment in db
+func (s SqlDatabase) StoreLightningAddressPayment(p cashu.LightningAddressPayment) error {
+	return s.db.Save(&p).Error
+}
+
+// GetLightningAddressPayments reads all claimed or unclaimed payments of a lightning address from db
+func (s SqlDatabase) GetLightningAddressPayments(name string, claimed bool) ([]cashu.LightningAddressPayment, error) {
+	payments := make([]cashu.LightningAddressPayment, 0)
+	tx := s.db.Where("name = ? AND claimed = ?", name, claimed).Find(&payments)
+	return payments, tx.Error
+}
+
+// GetLightningAddressPayment reads the lightning address payment of the invoice with hash from db
+func (s SqlDatabase) GetLightningAddressPayment(hash string) (cashu.LightningAddressPayment, error) {
+	p := cashu.LightningAddressPayment{}
+	tx := s.db.Where("hash = ?", hash).Take(&p)
+	return p, tx.Error
+}
+
+// ClaimLightningAddressPayment marks the unclaimed payment as claimed with a conditional update, so that concurrent claims can not both succeed
+func (s SqlDatabase) ClaimLightningAddressPayment(hash string) (bool, error) {
+	tx := s.db.Model(&cashu.LightningAddressPayment{}).Where("hash = ? AND claimed = ?", hash, false).Update("claimed", true)
+	return tx.RowsAffected == 1, tx.Error
+}
+
+// StoreOnchainQuote will create or update the on-chain quote in db
+func (s SqlDatabase) StoreOnchainQuote(q cashu.OnchainQuote) error {
+	return s.db.Save(&q).Error
+}
+
+// GetOnchainQuote reads the on-chain quote with id from db
+func (s SqlDatabase) GetOnchainQuote(id string) (cashu.OnchainQuote, error) {
+	q := cashu.OnchainQuote{}
+	tx := s.db.Where("id = ?", id).Take(&q)
+	return q, tx.Error
+}
+
+// IssueOnchainQuote marks the quote as issued with a conditional update, so that a paid quote can only be minted once
+func (s SqlDatabase) IssueOnchainQuote(id string, timePaid time.Time) (bool, error) {
+	tx := s.db.Model(&cashu.OnchainQuote{}).Where("id = ? AND issued = ?", id, false).
+		Updates(map[string]interface{}{"paid": true, "issued": true, "time_paid": timePaid})
+	return tx.RowsAffected == 1, tx.Error
+}
+
+// StorePendingMelt will write the pending melt to db
+func (s SqlDatabase) StorePendingMelt(m cashu.PendingMelt) error {
+	return s.db.Create(&m).Error
+}
+
+// GetPendingMelts returns all pending melts or the pending melts with ids
+func (s SqlDatabase) GetPendingMelts(ids ...string) ([]cashu.PendingMelt, error) {
+	melts := make([]cashu.PendingMelt, 0)
+	tx := s.db.Order("time_created")
+	if len(ids) > 0 {
+		tx = tx.Where("id IN ?", ids)
+	}
+	tx = tx.Find(&melts)
+	return melts, tx.Error
+}
+
+// DeletePendingMelt removes the pending melt with id from db
+func (s SqlDatabase) DeletePendingMelt(id string) error {
+	return s.db.Where("id = ?", id).Delete(&cashu.PendingMelt{}).Error
+}
+
+// NextOnchainDerivationIndex returns the derivation index of the next unused receive address
+func (s SqlDatabase) NextOnchainDerivationIndex() (uint32, error) {
+	var index uint32
+	tx := s.db.Model(&cashu.OnchainQuote{}).Select("COALESCE(MAX(derivation_index) + 1, 0)").Scan(&index)
+	return index, tx.Error
+}
@@ -0,0 +1,535 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/google/uuid"
+)
+
+// postgresTestDsn is the environment variable with the connection string of a local postgres test database.
+// Storage tests only run against postgres, if it is set.
+const postgresTestDsn = "POSTGRES_TEST_DSN"
+
+// mySqlTestConfig is the environment variable with the user:password@host:port of a local mysql test server.
+// Storage tests only run against mysql, if it is set.
+const mySqlTestConfig = "MYSQL_TEST_CONFIG"
+
+// newTestDatabases returns a memory storage, a migrated sqlite database and, if configured, postgres and mysql databases.
+func newTestDatabases(t *testing.T) map[string]MintStorage {
+	databases := map[string]MintStorage{"memory": NewMemoryDatabase()}
+	for name, database := range newEmptyTestDatabases(t) {
+		databases[name] = database
+	}
+	for name, database := range databases {
+		if err := database.MigrateSchema(MintMigrations); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	return databases
+}
+
+// newEmptyTestDatabases returns a sqlite database and, if configured, postgres and mysql databases without any tables.
+func newEmptyTestDatabases(t *testing.T) map[string]SqlDatabase {
+	lightning.Config.Lightning.Enabled = true
+	sqlite, err := createSqliteDatabase(&SqliteConfig{Path: t.TempDir(), FileName: "database.sqlite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	databases := map[string]SqlDatabase{"sqlite": sqlite}
+	if dsn := os.Getenv(postgresTestDsn); dsn != "" {
+		databases["postgres"] = newTestPostgresDatabase(t, dsn)
+	}
+	if config := os.Getenv(mySqlTestConfig); config != "" {
+		databases["mysql"] = newTestMySqlDatabase(t, config)
+	}
+	return databases
+}
+
+// newTestPostgresDatabase creates a new schema for the test, which is dropped after the test.
+func newTestPostgresDatabase(t *testing.T, dsn string) SqlDatabase {
+	schema := fmt.Sprintf("test_%s", strings.ReplaceAll(uuid.New().String(), "-", ""))
+	admin, err := createPostgresDatabase(&PostgresConfig{Dsn: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.db.Exec(fmt.Sprintf("CREATE SCHEMA %s", schema)).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.db.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+	})
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn = fmt.Sprintf("%s%ssearch_path=%s", dsn, separator, schema)
+	} else {
+		dsn = fmt.Sprintf("%s search_path=%s", dsn, schema)
+	}
+	database, err := createPostgresDatabase(&PostgresConfig{Dsn: dsn, MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// newTestMySqlDatabase creates a new database for the test, which is dropped after the test.
+func newTestMySqlDatabase(t *testing.T, config string) SqlDatabase {
+	credentials, address, found := strings.Cut(config, "@")
+	if !found {
+		t.Fatalf("invalid %s: %s", mySqlTestConfig, config)
+	}
+	user, password, _ := strings.Cut(credentials, ":")
+	host, port, _ := strings.Cut(address, ":")
+	mySqlConfig := &MySqlConfig{Host: host, Port: port, User: user, Password: password}
+	admin, err := createMySqlDatabase(mySqlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mySqlConfig.Database = fmt.Sprintf("test_%s", strings.ReplaceAll(uuid.New().String(), "-", ""))
+	if err = admin.db.Exec(fmt.Sprintf("CREATE DATABASE %s", mySqlConfig.Database)).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.db.Exec(fmt.Sprintf("DROP DATABASE %s", mySqlConfig.Database))
+	})
+	database, err := createMySqlDatabase(mySqlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestPostgresConfig_DataSourceName(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PostgresConfig
+		want   string
+	}{
+		{name: "dsn", config: PostgresConfig{Dsn: "postgres://cashu@localhost/cashu", Host: "ignored"}, want: "postgres://cashu@localhost/cashu"},
+		{name: "settings", config: PostgresConfig{Host: "localhost", Port: "5432", User: "cashu", Password: "secret", Database: "cashu"},
+			want: "host=localhost port=5432 user=cashu password=secret dbname=cashu sslmode=disable"},
+		{name: "sslMode", config: PostgresConfig{Host: "db", Port: "5432", User: "cashu", Password: "secret", Database: "mint", SslMode: "require"},
+			want: "host=db port=5432 user=cashu password=secret dbname=mint sslmode=require"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.DataSourceName(); got != tt.want {
+				t.Errorf("DataSourceName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySqlConfig_DataSourceName(t *testing.T) {
+	config := MySqlConfig{Host: "localhost", Port: "3306", User: "cashu", Password: "secret", Database: "mint"}
+	want := "cashu:secret@tcp(localhost:3306)/mint?charset=utf8mb4&parseTime=True&loc=UTC"
+	if got := config.DataSourceName(); got != want {
+		t.Errorf("DataSourceName() = %v, want %v", got, want)
+	}
+}
+
+func TestNewSqlDatabase(t *testing.T) {
+	config := Config
+	defer func() { Config = config }()
+	Config = Configuration{}
+	if _, err := NewSqlDatabase(); err != ErrNoDatabase {
+		t.Errorf("NewSqlDatabase() error = %v, want %v", err, ErrNoDatabase)
+	}
+	Config.Database.Sqlite = &SqliteConfig{Path: t.TempDir(), FileName: "database.sqlite"}
+	if storage, err := NewSqlDatabase(); err != nil || storage == nil {
+		t.Errorf("NewSqlDatabase() = %v, error = %v", storage, err)
+	}
+}
+
+func TestMintStorage_StoreProof(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			proof := cashu.Proof{Id: "keyset", Amount: 8, Secret: "secret", C: "C", Status: cashu.ProofStatusPending}
+			if err := database.StoreProof(proof); err != nil {
+				t.Fatalf("StoreProof() error = %v", err)
+			}
+			// storing the same proof again will update status and reservation
+			proof.Status = cashu.ProofStatusSpent
+			proof.Reserved = true
+			proof.SendId = uuid.New()
+			proof.TimeReserved = time.Now()
+			if err := database.StoreProof(proof); err != nil {
+				t.Fatalf("StoreProof() error = %v", err)
+			}
+			if err := database.StoreProof(cashu.Proof{Id: "keyset", Amount: 2, Secret: "other", C: "C"}); err != nil {
+				t.Fatalf("StoreProof() error = %v", err)
+			}
+			proofs, err := database.GetUsedProofs("secret")
+			if err != nil {
+				t.Fatalf("GetUsedProofs() error = %v", err)
+			}
+			if len(proofs) != 1 || proofs[0].Status != cashu.ProofStatusSpent || !proofs[0].Reserved || proofs[0].SendId != proof.SendId {
+				t.Errorf("GetUsedProofs() = %v, want updated proof", proofs)
+			}
+			if proofs, err = database.GetUsedProofs(); err != nil || len(proofs) != 2 {
+				t.Errorf("GetUsedProofs() = %v, error = %v, want 2 proofs", proofs, err)
+			}
+			if proofs, err = database.GetReservedProofs(); err != nil || len(proofs) != 1 {
+				t.Errorf("GetReservedProofs() = %v, error = %v, want 1 proof", proofs, err)
+			}
+			if proofs = database.ProofsUsed([]string{"secret", "unknown"}); len(proofs) != 1 {
+				t.Errorf("ProofsUsed() = %v, want 1 proof", proofs)
+			}
+			if err = database.DeleteProof(proof); err != nil {
+				t.Fatalf("DeleteProof() error = %v", err)
+			}
+			if proofs, err = database.GetUsedProofs("secret"); err != nil || len(proofs) != 0 {
+				t.Errorf("GetUsedProofs() = %v, error = %v, want deleted proof", proofs, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_UpdateLightningInvoice(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.StoreLightningInvoice(&invoice.Invoice{Amount: 10, Hash: "hash", Pr: "lnbc1", Create: time.Now()}); err != nil {
+				t.Fatalf("StoreLightningInvoice() error = %v", err)
+			}
+			if _, err := database.GetLightningInvoice("unknown"); err == nil {
+				t.Errorf("GetLightningInvoice() found unknown invoice")
+			}
+			if err := database.UpdateLightningInvoice("hash", UpdateInvoicePaid(true), UpdateInvoiceTimePaid(time.Now())); err != nil {
+				t.Fatalf("UpdateLightningInvoice() error = %v", err)
+			}
+			i, err := database.GetLightningInvoice("hash")
+			if err != nil {
+				t.Fatalf("GetLightningInvoice() error = %v", err)
+			}
+			if !i.IsPaid() || i.IsIssued() || i.GetAmount() != 10 {
+				t.Errorf("GetLightningInvoice() = %v", i)
+			}
+			if paid, err := database.GetLightningInvoices(true, time.Time{}); err != nil || len(paid) != 1 {
+				t.Errorf("GetLightningInvoices() = %v, error = %v, want 1 invoice", paid, err)
+			}
+			if paid, err := database.GetLightningInvoices(true, time.Now().Add(-time.Hour)); err != nil || len(paid) != 1 {
+				t.Errorf("GetLightningInvoices() = %v, error = %v, want 1 recent invoice", paid, err)
+			}
+			if paid, err := database.GetLightningInvoices(true, time.Now().Add(time.Hour)); err != nil || len(paid) != 0 {
+				t.Errorf("GetLightningInvoices() = %v, error = %v, want no invoice", paid, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_SetLightningInvoicePaid(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.StoreLightningInvoice(&invoice.Invoice{Amount: 10, Hash: "hash", Pr: "lnbc1", Create: time.Now()}); err != nil {
+				t.Fatalf("StoreLightningInvoice() error = %v", err)
+			}
+			tests := []struct {
+				name    string
+				hash    string
+				want    bool
+				wantErr bool
+			}{
+				{name: "unpaid", hash: "hash", want: true},
+				{name: "paid", hash: "hash"},
+				{name: "unknown", hash: "unknown", wantErr: true},
+			}
+			for _, tt := range tests {
+				got, err := database.SetLightningInvoicePaid(tt.hash, time.Now())
+				if (err != nil) != tt.wantErr || got != tt.want {
+					t.Errorf("%s: SetLightningInvoicePaid() = %v, error = %v, want %v", tt.name, got, err, tt.want)
+				}
+			}
+			if i, err := database.GetLightningInvoice("hash"); err != nil || !i.IsPaid() {
+				t.Errorf("GetLightningInvoice() = %v, error = %v, want paid invoice", i, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_NextOnchainDerivationIndex(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			for want := uint32(0); want < 3; want++ {
+				index, err := database.NextOnchainDerivationIndex()
+				if err != nil {
+					t.Fatalf("NextOnchainDerivationIndex() error = %v", err)
+				}
+				if index != want {
+					t.Fatalf("NextOnchainDerivationIndex() = %d, want %d", index, want)
+				}
+				quote := cashu.OnchainQuote{Id: uuid.New().String(), Address: fmt.Sprintf("address%d", index), DerivationIndex: index, TimeCreated: time.Now()}
+				if err = database.StoreOnchainQuote(quote); err != nil {
+					t.Fatalf("StoreOnchainQuote() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestMintStorage_IssueLightningInvoice(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.StoreLightningInvoice(&invoice.Invoice{Amount: 10, Hash: "hash", Pr: "lnbc1", Paid: true, Create: time.Now()}); err != nil {
+				t.Fatalf("StoreLightningInvoice() error = %v", err)
+			}
+			tests := []struct {
+				name string
+				hash string
+				want bool
+			}{
+				{name: "unissued", hash: "hash", want: true},
+				{name: "issued", hash: "hash"},
+				{name: "unknown", hash: "unknown"},
+			}
+			for _, tt := range tests {
+				if got, err := database.IssueLightningInvoice(tt.hash); err != nil || got != tt.want {
+					t.Errorf("%s: IssueLightningInvoice() = %v, error = %v, want %v", tt.name, got, err, tt.want)
+				}
+			}
+			if i, err := database.GetLightningInvoice("hash"); err != nil || !i.IsPaid() || !i.IsIssued() {
+				t.Errorf("GetLightningInvoice() = %v, error = %v, want paid and issued invoice", i, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_GetLightningAddressPayments(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.StoreLightningAddress(cashu.LightningAddress{Name: "alice", PublicKey: "key", TimeCreated: time.Now()}); err != nil {
+				t.Fatalf("StoreLightningAddress() error = %v", err)
+			}
+			payments := []cashu.LightningAddressPayment{{Hash: "a", Name: "alice", Amount: 1}, {Hash: "b", Name: "alice", Amount: 2, Claimed: true}, {Hash: "c", Name: "bob", Amount: 4}}
+			for _, p := range payments {
+				if err := database.StoreLightningAddressPayment(p); err != nil {
+					t.Fatalf("StoreLightningAddressPayment() error = %v", err)
+				}
+			}
+			unclaimed, err := database.GetLightningAddressPayments("alice", false)
+			if err != nil || len(unclaimed) != 1 || unclaimed[0].Hash != "a" {
+				t.Errorf("GetLightningAddressPayments() = %v, error = %v", unclaimed, err)
+			}
+			// a payment can only be claimed once
+			for i, want := range []bool{true, false} {
+				if claimed, err := database.ClaimLightningAddressPayment("a"); err != nil || claimed != want {
+					t.Errorf("ClaimLightningAddressPayment() #%d = %v, error = %v, want %v", i, claimed, err, want)
+				}
+			}
+			if p, err := database.GetLightningAddressPayment("c"); err != nil || p.Name != "bob" {
+				t.Errorf("GetLightningAddressPayment() = %v, error = %v, want payment of bob", p, err)
+			}
+			if _, err := database.GetLightningAddressPayment("unknown"); err == nil {
+				t.Errorf("GetLightningAddressPayment() found unknown payment")
+			}
+			if unclaimed, err = database.GetLightningAddressPayments("alice", false); err != nil || len(unclaimed) != 0 {
+				t.Errorf("GetLightningAddressPayments() = %v, error = %v, want no unclaimed payments", unclaimed, err)
+			}
+			a, err := database.GetLightningAddress("alice")
+			if err != nil || a.PublicKey != "key" {
+				t.Errorf("GetLightningAddress() = %v, error = %v", a, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_ArchiveProofs(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			proofs := []cashu.Proof{
+				{Id: "expired", Amount: 1, Secret: "a", C: "C", Status: cashu.ProofStatusSpent},
+				{Id: "expired", Amount: 2, Secret: "b", C: "C", Status: cashu.ProofStatusPending},
+				{Id: "active", Amount: 4, Secret: "c", C: "C", Status: cashu.ProofStatusSpent},
+			}
+			for _, p := range proofs {
+				if err := database.StoreProof(p); err != nil {
+					t.Fatalf("StoreProof() error = %v", err)
+				}
+			}
+			archived, err := database.ArchiveProofs("expired")
+			if err != nil || archived != 1 {
+				t.Fatalf("ArchiveProofs() = %d, error = %v, want 1 proof", archived, err)
+			}
+			if a, err := database.GetArchivedProofs("a", "b", "c"); err != nil || len(a) != 1 || a[0].Secret != "a" || a[0].Amount != 1 {
+				t.Errorf("GetArchivedProofs() = %v, error = %v, want proof a", a, err)
+			}
+			secrets, err := database.GetProofSecrets()
+			if err != nil || len(secrets) != 2 {
+				t.Errorf("GetProofSecrets() = %v, error = %v, want pending and active proof", secrets, err)
+			}
+			if secrets, err = database.GetProofSecrets("expired"); err != nil || len(secrets) != 1 || secrets[0] != "c" {
+				t.Errorf("GetProofSecrets() = %v, error = %v, want active proof", secrets, err)
+			}
+			if archived, err = database.ArchiveProofs(); err != nil || archived != 0 {
+				t.Errorf("ArchiveProofs() = %d, error = %v, want no proofs", archived, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_UpdateKeySet(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.StoreKeySet(crypto.KeySet{Id: "keyset", Active: true}); err != nil {
+				t.Fatalf("StoreKeySet() error = %v", err)
+			}
+			validTo := time.Now().Add(-time.Minute)
+			if err := database.UpdateKeySet("keyset", UpdateKeySetActive(false), UpdateKeySetValidTo(validTo)); err != nil {
+				t.Fatalf("UpdateKeySet() error = %v", err)
+			}
+			if err := database.UpdateKeySet("unknown", UpdateKeySetActive(false)); err == nil {
+				t.Errorf("UpdateKeySet() updated unknown keyset")
+			}
+			ks, err := database.GetKeySet(KeySetWithId("keyset"))
+			if err != nil || len(ks) != 1 || ks[0].Active || !ks[0].ValidTo.Equal(validTo) {
+				t.Errorf("GetKeySet() = %v, error = %v, want retired keyset", ks, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_GetKeySetBalances(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if balances, err := database.GetKeySetBalances(); err != nil || len(balances) != 0 {
+				t.Fatalf("GetKeySetBalances() = %v, error = %v, want no balances", balances, err)
+			}
+			promises := []cashu.Promise{
+				{B_b: "1", Amount: 1, Id: "expired"},
+				{B_b: "2", Amount: 2, Id: "active"},
+				{B_b: "3", Amount: 4, Id: "active"},
+				{B_b: "4", Amount: 8},
+			}
+			for _, p := range promises {
+				if err := database.StorePromise(p); err != nil {
+					t.Fatalf("StorePromise() error = %v", err)
+				}
+			}
+			proofs := []cashu.Proof{
+				{Id: "expired", Amount: 1, Secret: "a", C: "C", Status: cashu.ProofStatusSpent},
+				{Id: "active", Amount: 2, Secret: "b", C: "C", Status: cashu.ProofStatusPending},
+				{Id: "active", Amount: 4, Secret: "c", C: "C", Status: cashu.ProofStatusSpent},
+			}
+			for _, p := range proofs {
+				if err := database.StoreProof(p); err != nil {
+					t.Fatalf("StoreProof() error = %v", err)
+				}
+			}
+			if _, err := database.ArchiveProofs("expired"); err != nil {
+				t.Fatal(err)
+			}
+			// pending proofs are not redeemed yet. promises without keyset have an empty id.
+			want := []KeySetBalance{
+				{Id: "", Issued: 8},
+				{Id: "active", Issued: 6, Redeemed: 4},
+				{Id: "expired", Issued: 1, Redeemed: 1},
+			}
+			if balances, err := database.GetKeySetBalances(); err != nil || !reflect.DeepEqual(balances, want) {
+				t.Errorf("GetKeySetBalances() = %v, error = %v, want %v", balances, err, want)
+			}
+		})
+	}
+}
+
+func TestMintStorage_GetPendingMelts(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now().Truncate(time.Second)
+			melts := []cashu.PendingMelt{
+				{Id: "b", Method: cashu.MethodBolt11, Request: "lnbc", Amount: 8, Secrets: []string{"s3"}, TimeCreated: now.Add(time.Second)},
+				{Id: "a", Method: cashu.MethodBolt11, Request: "lnbc", Amount: 3, Secrets: []string{"s1", "s2"}, TimeCreated: now},
+			}
+			for _, m := range melts {
+				if err := database.StorePendingMelt(m); err != nil {
+					t.Fatalf("StorePendingMelt() error = %v", err)
+				}
+			}
+			if err := database.StorePendingMelt(melts[0]); err == nil {
+				t.Errorf("StorePendingMelt() stored melt twice")
+			}
+			tests := []struct {
+				ids  []string
+				want []string
+			}{
+				{want: []string{"a", "b"}},
+				{ids: []string{"b"}, want: []string{"b"}},
+				{ids: []string{"c"}, want: []string{}},
+			}
+			for _, tt := range tests {
+				got, err := database.GetPendingMelts(tt.ids...)
+				if err != nil {
+					t.Fatalf("GetPendingMelts() error = %v", err)
+				}
+				ids := make([]string, 0)
+				for _, m := range got {
+					ids = append(ids, m.Id)
+				}
+				if !reflect.DeepEqual(ids, tt.want) {
+					t.Errorf("GetPendingMelts(%v) = %v, want %v", tt.ids, ids, tt.want)
+				}
+			}
+			if got, err := database.GetPendingMelts("a"); err != nil || !reflect.DeepEqual(got[0].Secrets, melts[1].Secrets) {
+				t.Errorf("GetPendingMelts() = %v, error = %v, want secrets %v", got, err, melts[1].Secrets)
+			}
+			if err := database.DeletePendingMelt("a"); err != nil {
+				t.Fatalf("DeletePendingMelt() error = %v", err)
+			}
+			if got, err := database.GetPendingMelts(); err != nil || len(got) != 1 {
+				t.Errorf("GetPendingMelts() = %v, error = %v, want 1 melt", got, err)
+			}
+		})
+	}
+}
+
+func TestMintStorage_Close(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.Ping(); err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+			if err := database.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if _, ok := database.(SqlDatabase); ok && database.Ping() == nil {
+				t.Errorf("Ping() of closed database succeeded")
+			}
+		})
+	}
+}
+
+func TestMintStorage_IssueOnchainQuote(t *testing.T) {
+	for name, database := range newTestDatabases(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := database.StoreOnchainQuote(cashu.OnchainQuote{Id: "quote", Address: "address", Amount: 8, TimeCreated: time.Now()}); err != nil {
+				t.Fatalf("StoreOnchainQuote() error = %v", err)
+			}
+			tests := []struct {
+				name string
+				id   string
+				want bool
+			}{
+				{name: "unissued", id: "quote", want: true},
+				{name: "issued", id: "quote"},
+				{name: "unknown", id: "unknown"},
+			}
+			for _, tt := range tests {
+				if got, err := database.IssueOnchainQuote(tt.id, time.Now()); err != nil || got != tt.want {
+					t.Errorf("%s: IssueOnchainQuote() = %v, error = %v, want %v", tt.name, got, err, tt.want)
+				}
+			}
+			if q, err := database.GetOnchainQuote("quote"); err != nil || !q.Paid || !q.Issued {
+				t.Errorf("GetOnchainQuote() = %+v, error = %v, want issued quote", q, err)
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/cashubtc/cashu-feni/crypto"
 	"github.com/cashubtc/cashu-feni/lightning"
 	"github.com/cashubtc/cashu-feni/lightning/invoice"
-	"gorm.io/gorm"
 	"time"
 )
 
@@ -21,26 +20,84 @@ type MintStorage interface {
 	GetScripts(address string) ([]cashu.P2SHScript, error)
 	StoreLightningInvoice(i lightning.Invoicer) error
 	GetLightningInvoice(hash string) (lightning.Invoicer, error)
-	GetLightningInvoices(paid bool) ([]invoice.Invoice, error) // todo -- the return type of this interface function must be of type lightning.Invoicer
+	// GetLightningInvoices returns the invoices, which were created after createdAfter. A zero createdAfter matches all invoices.
+	GetLightningInvoices(paid bool, createdAfter time.Time) ([]invoice.Invoice, error) // todo -- the return type of this interface function must be of type lightning.Invoicer
 	UpdateLightningInvoice(hash string, options ...UpdateInvoiceOptions) error
+	// SetLightningInvoicePaid marks the invoice as paid, unless it is paid already. It returns false, if the invoice was paid already.
+	SetLightningInvoicePaid(hash string, timePaid time.Time) (bool, error)
+	// IssueLightningInvoice marks the invoice as issued, unless it is issued already. It returns false, if the invoice was issued already.
+	IssueLightningInvoice(hash string) (bool, error)
+	StoreLightningAddress(a cashu.LightningAddress) error
+	GetLightningAddress(name string) (cashu.LightningAddress, error)
+	StoreLightningAddressPayment(p cashu.LightningAddressPayment) error
+	GetLightningAddressPayments(name string, claimed bool) ([]cashu.LightningAddressPayment, error)
+	// GetLightningAddressPayment returns the lightning address payment of the invoice with hash
+	GetLightningAddressPayment(hash string) (cashu.LightningAddressPayment, error)
+	// ClaimLightningAddressPayment marks the payment as claimed, unless it is claimed already. It returns false, if the payment was claimed already.
+	ClaimLightningAddressPayment(hash string) (bool, error)
+	StoreOnchainQuote(q cashu.OnchainQuote) error
+	GetOnchainQuote(id string) (cashu.OnchainQuote, error)
+	// IssueOnchainQuote marks the quote as paid and issued, unless it is issued already. It returns false, if the quote was issued already.
+	IssueOnchainQuote(id string, timePaid time.Time) (bool, error)
+	NextOnchainDerivationIndex() (uint32, error)
 	GetKeySet(options ...GetKeySetOptions) ([]crypto.KeySet, error)
 	StoreKeySet(k crypto.KeySet) error
-	Migrate(interface{}) error
+	UpdateKeySet(id string, options ...UpdateKeySetOptions) error
+	// GetProofSecrets returns the secrets of all used proofs, except the proofs of the excluded keysets
+	GetProofSecrets(excludeKeySetIds ...string) ([]string, error)
+	// ArchiveProofs moves all spent proofs of the keysets into the archive and returns the number of archived proofs
+	ArchiveProofs(keySetIds ...string) (int64, error)
+	GetArchivedProofs(secrets ...string) ([]ArchivedProof, error)
+	// GetKeySetBalances returns the amount of issued promises and redeemed (spent and archived) proofs of every keyset
+	GetKeySetBalances() ([]KeySetBalance, error)
+	// GetPromises returns the promises issued by the keyset
+	GetPromises(keySetId string) ([]cashu.Promise, error)
+	// GetRedeemedProofs returns the spent and archived proofs of the keyset
+	GetRedeemedProofs(keySetId string) ([]cashu.Proof, error)
+	StorePendingMelt(m cashu.PendingMelt) error
+	// GetPendingMelts returns all pending melts or the pending melts with ids
+	GetPendingMelts(ids ...string) ([]cashu.PendingMelt, error)
+	DeletePendingMelt(id string) error
+	MigrateSchema(migrations []Migration) error
+	SchemaVersion() (uint, error)
+	// Ping checks, if the database is reachable
+	Ping() error
+	// Close flushes and closes the database. The storage can not be used afterwards.
+	Close() error
 }
 
 func KeySetWithId(id string) GetKeySetOptions {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Where("id = ?", id)
+	return func(query *KeySetQuery) {
+		query.Id = id
 	}
 }
 
 func KeySetWithMintUrl(mintUrl string) GetKeySetOptions {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Where("mint_url = ?", mintUrl)
+	return func(query *KeySetQuery) {
+		query.MintUrl = mintUrl
 	}
 }
 
-type GetKeySetOptions func(db *gorm.DB) *gorm.DB
+// KeySetQuery filters the keysets of GetKeySet. Empty fields match all keysets.
+type KeySetQuery struct {
+	Id      string
+	MintUrl string
+}
+
+func newKeySetQuery(options ...GetKeySetOptions) KeySetQuery {
+	query := KeySetQuery{}
+	for _, o := range options {
+		o(&query)
+	}
+	return query
+}
+
+// matches returns true, if k matches all filters of the query
+func (q KeySetQuery) matches(k crypto.KeySet) bool {
+	return (q.Id == "" || q.Id == k.Id) && (q.MintUrl == "" || q.MintUrl == k.MintUrl)
+}
+
+type GetKeySetOptions func(query *KeySetQuery)
 
 func UpdateInvoiceWithIssued(issued bool) UpdateInvoiceOptions {
 	return func(invoice lightning.Invoicer) {
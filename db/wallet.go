@@ -0,0 +1,108 @@
+package db
+
+import (
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/google/uuid"
+)
+
+// WalletStorage stores the data of a wallet.
+// Proofs of the wallet are unspent, until they are invalidated. Invalidated proofs are kept in the spent history.
+type WalletStorage interface {
+	// StoreProofs will add unspent proofs to the wallet
+	StoreProofs(proofs ...cashu.Proof) error
+	// GetProofs returns the unspent proofs of the wallet, including reserved proofs
+	GetProofs(options ...GetProofsOptions) ([]cashu.Proof, error)
+	// GetReservedProofs returns all unspent proofs, that were reserved for sending
+	GetReservedProofs() ([]cashu.Proof, error)
+	// ReserveProofs will reserve proofs for sending with sendId
+	ReserveProofs(proofs []cashu.Proof, sendId uuid.UUID) error
+	// UnreserveProofs will release the reservation of proofs
+	UnreserveProofs(proofs []cashu.Proof) error
+	// InvalidateProofs will remove spent proofs from the wallet and add them to the spent history
+	InvalidateProofs(proofs ...cashu.Proof) error
+	// GetSpentProofs returns the spent history or the spent proofs with secrets
+	GetSpentProofs(secrets ...string) ([]cashu.ProofsUsed, error)
+
+	StoreInvoice(i lightning.Invoicer) error
+	GetInvoices(paid bool) ([]invoice.Invoice, error)
+	UpdateInvoice(hash string, options ...UpdateInvoiceOptions) error
+
+	// StoreLock will store the P2SH script of a lock
+	StoreLock(script cashu.P2SHScript) error
+	// GetLocks returns all locks or the locks of address
+	GetLocks(address string) ([]cashu.P2SHScript, error)
+
+	StoreKeySet(k crypto.KeySet) error
+	GetKeySet(options ...GetKeySetOptions) ([]crypto.KeySet, error)
+
+	// StorePromises will store the blinded signatures received from mints
+	StorePromises(promises ...cashu.Promise) error
+	// GetPromises returns the received blinded signatures of the keysets
+	GetPromises(keySetIds ...string) ([]cashu.Promise, error)
+
+	// StoreLiabilityKey will pin the public key, which signs the liability reports of the mint
+	StoreLiabilityKey(key LiabilityKey) error
+	// GetLiabilityKey returns the pinned liability key of the mint. It returns nil, if no key is pinned.
+	GetLiabilityKey(mintUrl string) (*LiabilityKey, error)
+
+	// Encrypted returns true, if the proof secrets of the wallet are encrypted
+	Encrypted() (bool, error)
+	// Unlock decrypts the wallet key with passphrase. Proofs of encrypted wallets can only be accessed after unlocking.
+	Unlock(passphrase string) error
+	// ChangePassphrase sets the passphrase of the wallet. Unencrypted wallets will be encrypted.
+	ChangePassphrase(passphrase string) error
+
+	MigrateSchema(migrations []Migration) error
+	SchemaVersion() (uint, error)
+}
+
+// LiabilityKey is the public key, which signed the first liability report of a mint, that was verified by the wallet.
+// Later reports of the mint must be signed with the same key.
+type LiabilityKey struct {
+	MintUrl     string `gorm:"primaryKey;size:191"`
+	PublicKey   string `gorm:"size:64"`
+	TimeCreated time.Time
+}
+
+// ProofsQuery filters the proofs of GetProofs. Empty fields match all proofs.
+type ProofsQuery struct {
+	KeySetIds []string
+	MintUrl   string
+	Secrets   []string
+}
+
+type GetProofsOptions func(query *ProofsQuery)
+
+// ProofsWithKeySetId only returns proofs of the keysets with ids
+func ProofsWithKeySetId(ids ...string) GetProofsOptions {
+	return func(query *ProofsQuery) {
+		query.KeySetIds = append(query.KeySetIds, ids...)
+	}
+}
+
+// ProofsWithMintUrl only returns proofs of keysets of the mint with mintUrl
+func ProofsWithMintUrl(mintUrl string) GetProofsOptions {
+	return func(query *ProofsQuery) {
+		query.MintUrl = mintUrl
+	}
+}
+
+// ProofsWithSecret only returns the proofs with secrets
+func ProofsWithSecret(secrets ...string) GetProofsOptions {
+	return func(query *ProofsQuery) {
+		query.Secrets = append(query.Secrets, secrets...)
+	}
+}
+
+func newProofsQuery(options ...GetProofsOptions) ProofsQuery {
+	query := ProofsQuery{}
+	for _, o := range options {
+		o(&query)
+	}
+	return query
+}
@@ -0,0 +1,241 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ErrWalletLocked is returned, if proofs of an encrypted wallet are accessed before it was unlocked
+var ErrWalletLocked = errors.New("wallet is encrypted. please unlock it with your passphrase")
+
+// ErrWrongPassphrase is returned, if the wallet key can not be decrypted with the passphrase
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// WalletKey is the envelope of the key, which encrypts the proof secrets of the wallet.
+// The key is encrypted with a key derived from the passphrase, so that changing the passphrase
+// does not require to encrypt all proofs again.
+type WalletKey struct {
+	Id           uint `gorm:"primaryKey"`
+	Salt         []byte
+	N            int
+	R            int
+	P            int
+	EncryptedKey []byte
+	TimeCreated  time.Time
+}
+
+// walletKeyId is the id of the only wallet key
+const walletKeyId = 1
+
+func (s *SqlWalletDatabase) getWalletKey() (*WalletKey, error) {
+	keys := make([]WalletKey, 0)
+	if err := s.db.Where("id = ?", walletKeyId).Limit(1).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
+
+// Encrypted returns true, if the proof secrets of the wallet are encrypted
+func (s *SqlWalletDatabase) Encrypted() (bool, error) {
+	k, err := s.getWalletKey()
+	return k != nil, err
+}
+
+// Unlock decrypts the wallet key with passphrase. Unlocking an unencrypted wallet does nothing.
+func (s *SqlWalletDatabase) Unlock(passphrase string) error {
+	k, err := s.getWalletKey()
+	if err != nil || k == nil {
+		return err
+	}
+	kek, err := crypto.DeriveKeyScrypt([]byte(passphrase), k.Salt, crypto.ScryptParameters{N: k.N, R: k.R, P: k.P})
+	if err != nil {
+		return err
+	}
+	key, err := crypto.DecryptAESGCM(kek, k.EncryptedKey)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	s.key = key
+	return nil
+}
+
+// ChangePassphrase encrypts the wallet key with passphrase. Encrypted wallets must be unlocked before.
+// If the wallet is not encrypted yet, a new wallet key is created and all proof secrets are encrypted.
+func (s *SqlWalletDatabase) ChangePassphrase(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase must not be empty")
+	}
+	encrypted, err := s.Encrypted()
+	if err != nil {
+		return err
+	}
+	if encrypted && s.key == nil {
+		return ErrWalletLocked
+	}
+	key := s.key
+	if !encrypted {
+		key = make([]byte, 32)
+		if _, err = rand.Read(key); err != nil {
+			return err
+		}
+	}
+	salt := make([]byte, 32)
+	if _, err = rand.Read(salt); err != nil {
+		return err
+	}
+	parameters := crypto.DefaultScryptParameters
+	kek, err := crypto.DeriveKeyScrypt([]byte(passphrase), salt, parameters)
+	if err != nil {
+		return err
+	}
+	encryptedKey, err := crypto.EncryptAESGCM(kek, key)
+	if err != nil {
+		return err
+	}
+	walletKey := WalletKey{Id: walletKeyId, Salt: salt, N: parameters.N, R: parameters.R, P: parameters.P,
+		EncryptedKey: encryptedKey, TimeCreated: time.Now()}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if !encrypted {
+			if err := encryptStoredProofs(tx, key); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&walletKey).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.key = key
+	if !encrypted {
+		return s.removeDeletedSecrets()
+	}
+	return nil
+}
+
+// removeDeletedSecrets overwrites the plaintext secrets, which remain in the free pages and the write-ahead log
+// of the database file after the proofs were encrypted. VACUUM can not run in a transaction.
+func (s *SqlWalletDatabase) removeDeletedSecrets() error {
+	if err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error; err != nil {
+		return err
+	}
+	return s.db.Exec("VACUUM").Error
+}
+
+// encryptStoredProofs replaces all plaintext proofs of the wallet with encrypted proofs
+func encryptStoredProofs(tx *gorm.DB, key []byte) error {
+	proofs := make([]cashu.Proof, 0)
+	if err := tx.Find(&proofs).Error; err != nil {
+		return err
+	}
+	if len(proofs) == 0 {
+		return nil
+	}
+	log.Infof("encrypting %d proofs", len(proofs))
+	encrypted := make([]cashu.Proof, 0)
+	for _, proof := range proofs {
+		secret, err := encryptSecret(key, proof.Secret)
+		if err != nil {
+			return err
+		}
+		proof.Secret = secret
+		encrypted = append(encrypted, proof)
+	}
+	if err := tx.Where("secret IN ?", proofSecrets(proofs)).Delete(&cashu.Proof{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&encrypted).Error
+}
+
+func encryptSecret(key []byte, secret string) (string, error) {
+	ciphertext, err := crypto.EncryptAESGCMDeterministic(key, []byte(secret))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func decryptSecret(key []byte, secret string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := crypto.DecryptAESGCM(key, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// unlocked returns ErrWalletLocked, if the wallet is encrypted and was not unlocked
+func (s *SqlWalletDatabase) unlocked() error {
+	if s.key != nil {
+		return nil
+	}
+	encrypted, err := s.Encrypted()
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return ErrWalletLocked
+	}
+	return nil
+}
+
+// encryptSecrets returns the stored representation of secrets
+func (s *SqlWalletDatabase) encryptSecrets(secrets []string) ([]string, error) {
+	if err := s.unlocked(); err != nil {
+		return nil, err
+	}
+	if s.key == nil {
+		return secrets, nil
+	}
+	encrypted := make([]string, 0)
+	for _, secret := range secrets {
+		e, err := encryptSecret(s.key, secret)
+		if err != nil {
+			return nil, err
+		}
+		encrypted = append(encrypted, e)
+	}
+	return encrypted, nil
+}
+
+func (s *SqlWalletDatabase) encryptProofs(proofs []cashu.Proof) ([]cashu.Proof, error) {
+	secrets, err := s.encryptSecrets(proofSecrets(proofs))
+	if err != nil {
+		return nil, err
+	}
+	encrypted := make([]cashu.Proof, 0)
+	for i, proof := range proofs {
+		proof.Secret = secrets[i]
+		encrypted = append(encrypted, proof)
+	}
+	return encrypted, nil
+}
+
+func (s *SqlWalletDatabase) decryptProofs(proofs []cashu.Proof) ([]cashu.Proof, error) {
+	if err := s.unlocked(); err != nil {
+		return nil, err
+	}
+	if s.key == nil {
+		return proofs, nil
+	}
+	for i := range proofs {
+		secret, err := decryptSecret(s.key, proofs[i].Secret)
+		if err != nil {
+			return nil, err
+		}
+		proofs[i].Secret = secret
+	}
+	return proofs, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+)
+
+func TestWalletStorage_ChangePassphrase(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	if err := database.ChangePassphrase("first"); err != nil {
+		t.Fatalf("ChangePassphrase() error = %v", err)
+	}
+	// secrets must not be stored in plaintext
+	stored := make([]cashu.Proof, 0)
+	if err := database.(*SqlWalletDatabase).db.Where("secret IN ?", []string{"a1", "a2", "b1"}).Find(&stored).Error; err != nil || len(stored) != 0 {
+		t.Errorf("stored proofs = %v, error = %v, want encrypted secrets", stored, err)
+	}
+	if proofs, err := database.GetProofs(ProofsWithSecret("a2")); err != nil || len(proofs) != 1 || proofs[0].Amount != 2 {
+		t.Errorf("GetProofs() = %v, error = %v, want proof a2", proofs, err)
+	}
+	if err := database.StoreProofs(cashu.Proof{Id: "b", Amount: 8, Secret: "b2", C: "C"}); err != nil {
+		t.Fatalf("StoreProofs() error = %v", err)
+	}
+	if err := database.ChangePassphrase("second"); err != nil {
+		t.Fatalf("ChangePassphrase() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		wantErr    error
+	}{
+		{name: "locked", wantErr: ErrWalletLocked},
+		{name: "oldPassphrase", passphrase: "first", wantErr: ErrWrongPassphrase},
+		{name: "passphrase", passphrase: "second"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reopened := &SqlWalletDatabase{db: database.(*SqlWalletDatabase).db}
+			if encrypted, err := reopened.Encrypted(); err != nil || !encrypted {
+				t.Fatalf("Encrypted() = %v, error = %v, want encrypted wallet", encrypted, err)
+			}
+			if tt.passphrase != "" {
+				err := reopened.Unlock(tt.passphrase)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Unlock() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+			proofs, err := reopened.GetProofs()
+			if tt.wantErr != nil {
+				if !errors.Is(err, ErrWalletLocked) {
+					t.Errorf("GetProofs() error = %v, want %v", err, ErrWalletLocked)
+				}
+				if err = reopened.StoreProofs(cashu.Proof{Id: "a", Amount: 1, Secret: "plaintext", C: "C"}); !errors.Is(err, ErrWalletLocked) {
+					t.Errorf("StoreProofs() error = %v, want %v", err, ErrWalletLocked)
+				}
+				return
+			}
+			if err != nil || len(proofs) != 4 {
+				t.Fatalf("GetProofs() = %v, error = %v, want 4 proofs", proofs, err)
+			}
+			if err = reopened.InvalidateProofs(proofs[0]); err != nil {
+				t.Fatalf("InvalidateProofs() error = %v", err)
+			}
+			if spent, err := reopened.GetSpentProofs(proofs[0].Secret); err != nil || len(spent) != 1 {
+				t.Errorf("GetSpentProofs() = %v, error = %v, want 1 proof", spent, err)
+			}
+		})
+	}
+}
+
+func TestWalletStorage_ChangePassphrase_removesPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	database, err := NewSqliteWalletDatabase(&SqliteConfig{Path: dir, FileName: "wallet.sqlite3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.MigrateSchema(WalletMigrations); err != nil {
+		t.Fatal(err)
+	}
+	secret := "plaintext-secret-3f9a1c2e"
+	proofs := make([]cashu.Proof, 0)
+	for i := 0; i < 100; i++ {
+		proofs = append(proofs, cashu.Proof{Id: "a", Amount: 1, Secret: fmt.Sprintf("%s-%d", secret, i), C: "C"})
+	}
+	if err = database.StoreProofs(proofs...); err != nil {
+		t.Fatalf("StoreProofs() error = %v", err)
+	}
+	if err = database.ChangePassphrase("passphrase"); err != nil {
+		t.Fatalf("ChangePassphrase() error = %v", err)
+	}
+	// deleted plaintext secrets must not remain in free pages or the write-ahead log
+	for _, name := range []string{"wallet.sqlite3", "wallet.sqlite3-wal"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if bytes.Contains(content, []byte(secret)) {
+			t.Errorf("%s contains the plaintext secret", name)
+		}
+	}
+}
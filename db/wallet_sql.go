@@ -0,0 +1,213 @@
+package db
+
+import (
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SqlWalletDatabase is the sql implementation of WalletStorage
+type SqlWalletDatabase struct {
+	db *gorm.DB
+	// key encrypts the proof secrets. It is nil, if the wallet is not encrypted or not unlocked.
+	key []byte
+}
+
+// NewSqliteWalletDatabase opens the sqlite wallet database
+func NewSqliteWalletDatabase(config *SqliteConfig) (WalletStorage, error) {
+	database, err := createSqliteDatabase(config)
+	if err != nil {
+		return nil, err
+	}
+	return &SqlWalletDatabase{db: database.db}, nil
+}
+
+func (s *SqlWalletDatabase) StoreProofs(proofs ...cashu.Proof) error {
+	if len(proofs) == 0 {
+		return nil
+	}
+	encrypted, err := s.encryptProofs(proofs)
+	if err != nil {
+		return err
+	}
+	return s.db.Create(&encrypted).Error
+}
+
+func (s *SqlWalletDatabase) GetProofs(options ...GetProofsOptions) ([]cashu.Proof, error) {
+	proofs := make([]cashu.Proof, 0)
+	query := newProofsQuery(options...)
+	secrets, err := s.encryptSecrets(query.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	tx := s.db
+	if len(query.KeySetIds) > 0 {
+		tx = tx.Where("id IN ?", query.KeySetIds)
+	}
+	if query.MintUrl != "" {
+		tx = tx.Where("id IN (?)", s.db.Model(&crypto.KeySet{}).Select("id").Where("mint_url = ?", query.MintUrl))
+	}
+	if len(secrets) > 0 {
+		tx = tx.Where("secret IN ?", secrets)
+	}
+	if err = tx.Find(&proofs).Error; err != nil {
+		return nil, err
+	}
+	return s.decryptProofs(proofs)
+}
+
+func (s *SqlWalletDatabase) GetReservedProofs() ([]cashu.Proof, error) {
+	proofs := make([]cashu.Proof, 0)
+	if err := s.db.Where("reserved = ?", true).Find(&proofs).Error; err != nil {
+		return nil, err
+	}
+	return s.decryptProofs(proofs)
+}
+
+func (s *SqlWalletDatabase) ReserveProofs(proofs []cashu.Proof, sendId uuid.UUID) error {
+	secrets, err := s.encryptSecrets(proofSecrets(proofs))
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&cashu.Proof{}).Where("secret IN ?", secrets).
+		Updates(map[string]interface{}{"reserved": true, "send_id": sendId, "time_reserved": time.Now()}).Error
+}
+
+func (s *SqlWalletDatabase) UnreserveProofs(proofs []cashu.Proof) error {
+	secrets, err := s.encryptSecrets(proofSecrets(proofs))
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&cashu.Proof{}).Where("secret IN ?", secrets).
+		Updates(map[string]interface{}{"reserved": false, "send_id": uuid.Nil}).Error
+}
+
+// InvalidateProofs will move proofs to the spent history in one transaction
+func (s *SqlWalletDatabase) InvalidateProofs(proofs ...cashu.Proof) error {
+	if len(proofs) == 0 {
+		return nil
+	}
+	spent := make([]cashu.ProofsUsed, 0)
+	for _, proof := range proofs {
+		log.WithFields(proof.Log()).Info("invalidating proof")
+		spent = append(spent, cashu.ProofsUsed{Secret: proof.Secret, Amount: proof.Amount, C: proof.C, TimeUsed: time.Now()})
+	}
+	secrets, err := s.encryptSecrets(proofSecrets(proofs))
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("secret IN ?", secrets).Delete(&cashu.Proof{}).Error; err != nil {
+			return err
+		}
+		// proofs may already be in the spent history
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&spent).Error
+	})
+}
+
+func (s *SqlWalletDatabase) GetSpentProofs(secrets ...string) ([]cashu.ProofsUsed, error) {
+	proofs := make([]cashu.ProofsUsed, 0)
+	tx := s.db
+	if len(secrets) > 0 {
+		tx = tx.Where("secret IN ?", secrets)
+	}
+	tx = tx.Find(&proofs)
+	return proofs, tx.Error
+}
+
+func (s *SqlWalletDatabase) StoreInvoice(i lightning.Invoicer) error {
+	log.WithFields(i.Log()).Info("storing lightning invoice")
+	return s.db.Create(i).Error
+}
+
+func (s *SqlWalletDatabase) GetInvoices(paid bool) ([]invoice.Invoice, error) {
+	invoices := make([]invoice.Invoice, 0)
+	tx := s.db.Where("paid = ?", paid).Find(&invoices)
+	return invoices, tx.Error
+}
+
+func (s *SqlWalletDatabase) UpdateInvoice(hash string, options ...UpdateInvoiceOptions) error {
+	i := &invoice.Invoice{}
+	if err := s.db.Where("hash = ?", hash).Take(i).Error; err != nil {
+		return err
+	}
+	for _, option := range options {
+		option(i)
+	}
+	return s.db.Save(i).Error
+}
+
+func (s *SqlWalletDatabase) StoreLock(script cashu.P2SHScript) error {
+	log.Info("storing script")
+	return s.db.Create(&script).Error
+}
+
+func (s *SqlWalletDatabase) GetLocks(address string) ([]cashu.P2SHScript, error) {
+	scripts := make([]cashu.P2SHScript, 0)
+	tx := s.db
+	if address != "" {
+		tx = tx.Where("address = ?", address)
+	}
+	tx = tx.Find(&scripts)
+	return scripts, tx.Error
+}
+
+func (s *SqlWalletDatabase) StoreKeySet(k crypto.KeySet) error {
+	return s.db.Create(k).Error
+}
+
+func (s *SqlWalletDatabase) GetKeySet(options ...GetKeySetOptions) ([]crypto.KeySet, error) {
+	return SqlDatabase{db: s.db}.GetKeySet(options...)
+}
+
+func (s *SqlWalletDatabase) StorePromises(promises ...cashu.Promise) error {
+	if len(promises) == 0 {
+		return nil
+	}
+	return s.db.Create(&promises).Error
+}
+
+func (s *SqlWalletDatabase) GetPromises(keySetIds ...string) ([]cashu.Promise, error) {
+	promises := make([]cashu.Promise, 0)
+	tx := s.db.Where("id IN ?", keySetIds).Find(&promises)
+	return promises, tx.Error
+}
+
+func (s *SqlWalletDatabase) StoreLiabilityKey(key LiabilityKey) error {
+	return s.db.Create(&key).Error
+}
+
+func (s *SqlWalletDatabase) GetLiabilityKey(mintUrl string) (*LiabilityKey, error) {
+	keys := make([]LiabilityKey, 0)
+	if err := s.db.Where("mint_url = ?", mintUrl).Limit(1).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
+
+func (s *SqlWalletDatabase) MigrateSchema(migrations []Migration) error {
+	return migrateSchema(s.db, migrations)
+}
+
+func (s *SqlWalletDatabase) SchemaVersion() (uint, error) {
+	return schemaVersion(s.db)
+}
+
+// proofSecrets returns the secrets of proofs
+func proofSecrets(proofs []cashu.Proof) []string {
+	s := make([]string, 0)
+	for _, p := range proofs {
+		s = append(s, p.Secret)
+	}
+	return s
+}
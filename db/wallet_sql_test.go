@@ -0,0 +1,197 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/google/uuid"
+)
+
+// newTestWalletDatabase returns a migrated sqlite wallet database with proofs of two mints
+func newTestWalletDatabase(t *testing.T) WalletStorage {
+	lightning.Config.Lightning.Enabled = true
+	database, err := NewSqliteWalletDatabase(&SqliteConfig{Path: t.TempDir(), FileName: "wallet.sqlite3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.MigrateSchema(WalletMigrations); err != nil {
+		t.Fatal(err)
+	}
+	keySets := []crypto.KeySet{{Id: "a", MintUrl: "https://a.example"}, {Id: "b", MintUrl: "https://b.example"}}
+	for _, k := range keySets {
+		if err = database.StoreKeySet(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proofs := []cashu.Proof{
+		{Id: "a", Amount: 1, Secret: "a1", C: "C"},
+		{Id: "a", Amount: 2, Secret: "a2", C: "C"},
+		{Id: "b", Amount: 4, Secret: "b1", C: "C"},
+	}
+	if err = database.StoreProofs(proofs...); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestWalletStorage_GetProofs(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	tests := []struct {
+		name    string
+		options []GetProofsOptions
+		want    int
+	}{
+		{name: "all", want: 3},
+		{name: "keyset", options: []GetProofsOptions{ProofsWithKeySetId("a")}, want: 2},
+		{name: "keysets", options: []GetProofsOptions{ProofsWithKeySetId("a", "b")}, want: 3},
+		{name: "mint", options: []GetProofsOptions{ProofsWithMintUrl("https://b.example")}, want: 1},
+		{name: "unknownMint", options: []GetProofsOptions{ProofsWithMintUrl("https://c.example")}, want: 0},
+		{name: "secret", options: []GetProofsOptions{ProofsWithSecret("a2", "unknown")}, want: 1},
+		{name: "combined", options: []GetProofsOptions{ProofsWithMintUrl("https://a.example"), ProofsWithSecret("b1")}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proofs, err := database.GetProofs(tt.options...)
+			if err != nil {
+				t.Fatalf("GetProofs() error = %v", err)
+			}
+			if len(proofs) != tt.want {
+				t.Errorf("GetProofs() = %v, want %d proofs", proofs, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalletStorage_ReserveProofs(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	proofs, err := database.GetProofs(ProofsWithKeySetId("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendId := uuid.New()
+	if err = database.ReserveProofs(proofs, sendId); err != nil {
+		t.Fatalf("ReserveProofs() error = %v", err)
+	}
+	reserved, err := database.GetReservedProofs()
+	if err != nil || len(reserved) != 2 || reserved[0].SendId != sendId {
+		t.Errorf("GetReservedProofs() = %v, error = %v, want 2 proofs of send %s", reserved, err, sendId)
+	}
+	if err = database.UnreserveProofs(proofs[:1]); err != nil {
+		t.Fatalf("UnreserveProofs() error = %v", err)
+	}
+	if reserved, err = database.GetReservedProofs(); err != nil || len(reserved) != 1 || reserved[0].Secret != proofs[1].Secret {
+		t.Errorf("GetReservedProofs() = %v, error = %v, want 1 proof", reserved, err)
+	}
+}
+
+func TestWalletStorage_InvalidateProofs(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	proofs, err := database.GetProofs(ProofsWithSecret("a1", "b1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// invalidating proofs twice must not fail
+	for i := 0; i < 2; i++ {
+		if err = database.InvalidateProofs(proofs...); err != nil {
+			t.Fatalf("InvalidateProofs() error = %v", err)
+		}
+	}
+	if unspent, err := database.GetProofs(); err != nil || len(unspent) != 1 || unspent[0].Secret != "a2" {
+		t.Errorf("GetProofs() = %v, error = %v, want proof a2", unspent, err)
+	}
+	spent, err := database.GetSpentProofs()
+	if err != nil || len(spent) != 2 {
+		t.Errorf("GetSpentProofs() = %v, error = %v, want 2 proofs", spent, err)
+	}
+	if spent, err = database.GetSpentProofs("b1", "a2"); err != nil || len(spent) != 1 || spent[0].Amount != 4 {
+		t.Errorf("GetSpentProofs() = %v, error = %v, want proof b1", spent, err)
+	}
+}
+
+func TestWalletStorage_GetLiabilityKey(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	if key, err := database.GetLiabilityKey("https://a.example"); err != nil || key != nil {
+		t.Fatalf("GetLiabilityKey() = %v, error = %v, want no key", key, err)
+	}
+	if err := database.StoreLiabilityKey(LiabilityKey{MintUrl: "https://a.example", PublicKey: "key", TimeCreated: time.Now()}); err != nil {
+		t.Fatalf("StoreLiabilityKey() error = %v", err)
+	}
+	// a pinned key must not be replaced
+	if err := database.StoreLiabilityKey(LiabilityKey{MintUrl: "https://a.example", PublicKey: "other", TimeCreated: time.Now()}); err == nil {
+		t.Errorf("StoreLiabilityKey() replaced the pinned key")
+	}
+	if key, err := database.GetLiabilityKey("https://a.example"); err != nil || key == nil || key.PublicKey != "key" {
+		t.Errorf("GetLiabilityKey() = %v, error = %v, want key", key, err)
+	}
+	if key, err := database.GetLiabilityKey("https://b.example"); err != nil || key != nil {
+		t.Errorf("GetLiabilityKey() = %v, error = %v, want no key", key, err)
+	}
+}
+
+func TestWalletStorage_GetPromises(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	promises := []cashu.Promise{
+		{B_b: "B1", C_c: "C1", Amount: 1, Id: "a"},
+		{B_b: "B2", C_c: "C2", Amount: 2, Id: "a"},
+		{B_b: "B3", C_c: "C3", Amount: 4, Id: "b"},
+	}
+	if err := database.StorePromises(promises...); err != nil {
+		t.Fatalf("StorePromises() error = %v", err)
+	}
+	tests := []struct {
+		keySetIds []string
+		want      int
+	}{
+		{keySetIds: []string{"a"}, want: 2},
+		{keySetIds: []string{"a", "b"}, want: 3},
+		{keySetIds: []string{"c"}, want: 0},
+		{want: 0},
+	}
+	for _, tt := range tests {
+		got, err := database.GetPromises(tt.keySetIds...)
+		if err != nil {
+			t.Fatalf("GetPromises() error = %v", err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("GetPromises(%v) = %v, want %d promises", tt.keySetIds, got, tt.want)
+		}
+	}
+}
+
+func TestWalletStorage_UpdateInvoice(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	if err := database.StoreInvoice(&invoice.Invoice{Amount: 10, Hash: "hash", Pr: "lnbc1", Create: time.Now()}); err != nil {
+		t.Fatalf("StoreInvoice() error = %v", err)
+	}
+	if err := database.UpdateInvoice("unknown", UpdateInvoicePaid(true)); err == nil {
+		t.Errorf("UpdateInvoice() updated unknown invoice")
+	}
+	if err := database.UpdateInvoice("hash", UpdateInvoicePaid(true)); err != nil {
+		t.Fatalf("UpdateInvoice() error = %v", err)
+	}
+	if pending, err := database.GetInvoices(false); err != nil || len(pending) != 0 {
+		t.Errorf("GetInvoices(false) = %v, error = %v, want no invoices", pending, err)
+	}
+	if paid, err := database.GetInvoices(true); err != nil || len(paid) != 1 {
+		t.Errorf("GetInvoices(true) = %v, error = %v, want 1 invoice", paid, err)
+	}
+}
+
+func TestWalletStorage_GetLocks(t *testing.T) {
+	database := newTestWalletDatabase(t)
+	for _, address := range []string{"first", "second"} {
+		if err := database.StoreLock(cashu.P2SHScript{Script: "script", Signature: "signature", Address: address}); err != nil {
+			t.Fatalf("StoreLock() error = %v", err)
+		}
+	}
+	if locks, err := database.GetLocks(""); err != nil || len(locks) != 2 {
+		t.Errorf("GetLocks() = %v, error = %v, want 2 locks", locks, err)
+	}
+	if locks, err := database.GetLocks("second"); err != nil || len(locks) != 1 {
+		t.Errorf("GetLocks() = %v, error = %v, want 1 lock", locks, err)
+	}
+}
@@ -0,0 +1,106 @@
+package exchange
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/imroc/req"
+)
+
+// RateProvider returns exchange rates of units in satoshi.
+// It is used to price mints and melts of keysets, that are not denominated in satoshi.
+type RateProvider interface {
+	SatPerUnit(unit string) (float64, error) // SatPerUnit should return the value of one unit in satoshi
+}
+
+// FixedRates is a RateProvider with fixed exchange rates (satoshi per unit).
+type FixedRates map[string]float64
+
+func (r FixedRates) SatPerUnit(unit string) (float64, error) {
+	rate, ok := r[unit]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no exchange rate for unit %s", unit)
+	}
+	return rate, nil
+}
+
+const (
+	satPerBitcoin = 100_000_000
+	// krakenUrl is the public ticker api of kraken
+	krakenUrl = "https://api.kraken.com/0/public/Ticker"
+	// krakenCacheDuration rates are requested once in this duration
+	krakenCacheDuration = time.Minute
+)
+
+// krakenPairs maps fiat units to the kraken ticker pair
+var krakenPairs = map[string]string{
+	"usd": "XXBTZUSD",
+	"eur": "XXBTZEUR",
+}
+
+// Kraken is a RateProvider for fiat units using the kraken ticker.
+// Fiat units are denominated in cents.
+type Kraken struct {
+	url   string
+	rates map[string]cachedRate
+	mu    sync.Mutex
+}
+
+type cachedRate struct {
+	rate    float64
+	updated time.Time
+}
+
+type krakenTicker struct {
+	Error  []string `json:"error"`
+	Result map[string]struct {
+		// C is the last trade closed [price, lot volume]
+		C []string `json:"c"`
+	} `json:"result"`
+}
+
+// NewKraken returns a new kraken rate provider.
+func NewKraken() *Kraken {
+	return &Kraken{url: krakenUrl, rates: make(map[string]cachedRate)}
+}
+
+// SatPerUnit returns the satoshi value of one cent of unit.
+func (k *Kraken) SatPerUnit(unit string) (float64, error) {
+	pair, ok := krakenPairs[unit]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for unit %s", unit)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if cached, ok := k.rates[unit]; ok && time.Since(cached.updated) < krakenCacheDuration {
+		return cached.rate, nil
+	}
+	resp, err := req.Get(k.url, req.QueryParam{"pair": pair})
+	if err != nil {
+		return 0, err
+	}
+	ticker := krakenTicker{}
+	if err = resp.ToJSON(&ticker); err != nil {
+		return 0, err
+	}
+	if len(ticker.Error) > 0 {
+		return 0, fmt.Errorf("kraken: %v", ticker.Error)
+	}
+	result, ok := ticker.Result[pair]
+	if !ok || len(result.C) == 0 {
+		return 0, fmt.Errorf("kraken: no price for %s", pair)
+	}
+	price, err := strconv.ParseFloat(result.C[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("kraken: invalid price %f", price)
+	}
+	// price is in unit per bitcoin. one cent is worth satPerBitcoin / (price * 100) satoshi.
+	rate := satPerBitcoin / (price * 100)
+	k.rates[unit] = cachedRate{rate: rate, updated: time.Now()}
+	return rate, nil
+}
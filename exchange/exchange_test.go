@@ -0,0 +1,65 @@
+package exchange
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFixedRates_SatPerUnit(t *testing.T) {
+	rates := FixedRates{"usd": 3.5}
+	tests := []struct {
+		name    string
+		unit    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "usd", unit: "usd", want: 3.5},
+		{name: "unknown", unit: "eur", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.SatPerUnit(tt.unit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SatPerUnit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SatPerUnit() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKraken_SatPerUnit(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("pair") != "XXBTZUSD" {
+			w.Write([]byte(`{"error":["EQuery:Unknown asset pair"]}`))
+			return
+		}
+		w.Write([]byte(`{"error":[],"result":{"XXBTZUSD":{"c":["50000.00000","0.1"]}}}`))
+	}))
+	defer server.Close()
+	k := &Kraken{url: server.URL, rates: make(map[string]cachedRate)}
+	for i := 0; i < 2; i++ {
+		rate, err := k.SatPerUnit("usd")
+		if err != nil {
+			t.Fatalf("SatPerUnit() error = %v", err)
+		}
+		// 1 cent = 100_000_000 / 5_000_000 sat
+		if math.Abs(rate-20) > 0.0001 {
+			t.Errorf("SatPerUnit() = %f, want 20", rate)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("SatPerUnit() requested kraken %d times, want 1", requests)
+	}
+	if _, err := k.SatPerUnit("eur"); err == nil {
+		t.Errorf("SatPerUnit() expected kraken error for eur")
+	}
+	if _, err := k.SatPerUnit("sat"); err == nil {
+		t.Errorf("SatPerUnit() expected error for unknown unit")
+	}
+}
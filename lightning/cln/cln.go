@@ -0,0 +1,174 @@
+package cln
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/imroc/req"
+)
+
+const (
+	invoiceStatusPaid  = "paid"
+	payStatusComplete  = "complete"
+	channelStateNormal = "CHANNELD_NORMAL"
+)
+
+// NewClient returns a new core lightning client for the clnrest api. Pass the rune (key) and the url of clnrest here.
+func NewClient(key, url string) lightning.Client {
+	return &Client{
+		url: url,
+		header: req.Header{
+			"Content-Type": "application/json",
+			"Accept":       "application/json",
+			"Rune":         key,
+		},
+	}
+}
+
+// call will call the rpc method with params and decode the response into v
+func (c Client) call(method string, params interface{}, v interface{}, r ...*req.Req) error {
+	request := req.New()
+	if len(r) > 0 {
+		request = r[0]
+	}
+	resp, err := request.Post(fmt.Sprintf("%s/v1/%s", c.url, method), c.header, req.BodyJSON(params))
+	if err != nil {
+		return err
+	}
+	if resp.Response().StatusCode >= 300 {
+		var reqErr Error
+		err = resp.ToJSON(&reqErr)
+		if err != nil {
+			return err
+		}
+		return reqErr
+	}
+	return resp.ToJSON(v)
+}
+
+// newLabel returns a random label for a new invoice
+func newLabel() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "cashu-feni-" + hex.EncodeToString(b), nil
+}
+
+// CreateInvoice creates an invoice for amount (in satoshi).
+func (c *Client) CreateInvoice(amount int64, memo string) (lightning.Invoicer, error) {
+	return c.createInvoice(InvoiceParams{AmountMsat: uint64(amount) * 1000, Description: memo})
+}
+
+// CreateInvoiceWithDescription creates an invoice with the sha256 hash of description as description hash.
+func (c *Client) CreateInvoiceWithDescription(amount int64, description string) (lightning.Invoicer, error) {
+	return c.createInvoice(InvoiceParams{AmountMsat: uint64(amount) * 1000, Description: description, DescHashOnly: true})
+}
+
+func (c *Client) createInvoice(params InvoiceParams) (lightning.Invoicer, error) {
+	label, err := newLabel()
+	if err != nil {
+		return nil, err
+	}
+	params.Label = label
+	response := InvoiceResponse{}
+	if err = c.call("invoice", params, &response); err != nil {
+		return nil, err
+	}
+	return &invoice.Invoice{
+		Amount: int64(params.AmountMsat / 1000),
+		Hash:   response.PaymentHash,
+		Pr:     response.Bolt11,
+	}, nil
+}
+
+// Pay pays a BOLT11 payment request or a BOLT12 invoice.
+func (c *Client) Pay(paymentRequest string) (lightning.Invoicer, error) {
+	return c.PayWithFeeLimit(paymentRequest, 0)
+}
+
+// PayWithFeeLimit pays a BOLT11 payment request or a BOLT12 invoice. The routing fees are limited to maxFeeMsat.
+func (c *Client) PayWithFeeLimit(paymentRequest string, maxFeeMsat uint64) (lightning.Invoicer, error) {
+	response, err := c.pay(paymentRequest, maxFeeMsat)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice.Invoice{
+		Amount:   int64(response.AmountMsat / 1000),
+		Hash:     response.PaymentHash,
+		Pr:       paymentRequest,
+		Preimage: response.PaymentPreimage,
+		Paid:     response.Status == payStatusComplete,
+	}, nil
+}
+
+func (c *Client) pay(paymentRequest string, maxFeeMsat uint64) (PayResponse, error) {
+	r := req.New()
+	r.SetTimeout(time.Hour * 24)
+	response := PayResponse{}
+	err := c.call("pay", PayParams{Bolt11: paymentRequest, MaxFee: maxFeeMsat}, &response, r)
+	if err != nil && lightning.IsConnectionError(err) {
+		err = lightning.PaymentNotSent(err)
+	}
+	return response, err
+}
+
+// PayOffer fetches a BOLT12 invoice for amountMsat from the offer and pays it. The routing fees are limited to maxFeeMsat.
+func (c *Client) PayOffer(offer string, amountMsat uint64, maxFeeMsat uint64) (lightning.OfferPayment, error) {
+	fetched := FetchInvoiceResponse{}
+	if err := c.call("fetchinvoice", FetchInvoiceParams{Offer: offer, AmountMsat: amountMsat}, &fetched); err != nil {
+		// nothing is paid, if the invoice could not be fetched
+		return nil, lightning.PaymentNotSent(err)
+	}
+	response, err := c.pay(fetched.Invoice, maxFeeMsat)
+	if err != nil {
+		return nil, err
+	}
+	return ClnPayment{Paid: response.Status == payStatusComplete, Preimage: response.PaymentPreimage, Invoice: fetched.Invoice}, nil
+}
+
+// InvoiceStatus returns the status of an incoming invoice or of an outgoing payment.
+func (c Client) InvoiceStatus(paymentHash string) (lightning.Payment, error) {
+	invoices := ListInvoicesResponse{}
+	if err := c.call("listinvoices", ListInvoicesParams{PaymentHash: paymentHash}, &invoices); err != nil {
+		return nil, err
+	}
+	if len(invoices.Invoices) > 0 {
+		i := invoices.Invoices[0]
+		return ClnPayment{Paid: i.Status == invoiceStatusPaid, Preimage: i.PaymentPreimage}, nil
+	}
+	pays := ListPaysResponse{}
+	if err := c.call("listpays", ListPaysParams{PaymentHash: paymentHash}, &pays); err != nil {
+		return nil, err
+	}
+	for _, p := range pays.Pays {
+		if p.Status == payStatusComplete {
+			return ClnPayment{Paid: true, Preimage: p.Preimage}, nil
+		}
+	}
+	if len(pays.Pays) == 0 {
+		return nil, fmt.Errorf("payment not found: %s", paymentHash)
+	}
+	return ClnPayment{}, nil
+}
+
+// Liquidity returns the liquidity of all active channels.
+func (c Client) Liquidity() (lightning.Liquidity, error) {
+	funds := ListFundsResponse{}
+	if err := c.call("listfunds", struct{}{}, &funds); err != nil {
+		return lightning.Liquidity{}, err
+	}
+	liquidity := lightning.Liquidity{}
+	for _, channel := range funds.Channels {
+		if channel.State != channelStateNormal || !channel.Connected {
+			continue
+		}
+		liquidity.Outbound += channel.OurAmountMsat
+		liquidity.Inbound += channel.AmountMsat - channel.OurAmountMsat
+	}
+	return liquidity, nil
+}
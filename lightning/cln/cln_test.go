@@ -0,0 +1,134 @@
+package cln
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/lightning"
+)
+
+// newTestServer returns a clnrest server, that answers every rpc method with the response in responses.
+func newTestServer(t *testing.T, responses map[string]string, requests map[string]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Rune") != "rune" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":1501,"message":"Not authorized"}`))
+			return
+		}
+		method := strings.TrimPrefix(r.URL.Path, "/v1/")
+		params := make(map[string]interface{})
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Fatal(err)
+		}
+		requests[method] = params
+		response, ok := responses[method]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"code":-32601,"message":"Unknown command"}`))
+			return
+		}
+		w.Write([]byte(response))
+	}))
+}
+
+func TestClient_PayOffer(t *testing.T) {
+	requests := make(map[string]map[string]interface{})
+	server := newTestServer(t, map[string]string{
+		"fetchinvoice": `{"invoice":"lni1invoice"}`,
+		"pay":          `{"payment_hash":"hash","payment_preimage":"preimage","status":"complete","amount_msat":10000}`,
+	}, requests)
+	defer server.Close()
+	client := NewClient("rune", server.URL).(lightning.OfferPayer)
+	payment, err := client.PayOffer("lno1offer", 10000, 4000)
+	if err != nil {
+		t.Fatalf("PayOffer() error = %v", err)
+	}
+	if !payment.IsPaid() || payment.GetPreimage() != "preimage" || payment.GetInvoice() != "lni1invoice" {
+		t.Errorf("PayOffer() = %v", payment)
+	}
+	if requests["fetchinvoice"]["offer"] != "lno1offer" || requests["fetchinvoice"]["amount_msat"] != float64(10000) {
+		t.Errorf("PayOffer() fetchinvoice params = %v", requests["fetchinvoice"])
+	}
+	if requests["pay"]["bolt11"] != "lni1invoice" || requests["pay"]["maxfee"] != float64(4000) {
+		t.Errorf("PayOffer() pay params = %v", requests["pay"])
+	}
+}
+
+func TestClient_InvoiceStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    map[string]string
+		wantPaid     bool
+		wantPreimage string
+		wantErr      bool
+	}{
+		{
+			name:         "paidInvoice",
+			responses:    map[string]string{"listinvoices": `{"invoices":[{"payment_hash":"hash","status":"paid","payment_preimage":"preimage"}]}`},
+			wantPaid:     true,
+			wantPreimage: "preimage",
+		},
+		{
+			name:      "unpaidInvoice",
+			responses: map[string]string{"listinvoices": `{"invoices":[{"payment_hash":"hash","status":"unpaid"}]}`},
+			wantPaid:  false,
+		},
+		{
+			name: "completePayment",
+			responses: map[string]string{
+				"listinvoices": `{"invoices":[]}`,
+				"listpays":     `{"pays":[{"payment_hash":"hash","status":"failed"},{"payment_hash":"hash","status":"complete","preimage":"preimage"}]}`,
+			},
+			wantPaid:     true,
+			wantPreimage: "preimage",
+		},
+		{
+			name: "unknown",
+			responses: map[string]string{
+				"listinvoices": `{"invoices":[]}`,
+				"listpays":     `{"pays":[]}`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, tt.responses, make(map[string]map[string]interface{}))
+			defer server.Close()
+			payment, err := NewClient("rune", server.URL).InvoiceStatus("hash")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InvoiceStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if payment.IsPaid() != tt.wantPaid || payment.GetPreimage() != tt.wantPreimage {
+				t.Errorf("InvoiceStatus() = %v, want paid %v preimage %s", payment, tt.wantPaid, tt.wantPreimage)
+			}
+		})
+	}
+}
+
+func TestClient_CreateInvoiceWithDescription(t *testing.T) {
+	requests := make(map[string]map[string]interface{})
+	server := newTestServer(t, map[string]string{"invoice": `{"payment_hash":"hash","bolt11":"lnbc1"}`}, requests)
+	defer server.Close()
+	client := NewClient("rune", server.URL).(lightning.DescriptionHashInvoiceCreator)
+	i, err := client.CreateInvoiceWithDescription(10, "metadata")
+	if err != nil {
+		t.Fatalf("CreateInvoiceWithDescription() error = %v", err)
+	}
+	if i.GetHash() != "hash" || i.GetPaymentRequest() != "lnbc1" || i.GetAmount() != 10 {
+		t.Errorf("CreateInvoiceWithDescription() = %v", i)
+	}
+	params := requests["invoice"]
+	if params["amount_msat"] != float64(10000) || params["description"] != "metadata" || params["deschashonly"] != true || params["label"] == "" {
+		t.Errorf("CreateInvoiceWithDescription() params = %v", params)
+	}
+	if _, err = NewClient("invalid", server.URL).CreateInvoice(10, "memo"); err == nil || err.Error() != "Not authorized" {
+		t.Errorf("CreateInvoice() error = %v, want Not authorized", err)
+	}
+}
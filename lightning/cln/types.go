@@ -0,0 +1,114 @@
+package cln
+
+import (
+	"github.com/imroc/req"
+)
+
+type Client struct {
+	header req.Header
+	url    string
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err Error) Error() string {
+	return err.Message
+}
+
+type InvoiceParams struct {
+	AmountMsat   uint64 `json:"amount_msat"`            // amount in milli satoshi
+	Label        string `json:"label"`                  // unique label of the invoice
+	Description  string `json:"description"`            // the invoice description
+	DescHashOnly bool   `json:"deschashonly,omitempty"` // only commit to the sha256 hash of the description
+}
+
+type InvoiceResponse struct {
+	PaymentHash string `json:"payment_hash"`
+	Bolt11      string `json:"bolt11"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+type ListInvoicesParams struct {
+	PaymentHash string `json:"payment_hash"`
+}
+
+type ListInvoicesResponse struct {
+	Invoices []InvoiceDetails `json:"invoices"`
+}
+
+type InvoiceDetails struct {
+	Label           string `json:"label"`
+	PaymentHash     string `json:"payment_hash"`
+	Status          string `json:"status"` // unpaid, paid or expired
+	PaymentPreimage string `json:"payment_preimage"`
+	AmountMsat      uint64 `json:"amount_msat"`
+}
+
+type PayParams struct {
+	Bolt11 string `json:"bolt11"` // BOLT11 payment request or BOLT12 invoice
+	// MaxFee is the maximum routing fee in msat. The fee limit of the node is used, if not set.
+	MaxFee uint64 `json:"maxfee,omitempty"`
+}
+
+type PayResponse struct {
+	PaymentHash     string `json:"payment_hash"`
+	PaymentPreimage string `json:"payment_preimage"`
+	Status          string `json:"status"` // complete, pending or failed
+	AmountMsat      uint64 `json:"amount_msat"`
+	AmountSentMsat  uint64 `json:"amount_sent_msat"`
+}
+
+type ListPaysParams struct {
+	PaymentHash string `json:"payment_hash"`
+}
+
+type ListPaysResponse struct {
+	Pays []PayDetails `json:"pays"`
+}
+
+type PayDetails struct {
+	PaymentHash string `json:"payment_hash"`
+	Status      string `json:"status"` // complete, pending or failed
+	Preimage    string `json:"preimage"`
+}
+
+type FetchInvoiceParams struct {
+	Offer      string `json:"offer"`
+	AmountMsat uint64 `json:"amount_msat,omitempty"`
+}
+
+type FetchInvoiceResponse struct {
+	Invoice string `json:"invoice"`
+}
+
+type ListFundsResponse struct {
+	Channels []Channel `json:"channels"`
+}
+
+type Channel struct {
+	State         string `json:"state"`
+	Connected     bool   `json:"connected"`
+	OurAmountMsat uint64 `json:"our_amount_msat"`
+	AmountMsat    uint64 `json:"amount_msat"`
+}
+
+// ClnPayment is the status of an incoming or outgoing payment
+type ClnPayment struct {
+	Paid     bool   `json:"paid"`
+	Preimage string `json:"preimage"`
+	// Invoice is the BOLT12 invoice of a paid offer
+	Invoice string `json:"invoice,omitempty"`
+}
+
+func (p ClnPayment) IsPaid() bool {
+	return p.Paid
+}
+func (p ClnPayment) GetPreimage() string {
+	return p.Preimage
+}
+func (p ClnPayment) GetInvoice() string {
+	return p.Invoice
+}
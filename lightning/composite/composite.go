@@ -0,0 +1,274 @@
+package composite
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/lightning"
+	log "github.com/sirupsen/logrus"
+)
+
+// Strategy decides which backend creates new invoices.
+type Strategy string
+
+const (
+	// RoundRobin creates invoices on all backends in turn.
+	RoundRobin Strategy = "round_robin"
+	// Liquidity creates invoices on the backend with the most inbound liquidity.
+	Liquidity Strategy = "liquidity"
+)
+
+// routeExpiry is the time, after which a payment hash is forgotten. Status requests of forgotten payment hashes ask all backends.
+const routeExpiry = 24 * time.Hour
+
+// route is the backend that created or paid an invoice
+type route struct {
+	backend lightning.Client
+	created time.Time
+}
+
+// Client routes lightning requests through multiple lightning backends.
+// Payments are sent through any backend with enough outbound liquidity.
+type Client struct {
+	backends []lightning.Client
+	strategy Strategy
+	next     uint64
+	// invoices maps payment hashes to the backend that created or paid the invoice
+	invoices map[string]route
+	// pruned is the time, when expired routes were removed last
+	pruned time.Time
+	mu     sync.RWMutex
+}
+
+// NewClient returns a new composite lightning client for all backends.
+func NewClient(strategy Strategy, backends ...lightning.Client) *Client {
+	if strategy == "" {
+		strategy = RoundRobin
+	}
+	return &Client{
+		backends: backends,
+		strategy: strategy,
+		invoices: make(map[string]route),
+		pruned:   time.Now(),
+	}
+}
+
+// remember routes the payment hash to backend. Expired routes are removed, so that the routes do not grow forever.
+func (c *Client) remember(paymentHash string, backend lightning.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.pruned) > routeExpiry {
+		for hash, r := range c.invoices {
+			if now.Sub(r.created) > routeExpiry {
+				delete(c.invoices, hash)
+			}
+		}
+		c.pruned = now
+	}
+	c.invoices[paymentHash] = route{backend: backend, created: now}
+}
+
+func (c *Client) lookup(paymentHash string) (lightning.Client, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	r, ok := c.invoices[paymentHash]
+	return r.backend, ok
+}
+
+// CreateInvoice creates the invoice on a backend chosen by the routing strategy.
+func (c *Client) CreateInvoice(amount int64, memo string) (lightning.Invoicer, error) {
+	if len(c.backends) == 0 {
+		return nil, fmt.Errorf("no lightning backend configured")
+	}
+	backend := c.invoiceBackend()
+	invoice, err := backend.CreateInvoice(amount, memo)
+	if err != nil {
+		return nil, err
+	}
+	c.remember(invoice.GetHash(), backend)
+	return invoice, nil
+}
+
+// CreateInvoiceWithDescription creates the invoice with a description hash on a backend chosen by the routing strategy.
+func (c *Client) CreateInvoiceWithDescription(amount int64, description string) (lightning.Invoicer, error) {
+	if len(c.backends) == 0 {
+		return nil, fmt.Errorf("no lightning backend configured")
+	}
+	backend := c.invoiceBackend()
+	creator, ok := backend.(lightning.DescriptionHashInvoiceCreator)
+	if !ok {
+		return nil, fmt.Errorf("lightning backend does not support description hashes")
+	}
+	invoice, err := creator.CreateInvoiceWithDescription(amount, description)
+	if err != nil {
+		return nil, err
+	}
+	c.remember(invoice.GetHash(), backend)
+	return invoice, nil
+}
+
+// invoiceBackend returns the backend for the next invoice.
+func (c *Client) invoiceBackend() lightning.Client {
+	if c.strategy == Liquidity {
+		liquidities := c.liquidities()
+		if len(liquidities) > 0 {
+			// prefer most inbound liquidity. if inbound liquidity is unknown, balance the outbound liquidity.
+			sort.SliceStable(liquidities, func(i, j int) bool {
+				if liquidities[i].liquidity.Inbound != liquidities[j].liquidity.Inbound {
+					return liquidities[i].liquidity.Inbound > liquidities[j].liquidity.Inbound
+				}
+				return liquidities[i].liquidity.Outbound < liquidities[j].liquidity.Outbound
+			})
+			return liquidities[0].backend
+		}
+	}
+	i := atomic.AddUint64(&c.next, 1) - 1
+	return c.backends[i%uint64(len(c.backends))]
+}
+
+type backendLiquidity struct {
+	index     int
+	backend   lightning.Client
+	liquidity lightning.Liquidity
+}
+
+// liquidities returns the liquidity of every backend that is able to report it.
+func (c *Client) liquidities() []backendLiquidity {
+	liquidities := make([]backendLiquidity, 0)
+	for i, backend := range c.backends {
+		reporter, ok := backend.(lightning.LiquidityReporter)
+		if !ok {
+			continue
+		}
+		liquidity, err := reporter.Liquidity()
+		if err != nil {
+			log.WithFields(log.Fields{"error.message": err.Error()}).Warn("could not get backend liquidity")
+			continue
+		}
+		liquidities = append(liquidities, backendLiquidity{index: i, backend: backend, liquidity: liquidity})
+	}
+	return liquidities
+}
+
+// Pay pays the payment request using the first backend with enough outbound liquidity.
+// Backends are tried in order of their outbound liquidity. Backends without liquidity information are tried last.
+// The next backend is only tried, if the payment was not sent by the previous backend.
+func (c *Client) Pay(paymentRequest string) (lightning.Invoicer, error) {
+	return c.PayWithFeeLimit(paymentRequest, 0)
+}
+
+// PayWithFeeLimit pays the payment request like Pay. The routing fees are limited to maxFeeMsat on backends,
+// that support fee limits.
+func (c *Client) PayWithFeeLimit(paymentRequest string, maxFeeMsat uint64) (lightning.Invoicer, error) {
+	bolt, err := lightning.DecodePaymentRequest(paymentRequest)
+	if err != nil {
+		return nil, err
+	}
+	candidates := c.paymentBackends(uint64(bolt.MSatoshi))
+	if len(candidates) == 0 {
+		return nil, lightning.PaymentNotSent(fmt.Errorf("no lightning backend with enough outbound liquidity"))
+	}
+	for _, backend := range candidates {
+		invoice, err := pay(backend, paymentRequest, maxFeeMsat)
+		if err != nil {
+			// the payment may still be in flight, unless it was not sent. retrying it on another backend could pay it twice.
+			if !errors.Is(err, lightning.ErrPaymentNotSent) {
+				c.remember(bolt.PaymentHash, backend)
+				return nil, err
+			}
+			log.WithFields(log.Fields{"error.message": err.Error()}).Warn("payment was not sent. trying next backend")
+			continue
+		}
+		c.remember(invoice.GetHash(), backend)
+		c.remember(bolt.PaymentHash, backend)
+		return invoice, nil
+	}
+	return nil, lightning.PaymentNotSent(fmt.Errorf("payment failed on all lightning backends"))
+}
+
+// pay pays the payment request with backend. The fee limit is used, if the backend supports it.
+func pay(backend lightning.Client, paymentRequest string, maxFeeMsat uint64) (lightning.Invoicer, error) {
+	if payer, ok := backend.(lightning.FeeLimitedPayer); ok && maxFeeMsat > 0 {
+		return payer.PayWithFeeLimit(paymentRequest, maxFeeMsat)
+	}
+	return backend.Pay(paymentRequest)
+}
+
+// PayOffer pays the BOLT12 offer using the first backend, that supports offers and has enough outbound liquidity.
+func (c *Client) PayOffer(offer string, amountMsat uint64, maxFeeMsat uint64) (lightning.OfferPayment, error) {
+	for _, backend := range c.paymentBackends(amountMsat) {
+		payer, ok := backend.(lightning.OfferPayer)
+		if !ok {
+			continue
+		}
+		payment, err := payer.PayOffer(offer, amountMsat, maxFeeMsat)
+		if err != nil {
+			if !errors.Is(err, lightning.ErrPaymentNotSent) {
+				return nil, err
+			}
+			log.WithFields(log.Fields{"error.message": err.Error()}).Warn("offer payment was not sent. trying next backend")
+			continue
+		}
+		return payment, nil
+	}
+	return nil, lightning.PaymentNotSent(fmt.Errorf("no lightning backend could pay the offer"))
+}
+
+// paymentBackends returns all backends that may be able to pay amountMsat.
+func (c *Client) paymentBackends(amountMsat uint64) []lightning.Client {
+	liquidities := c.liquidities()
+	sort.SliceStable(liquidities, func(i, j int) bool {
+		return liquidities[i].liquidity.Outbound > liquidities[j].liquidity.Outbound
+	})
+	candidates := make([]lightning.Client, 0)
+	reported := make(map[int]struct{})
+	for _, l := range liquidities {
+		reported[l.index] = struct{}{}
+		if l.liquidity.Outbound >= amountMsat {
+			candidates = append(candidates, l.backend)
+		}
+	}
+	for i, backend := range c.backends {
+		if _, ok := reported[i]; !ok {
+			candidates = append(candidates, backend)
+		}
+	}
+	return candidates
+}
+
+// InvoiceStatus returns the payment status from the backend that knows the payment hash.
+func (c *Client) InvoiceStatus(paymentHash string) (lightning.Payment, error) {
+	if backend, ok := c.lookup(paymentHash); ok {
+		return backend.InvoiceStatus(paymentHash)
+	}
+	// unknown hash (e.g. after a restart). ask all backends.
+	var err error
+	for _, backend := range c.backends {
+		var payment lightning.Payment
+		payment, err = backend.InvoiceStatus(paymentHash)
+		if err != nil {
+			continue
+		}
+		c.remember(paymentHash, backend)
+		return payment, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no lightning backend configured")
+	}
+	return nil, err
+}
+
+// Liquidity returns the sum of the liquidity of all backends.
+func (c *Client) Liquidity() (lightning.Liquidity, error) {
+	sum := lightning.Liquidity{}
+	for _, l := range c.liquidities() {
+		sum.Inbound += l.liquidity.Inbound
+		sum.Outbound += l.liquidity.Outbound
+	}
+	return sum, nil
+}
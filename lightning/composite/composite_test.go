@@ -0,0 +1,203 @@
+package composite
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/cashubtc/cashu-feni/lightning/lnbits"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// testBackend is a lightning backend with fixed liquidity.
+type testBackend struct {
+	name      string
+	liquidity lightning.Liquidity
+	invoices  map[string]bool
+	payments  int
+	// payErr is returned by Pay, if set
+	payErr error
+}
+
+func newTestBackend(name string, liquidity lightning.Liquidity) *testBackend {
+	return &testBackend{name: name, liquidity: liquidity, invoices: make(map[string]bool)}
+}
+
+func (b *testBackend) InvoiceStatus(paymentHash string) (lightning.Payment, error) {
+	paid, ok := b.invoices[paymentHash]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return lnbits.LNbitsPayment{Paid: paid}, nil
+}
+
+func (b *testBackend) Pay(paymentRequest string) (lightning.Invoicer, error) {
+	b.payments++
+	if b.payErr != nil {
+		return nil, b.payErr
+	}
+	return &invoice.Invoice{Hash: fmt.Sprintf("%s-payment-%d", b.name, b.payments)}, nil
+}
+
+func (b *testBackend) CreateInvoice(amount int64, memo string) (lightning.Invoicer, error) {
+	hash := fmt.Sprintf("%s-%d", b.name, len(b.invoices))
+	b.invoices[hash] = false
+	return &invoice.Invoice{Hash: hash, Amount: amount}, nil
+}
+
+func (b *testBackend) Liquidity() (lightning.Liquidity, error) {
+	return b.liquidity, nil
+}
+
+func newTestPaymentRequest(t *testing.T, amount int64) string {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var preimage [32]byte
+	if _, err = rand.Read(preimage[:]); err != nil {
+		t.Fatal(err)
+	}
+	i, err := zpay32.NewInvoice(&chaincfg.MainNetParams, sha256.Sum256(preimage[:]), time.Now(),
+		zpay32.Amount(lnwire.MilliSatoshi(amount*1000)), zpay32.Description("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := i.Encode(zpay32.MessageSigner{SignCompact: func(msg []byte) ([]byte, error) {
+		return ecdsa.SignCompact(key, chainhash.HashB(msg), true)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pr
+}
+
+func TestClient_CreateInvoice(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+		want     []string
+	}{
+		{name: "roundRobin", strategy: RoundRobin, want: []string{"a", "b", "a"}},
+		{name: "liquidity", strategy: Liquidity, want: []string{"b", "b", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestBackend("a", lightning.Liquidity{Inbound: 1000, Outbound: 5000})
+			b := newTestBackend("b", lightning.Liquidity{Inbound: 9000, Outbound: 1000})
+			c := NewClient(tt.strategy, a, b)
+			for i, want := range tt.want {
+				invoice, err := c.CreateInvoice(10, "test")
+				if err != nil {
+					t.Fatal(err)
+				}
+				backend, ok := c.lookup(invoice.GetHash())
+				if !ok || backend.(*testBackend).name != want {
+					t.Errorf("CreateInvoice() #%d created on %v, want %s", i, backend, want)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Pay(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		want    string
+		wantErr bool
+	}{
+		{name: "mostLiquidity", amount: 10, want: "b"},
+		{name: "onlyOneBackendHasLiquidity", amount: 6, want: "b"},
+		{name: "notEnoughLiquidity", amount: 20, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestBackend("a", lightning.Liquidity{Outbound: 5000})
+			b := newTestBackend("b", lightning.Liquidity{Outbound: 10000})
+			c := NewClient(RoundRobin, a, b)
+			payment, err := c.Pay(newTestPaymentRequest(t, tt.amount))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Pay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			backend, ok := c.lookup(payment.GetHash())
+			if !ok || backend.(*testBackend).name != tt.want {
+				t.Errorf("Pay() paid with %v, want %s", backend, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Pay_failover(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantErr      bool
+		wantPayments int
+	}{
+		{name: "notSent", err: lightning.PaymentNotSent(fmt.Errorf("connection refused")), wantPayments: 1},
+		{name: "inFlight", err: fmt.Errorf("timeout"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestBackend("a", lightning.Liquidity{Outbound: 10000})
+			a.payErr = tt.err
+			b := newTestBackend("b", lightning.Liquidity{Outbound: 5000})
+			c := NewClient(RoundRobin, a, b)
+			if _, err := c.Pay(newTestPaymentRequest(t, 2)); (err != nil) != tt.wantErr {
+				t.Fatalf("Pay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// payments, which may be in flight, must not be sent again by the next backend
+			if b.payments != tt.wantPayments {
+				t.Errorf("Pay() tried backend b %d times, want %d", b.payments, tt.wantPayments)
+			}
+		})
+	}
+}
+
+func TestClient_remember(t *testing.T) {
+	a := newTestBackend("a", lightning.Liquidity{})
+	c := NewClient(RoundRobin, a)
+	c.remember("expired", a)
+	c.invoices["expired"] = route{backend: a, created: time.Now().Add(-2 * routeExpiry)}
+	c.pruned = time.Now().Add(-2 * routeExpiry)
+	c.remember("new", a)
+	if _, ok := c.lookup("expired"); ok {
+		t.Errorf("remember() did not remove expired route")
+	}
+	if _, ok := c.lookup("new"); !ok {
+		t.Errorf("remember() did not add route")
+	}
+}
+
+func TestClient_InvoiceStatus(t *testing.T) {
+	a := newTestBackend("a", lightning.Liquidity{})
+	b := newTestBackend("b", lightning.Liquidity{})
+	b.invoices["unknown"] = true
+	c := NewClient(RoundRobin, a, b)
+	payment, err := c.InvoiceStatus("unknown")
+	if err != nil {
+		t.Fatalf("InvoiceStatus() error = %v", err)
+	}
+	if !payment.IsPaid() {
+		t.Errorf("InvoiceStatus() paid = false, want true")
+	}
+	if backend, ok := c.lookup("unknown"); !ok || backend != b {
+		t.Errorf("InvoiceStatus() did not remember backend")
+	}
+	if _, err = c.InvoiceStatus("missing"); err == nil {
+		t.Errorf("InvoiceStatus() expected error for missing invoice")
+	}
+}
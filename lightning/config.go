@@ -15,6 +15,17 @@ type Configuration struct {
 	Lightning struct {
 		Enabled bool          `json:"enabled" yaml:"enabled"`
 		Lnbits  *LnbitsConfig `json:"lnbits" yaml:"lnbits"`
+		// Cln is a core lightning node with the clnrest plugin. Cln supports BOLT12 offers.
+		Cln *ClnConfig `json:"cln" yaml:"cln"`
+		// Backends are additional lnbits or cln backends. Requests are routed through all backends, if set.
+		Backends []*BackendConfig `json:"backends" yaml:"backends"`
+		// Routing strategy for invoice creation with multiple backends (round_robin or liquidity)
+		Routing string `json:"routing" yaml:"routing"`
+		// InvoiceWatcherInterval in seconds. The mint checks all unpaid invoices in this interval (0 disables the watcher).
+		InvoiceWatcherInterval int `json:"invoice_watcher_interval" yaml:"invoice_watcher_interval"`
+		// LightningFeePercent and LightningReserveFeeMin are the default fees of backends without fee configuration
+		LightningFeePercent    float64 `json:"lightning_fee_percent" yaml:"lightning_fee_percent"`
+		LightningReserveFeeMin float64 `json:"lightning_reserve_fee_min" yaml:"lightning_reserve_fee_min"`
 	} `json:"lightning" json:"lightning"`
 }
 type LnbitsConfig struct {
@@ -22,6 +33,22 @@ type LnbitsConfig struct {
 	LightningReserveFeeMin float64 `json:"lightning_reserve_fee_min" yaml:"lightning_reserve_fee_min"`
 	AdminKey               string  `yaml:"admin_key"`
 	Url                    string  `yaml:"url"`
+	// WebhookUrl is the public url of the mints /lnbits/webhook route. Invoices are settled instantly, if set.
+	WebhookUrl string `yaml:"webhook_url"`
+}
+
+// BackendConfig is an additional lightning backend. Either Lnbits or Cln should be set.
+type BackendConfig struct {
+	Lnbits *LnbitsConfig `json:"lnbits" yaml:"lnbits"`
+	Cln    *ClnConfig    `json:"cln" yaml:"cln"`
+}
+
+type ClnConfig struct {
+	LightningFeePercent    float64 `json:"lightning_fee_percent" yaml:"lightning_fee_percent"`
+	LightningReserveFeeMin float64 `json:"lightning_reserve_fee_min" yaml:"lightning_reserve_fee_min"`
+	// Rune authenticates the mint on the clnrest api
+	Rune string `yaml:"rune"`
+	Url  string `yaml:"url"`
 }
 
 var Config Configuration
@@ -52,5 +79,36 @@ func FeeReserve(amountMsat uint64, internal bool) uint64 {
 	if internal {
 		return 0
 	}
-	return uint64(math.Max(Config.Lightning.Lnbits.LightningReserveFeeMin, float64(amountMsat)*Config.Lightning.Lnbits.LightningFeePercent/1000))
+	reserveFeeMin, feePercent := feeConfig()
+	return uint64(math.Max(reserveFeeMin, float64(amountMsat)*feePercent/1000))
+}
+
+// feeConfig returns the fee configuration for payments.
+// Payments can be routed through every configured backend, so the highest fees of all backends are reserved.
+// Backends without fee configuration and mints without backends use the default fees.
+func feeConfig() (reserveFeeMin, feePercent float64) {
+	cfg := Config.Lightning
+	configured := false
+	reserve := func(backendReserveFeeMin, backendFeePercent float64) {
+		configured = true
+		if backendReserveFeeMin == 0 && backendFeePercent == 0 {
+			backendReserveFeeMin, backendFeePercent = cfg.LightningReserveFeeMin, cfg.LightningFeePercent
+		}
+		reserveFeeMin, feePercent = math.Max(reserveFeeMin, backendReserveFeeMin), math.Max(feePercent, backendFeePercent)
+	}
+	for _, backend := range append([]*BackendConfig{{Lnbits: cfg.Lnbits, Cln: cfg.Cln}}, cfg.Backends...) {
+		if backend == nil {
+			continue
+		}
+		if backend.Lnbits != nil {
+			reserve(backend.Lnbits.LightningReserveFeeMin, backend.Lnbits.LightningFeePercent)
+		}
+		if backend.Cln != nil {
+			reserve(backend.Cln.LightningReserveFeeMin, backend.Cln.LightningFeePercent)
+		}
+	}
+	if !configured {
+		return cfg.LightningReserveFeeMin, cfg.LightningFeePercent
+	}
+	return reserveFeeMin, feePercent
 }
@@ -0,0 +1,18 @@
+package lightning
+
+import (
+	"fmt"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+)
+
+// DecodePaymentRequest decodes the BOLT11 payment request.
+// decodepay panics on some malformed requests. These panics are returned as error.
+func DecodePaymentRequest(pr string) (bolt decodepay.Bolt11, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid payment request: %v", r)
+		}
+	}()
+	return decodepay.Decodepay(pr)
+}
@@ -0,0 +1,21 @@
+package lightning
+
+import "testing"
+
+func TestDecodePaymentRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		pr   string
+	}{
+		{name: "empty", pr: ""},
+		{name: "short", pr: "00"},
+		{name: "prefix", pr: "lnbc1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodePaymentRequest(tt.pr); err == nil {
+				t.Errorf("DecodePaymentRequest() error = nil, want error")
+			}
+		})
+	}
+}
@@ -9,12 +9,17 @@ import (
 type Invoice struct {
 	Amount   int64     `json:"amount"`
 	Pr       string    `json:"payment_request"`
-	Hash     string    `json:"payment_hash" gorm:"primaryKey"`
+	Hash     string    `json:"payment_hash" gorm:"primaryKey;size:64"`
 	Issued   bool      `json:"issued"`
 	Preimage string    `json:"preimage"`
 	Paid     bool      `json:"paid"`
 	Create   time.Time `json:"time_created"`
 	TimePaid time.Time `json:"time_paid"`
+	// WebhookToken authenticates the payment webhook of the lightning backend
+	WebhookToken string `json:"-" structs:"-"`
+	// Unit of the mint request. The invoice amount is always in satoshi.
+	Unit       string `json:"unit,omitempty"`
+	UnitAmount uint64 `json:"unit_amount,omitempty"`
 }
 
 func (i Invoice) Log() map[string]interface{} {
@@ -52,16 +57,39 @@ func (i *Invoice) GetPaymentRequest() string {
 func (i *Invoice) SetPaid(paid bool) {
 	i.Paid = paid
 }
+func (i *Invoice) IsPaid() bool {
+	return i.Paid
+}
 func (i *Invoice) SetIssued(issued bool) {
 	i.Issued = issued
 }
 
+func (i *Invoice) GetWebhookToken() string {
+	return i.WebhookToken
+}
+
 func (i *Invoice) SetAmount(amount int64) {
 	i.Amount = amount
 }
 func (i *Invoice) GetAmount() int64 {
 	return i.Amount
 }
+func (i *Invoice) SetUnit(unit string, amount uint64) {
+	i.Unit = unit
+	i.UnitAmount = amount
+}
+func (i *Invoice) GetUnit() string {
+	if i.Unit == "" {
+		return "sat"
+	}
+	return i.Unit
+}
+func (i *Invoice) GetUnitAmount() uint64 {
+	if i.Unit == "" {
+		return uint64(i.Amount)
+	}
+	return i.UnitAmount
+}
 func (i *Invoice) IsIssued() bool {
 	return i.Issued
 }
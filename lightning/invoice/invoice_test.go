@@ -0,0 +1,26 @@
+package invoice
+
+import (
+	"testing"
+)
+
+func TestInvoice_Log(t *testing.T) {
+	tests := []struct {
+		name    string
+		invoice Invoice
+	}{
+		{name: "withWebhookToken", invoice: Invoice{Hash: "hash", Amount: 10, WebhookToken: "secret"}},
+		{name: "withoutWebhookToken", invoice: Invoice{Hash: "hash", Amount: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.invoice.Log()
+			if _, ok := got["WebhookToken"]; ok {
+				t.Errorf("Log() = %v, must not contain the webhook token", got)
+			}
+			if got["Hash"] != "hash" {
+				t.Errorf("Log() = %v, want hash", got)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package lightning
 
 import (
+	"errors"
 	"fmt"
 	cashuLog "github.com/cashubtc/cashu-feni/log"
+	"net"
 	"time"
 )
 
@@ -19,6 +21,7 @@ type Invoicer interface {
 	GetHash() string  // get the payment hash
 
 	SetPaid(i bool) // SetPaid to true, if lightning invoice was paid
+	IsPaid() bool   // IsPaid returns true, if lightning invoice was paid
 
 	SetIssued(i bool) // SetIssued to true, if lightning invoice was paid
 	IsIssued() bool   // IsIssued returns true, if lightning invoice is paid
@@ -26,6 +29,10 @@ type Invoicer interface {
 	SetAmount(a int64) // SetAmount of the lightning invoice
 	GetAmount() int64  // GetAmount of the lightning invoice
 
+	SetUnit(unit string, amount uint64) // SetUnit sets the unit and the amount in unit of the mint request
+	GetUnit() string                    // GetUnit returns the unit of the mint request (sat, if not set)
+	GetUnitAmount() uint64              // GetUnitAmount returns the amount of the mint request in unit
+
 	GetPaymentRequest() string // GetPaymentRequest should return the payment request (probably bech encoded)
 	SetPaymentRequest(string)  // SetPaymentRequest
 
@@ -33,6 +40,19 @@ type Invoicer interface {
 	SetTimePaid(t time.Time)
 }
 
+// DescriptionHashInvoiceCreator is implemented by lightning clients that can create invoices committing to a
+// description hash instead of a memo (required for lnurl-pay).
+type DescriptionHashInvoiceCreator interface {
+	// CreateInvoiceWithDescription should create an invoice for amount, with the sha256 hash of description as description hash
+	CreateInvoiceWithDescription(amount int64, description string) (Invoicer, error)
+}
+
+// WebhookInvoicer is an invoice that will be settled by a webhook call of the lightning backend.
+type WebhookInvoicer interface {
+	Invoicer
+	GetWebhookToken() string // GetWebhookToken returns the token that authenticates the webhook call
+}
+
 // Payment should give information about the payment status
 type Payment interface {
 	IsPaid() bool        // IsPaid must return true, if payment is fulfilled
@@ -40,9 +60,54 @@ type Payment interface {
 	// TODO -- add Change
 }
 
+// OfferPayment is the payment of a BOLT12 offer
+type OfferPayment interface {
+	Payment
+	GetInvoice() string // GetInvoice must return the BOLT12 invoice, that was fetched for the offer and paid
+}
+
+// OfferPayer is implemented by lightning clients that can pay BOLT12 offers.
+type OfferPayer interface {
+	// PayOffer should fetch an invoice for amountMsat from the offer and pay it. The routing fees must not exceed maxFeeMsat.
+	PayOffer(offer string, amountMsat uint64, maxFeeMsat uint64) (OfferPayment, error)
+}
+
+// FeeLimitedPayer is implemented by lightning clients that can limit the routing fees of a payment.
+type FeeLimitedPayer interface {
+	// PayWithFeeLimit should pay the payment request. The routing fees must not exceed maxFeeMsat.
+	PayWithFeeLimit(paymentRequest string, maxFeeMsat uint64) (Invoicer, error)
+}
+
 // Client should be able to perform lightning services
 type Client interface {
 	InvoiceStatus(paymentHash string) (Payment, error)         // InvoiceStatus should return Payment information for a payment hash
 	Pay(paymentRequest string) (Invoicer, error)               // Pay should pay the payment request.
 	CreateInvoice(amount int64, memo string) (Invoicer, error) // CreateInvoice should create an invoice for given amount and memo
 }
+
+// Liquidity of a lightning backend in milli satoshi.
+type Liquidity struct {
+	Inbound  uint64 // Inbound liquidity for receiving payments. Zero, if unknown.
+	Outbound uint64 // Outbound liquidity for sending payments.
+}
+
+// LiquidityReporter is implemented by lightning clients that know about their liquidity.
+type LiquidityReporter interface {
+	Liquidity() (Liquidity, error) // Liquidity should return the current liquidity of the backend
+}
+
+// ErrPaymentNotSent marks payment errors, which occurred before the payment was sent (e.g. the backend was not reachable).
+// Only these payments can be retried safely. Other errors (e.g. timeouts) may belong to payments, which are still in flight.
+var ErrPaymentNotSent = errors.New("payment not sent")
+
+// PaymentNotSent marks err as error of a payment, which was not sent
+func PaymentNotSent(err error) error {
+	return fmt.Errorf("%w: %v", ErrPaymentNotSent, err)
+}
+
+// IsConnectionError returns true, if err is returned, because the connection to the backend could not be established.
+// Requests with these errors never reached the backend.
+func IsConnectionError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,80 @@
+package lightning
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	_, refused := http.Get("http://" + address)
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connectionRefused", err: refused, want: true},
+		{name: "timeout", err: fmt.Errorf("timeout"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConnectionError(tt.err); got != tt.want {
+				t.Errorf("IsConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+			if got := errors.Is(PaymentNotSent(tt.err), ErrPaymentNotSent); !got {
+				t.Errorf("PaymentNotSent() is not ErrPaymentNotSent")
+			}
+		})
+	}
+}
+
+func TestFeeReserve(t *testing.T) {
+	config := Config
+	t.Cleanup(func() { Config = config })
+	tests := []struct {
+		name      string
+		configure func(c *Configuration)
+		amount    uint64
+		want      uint64
+	}{
+		{name: "lnbits", amount: 10000000, want: 10000, configure: func(c *Configuration) {
+			c.Lightning.Lnbits = &LnbitsConfig{LightningFeePercent: 1.0, LightningReserveFeeMin: 4000}
+		}},
+		{name: "cln", amount: 1000, want: 2000, configure: func(c *Configuration) {
+			c.Lightning.Cln = &ClnConfig{LightningFeePercent: 1.0, LightningReserveFeeMin: 2000}
+		}},
+		{name: "backendsOnly", amount: 10000000, want: 20000, configure: func(c *Configuration) {
+			c.Lightning.Backends = []*BackendConfig{
+				{Lnbits: &LnbitsConfig{LightningFeePercent: 1.0, LightningReserveFeeMin: 4000}},
+				{Cln: &ClnConfig{LightningFeePercent: 2.0, LightningReserveFeeMin: 1000}},
+			}
+		}},
+		{name: "backendDefault", amount: 1000, want: 3000, configure: func(c *Configuration) {
+			c.Lightning.LightningReserveFeeMin = 3000
+			c.Lightning.Backends = []*BackendConfig{{Cln: &ClnConfig{}}}
+		}},
+		{name: "noBackends", amount: 10000000, want: 5000, configure: func(c *Configuration) {
+			c.Lightning.LightningFeePercent = 0.5
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config = Configuration{}
+			tt.configure(&Config)
+			if got := FeeReserve(tt.amount, false); got != tt.want {
+				t.Errorf("FeeReserve() = %d, want %d", got, tt.want)
+			}
+			if got := FeeReserve(tt.amount, true); got != 0 {
+				t.Errorf("FeeReserve() = %d, want no fees for internal payments", got)
+			}
+		})
+	}
+}
@@ -1,17 +1,21 @@
 package lnbits
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/cashubtc/cashu-feni/lightning"
 	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"net/url"
 	"time"
 
 	"github.com/imroc/req"
 )
 
 // NewClient returns a new lnbits api client. Pass your API key and url here.
-func NewClient(key, url string) lightning.Client {
-	return &Client{
+func NewClient(key, url string, opt ...ClientOptions) lightning.Client {
+	c := &Client{
 		url: url,
 		// info: this header holds the ADMIN key for the entire API
 		// it can be used to create wallets for example
@@ -23,6 +27,38 @@ func NewClient(key, url string) lightning.Client {
 			"X-Api-Key":    key,
 		},
 	}
+	for _, o := range opt {
+		o(c)
+	}
+	return c
+}
+
+type ClientOptions func(c *Client)
+
+// WithWebhook will register webhookUrl for every created invoice.
+// LNbits calls the webhook as soon as the invoice is paid.
+func WithWebhook(webhookUrl string) ClientOptions {
+	return func(c *Client) {
+		c.webhook = webhookUrl
+	}
+}
+
+// webhookWithToken appends a new random token to the webhook url.
+// The token authenticates the webhook call for a single invoice.
+func (c Client) webhookWithToken() (string, string, error) {
+	u, err := url.Parse(c.webhook)
+	if err != nil {
+		return "", "", err
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), token, nil
 }
 
 // Info returns wallet information
@@ -46,13 +82,39 @@ func (c Client) Status() (wtx Wallet, err error) {
 	return
 }
 
+// Liquidity returns the wallet balance as outbound liquidity.
+func (c Client) Liquidity() (lightning.Liquidity, error) {
+	wallet, err := c.Status()
+	if err != nil {
+		return lightning.Liquidity{}, err
+	}
+	return lightning.Liquidity{Outbound: wallet.Balance}, nil
+}
+
 func NewInvoice() lightning.Invoicer {
 	return &invoice.Invoice{}
 }
 
 // Invoice creates an invoice associated with this wallet.
 func (c *Client) CreateInvoice(amount int64, memo string) (lightning.Invoicer, error) {
-	params := InvoiceParams{Amount: amount, Memo: memo}
+	return c.createInvoice(InvoiceParams{Amount: amount, Memo: memo})
+}
+
+// CreateInvoiceWithDescription creates an invoice with the sha256 hash of description as description hash.
+func (c *Client) CreateInvoiceWithDescription(amount int64, description string) (lightning.Invoicer, error) {
+	h := sha256.Sum256([]byte(description))
+	return c.createInvoice(InvoiceParams{Amount: amount, DescriptionHash: hex.EncodeToString(h[:])})
+}
+
+func (c *Client) createInvoice(params InvoiceParams) (lightning.Invoicer, error) {
+	var token string
+	if c.webhook != "" {
+		var err error
+		params.Webhook, token, err = c.webhookWithToken()
+		if err != nil {
+			return nil, err
+		}
+	}
 	resp, err := req.Post(c.url+"/api/v1/payments", c.header, req.BodyJSON(&params))
 	if err != nil {
 		return nil, err
@@ -71,6 +133,7 @@ func (c *Client) CreateInvoice(amount int64, memo string) (lightning.Invoicer, e
 	err = resp.ToJSON(i)
 	if err == nil {
 		i.SetAmount(params.Amount)
+		i.WebhookToken = token
 		return i, nil
 	}
 	return nil, err
@@ -83,6 +146,9 @@ func (c *Client) Pay(paymentRequest string) (wtx lightning.Invoicer, err error)
 	params := PaymentParams{Out: true, Bolt11: paymentRequest}
 	resp, err := r.Post(c.url+"/api/v1/payments", c.header, req.BodyJSON(&params))
 	if err != nil {
+		if lightning.IsConnectionError(err) {
+			err = lightning.PaymentNotSent(err)
+		}
 		return
 	}
 	i := invoice.Invoice{}
@@ -0,0 +1,60 @@
+package lnbits
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+)
+
+func TestClient_CreateInvoice(t *testing.T) {
+	tests := []struct {
+		name      string
+		webhook   string
+		wantToken bool
+	}{
+		{name: "withoutWebhook", webhook: "", wantToken: false},
+		{name: "withWebhook", webhook: "https://mint.example.com/lnbits/webhook", wantToken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params InvoiceParams
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte(`{"payment_hash":"hash","payment_request":"lnbc1"}`))
+			}))
+			defer server.Close()
+			options := make([]ClientOptions, 0)
+			if tt.webhook != "" {
+				options = append(options, WithWebhook(tt.webhook))
+			}
+			client := NewClient("key", server.URL, options...)
+			i, err := client.CreateInvoice(10, "memo")
+			if err != nil {
+				t.Fatalf("CreateInvoice() error = %v", err)
+			}
+			token := i.(*invoice.Invoice).GetWebhookToken()
+			if (token != "") != tt.wantToken {
+				t.Fatalf("CreateInvoice() token = %s, wantToken %v", token, tt.wantToken)
+			}
+			if !tt.wantToken {
+				if params.Webhook != "" {
+					t.Errorf("CreateInvoice() webhook = %s, want empty", params.Webhook)
+				}
+				return
+			}
+			u, err := url.Parse(params.Webhook)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Query().Get("token") != token {
+				t.Errorf("CreateInvoice() webhook token = %s, want %s", u.Query().Get("token"), token)
+			}
+		})
+	}
+}
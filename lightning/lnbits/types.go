@@ -7,6 +7,7 @@ import (
 type Client struct {
 	header     req.Header
 	url        string
+	webhook    string
 	AdminKey   string
 	InvoiceKey string
 }
@@ -0,0 +1,91 @@
+package lnurl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/imroc/req"
+)
+
+const hrp = "lnurl"
+
+// Decode will decode a bech32 encoded lnurl (LUD-01) and return the url.
+func Decode(lnurl string) (string, error) {
+	lnurl = strings.TrimPrefix(strings.ToLower(lnurl), "lightning:")
+	prefix, data, err := bech32.DecodeNoLimit(lnurl)
+	if err != nil {
+		return "", err
+	}
+	if prefix != hrp {
+		return "", fmt.Errorf("invalid lnurl prefix: %s", prefix)
+	}
+	decoded, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(string(decoded))
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Encode will encode the url as bech32 lnurl.
+func Encode(u string) (string, error) {
+	converted, err := bech32.ConvertBits([]byte(u), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := bech32.Encode(hrp, converted)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(encoded), nil
+}
+
+// IsLnurl returns true, if s looks like a bech32 encoded lnurl.
+func IsLnurl(s string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(strings.ToLower(s), "lightning:"), hrp+"1")
+}
+
+// Error is returned by lnurl services (LUD-06).
+type Error struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (e Error) Error() string {
+	return e.Reason
+}
+
+const statusError = "ERROR"
+
+// NewError returns the lnurl error response for err.
+func NewError(err error) Error {
+	return Error{Status: statusError, Reason: err.Error()}
+}
+
+// get will request u and decode the json response into v.
+// lnurl error responses are returned as Error.
+func get(u string, v interface{}, params ...interface{}) error {
+	resp, err := req.Get(u, params...)
+	if err != nil {
+		return err
+	}
+	lnurlErr := Error{}
+	if err = resp.ToJSON(&lnurlErr); err != nil {
+		return err
+	}
+	if strings.ToUpper(lnurlErr.Status) == statusError {
+		return lnurlErr
+	}
+	if resp.Response().StatusCode >= 300 {
+		return fmt.Errorf("lnurl service returned status %d", resp.Response().StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	return resp.ToJSON(v)
+}
@@ -0,0 +1,223 @@
+package lnurl
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		lnurl   string
+		want    string
+		wantErr bool
+	}{
+		{name: "lud01", lnurl: "LNURL1DP68GURN8GHJ7UM9WFMXJCM99E3K7MF0V9CXJ0M385EKVCENXC6R2C35XVUKXEFCV5MKVV34X5EKZD3EV56NYD3HXQURZEPEXEJXXEPNXSCRVWFNV9NXZCN9XQ6XYEFHVGCXXCMYXYMNSERXFQ5FNS",
+			want: "https://service.com/api?q=3fc3645b439ce8e7f2553a69e5267081d96dcd340693afabe04be7b0ccd178df"},
+		{name: "lightningScheme", lnurl: "lightning:LNURL1DP68GURN8GHJ7UM9WFMXJCM99E3K7MF0V9CXJ0M385EKVCENXC6R2C35XVUKXEFCV5MKVV34X5EKZD3EV56NYD3HXQURZEPEXEJXXEPNXSCRVWFNV9NXZCN9XQ6XYEFHVGCXXCMYXYMNSERXFQ5FNS",
+			want: "https://service.com/api?q=3fc3645b439ce8e7f2553a69e5267081d96dcd340693afabe04be7b0ccd178df"},
+		{name: "invalid", lnurl: "lnurl1invalid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.lnurl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	u := "https://mint.example.com/lnurl/withdraw?k1=123"
+	encoded, err := Encode(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsLnurl(encoded) {
+		t.Errorf("IsLnurl(%s) = false, want true", encoded)
+	}
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != u {
+		t.Errorf("Decode(Encode()) = %s, want %s", decoded, u)
+	}
+}
+
+func TestWithdrawParams_Withdraw(t *testing.T) {
+	var paymentRequest string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/withdraw", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(WithdrawParams{
+			Tag:             tagWithdrawRequest,
+			Callback:        server.URL + "/callback?id=1",
+			K1:              "k1",
+			MinWithdrawable: 1000,
+			MaxWithdrawable: 100000,
+		})
+	})
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("k1") != "k1" || r.URL.Query().Get("id") != "1" {
+			json.NewEncoder(w).Encode(Error{Status: statusError, Reason: "invalid k1"})
+			return
+		}
+		paymentRequest = r.URL.Query().Get("pr")
+		w.Write([]byte(`{"status":"OK"}`))
+	})
+	encoded, err := Encode(server.URL + "/withdraw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := FetchWithdrawParams(encoded)
+	if err != nil {
+		t.Fatalf("FetchWithdrawParams() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		amount  uint64
+		wantErr bool
+	}{
+		{name: "tooLow", amount: 0, wantErr: true},
+		{name: "withdrawable", amount: 100, wantErr: false},
+		{name: "tooHigh", amount: 101, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := params.CheckAmount(tt.amount); (err != nil) != tt.wantErr {
+				t.Errorf("CheckAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err = params.Withdraw("lnbc1"); err != nil {
+		t.Fatalf("Withdraw() error = %v", err)
+	}
+	if paymentRequest != "lnbc1" {
+		t.Errorf("Withdraw() pr = %s, want lnbc1", paymentRequest)
+	}
+	params.K1 = "invalid"
+	if err = params.Withdraw("lnbc1"); err == nil || err.Error() != "invalid k1" {
+		t.Errorf("Withdraw() error = %v, want invalid k1", err)
+	}
+}
+
+// newTestPaymentRequest creates a signed bolt11 payment request for amount msat committing to descriptionHash.
+func newTestPaymentRequest(t *testing.T, amount uint64, descriptionHash [32]byte) string {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var preimage [32]byte
+	if _, err = rand.Read(preimage[:]); err != nil {
+		t.Fatal(err)
+	}
+	i, err := zpay32.NewInvoice(&chaincfg.MainNetParams, sha256.Sum256(preimage[:]), time.Now(),
+		zpay32.Amount(lnwire.MilliSatoshi(amount)), zpay32.DescriptionHash(descriptionHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := i.Encode(zpay32.MessageSigner{SignCompact: func(msg []byte) ([]byte, error) {
+		return ecdsa.SignCompact(key, chainhash.HashB(msg), true)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pr
+}
+
+func TestLightningAddressUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+		wantErr bool
+	}{
+		{name: "address", address: "Satoshi@Mint.Example.com", want: "https://mint.example.com/.well-known/lnurlp/satoshi"},
+		{name: "onion", address: "satoshi@mint.onion", want: "http://mint.onion/.well-known/lnurlp/satoshi"},
+		{name: "invalid", address: "satoshi", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LightningAddressUrl(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LightningAddressUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LightningAddressUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayParams_Invoice(t *testing.T) {
+	metadata := `[["text/plain","pay satoshi"]]`
+	tests := []struct {
+		name            string
+		amount          uint64
+		invoiceAmount   uint64
+		descriptionHash [32]byte
+		wantErr         bool
+	}{
+		{name: "valid", amount: 100, invoiceAmount: 100000, descriptionHash: sha256.Sum256([]byte(metadata))},
+		{name: "amountMismatch", amount: 100, invoiceAmount: 200000, descriptionHash: sha256.Sum256([]byte(metadata)), wantErr: true},
+		{name: "metadataMismatch", amount: 100, invoiceAmount: 100000, descriptionHash: sha256.Sum256([]byte("other")), wantErr: true},
+		{name: "notSendable", amount: 1000, invoiceAmount: 1000000, descriptionHash: sha256.Sum256([]byte(metadata)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			mux.HandleFunc("/pay", func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(PayParams{
+					Tag:         TagPayRequest,
+					Callback:    server.URL + "/callback",
+					MinSendable: 1000,
+					MaxSendable: 500000,
+					Metadata:    metadata,
+				})
+			})
+			mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("amount") != fmt.Sprintf("%d", tt.amount*1000) {
+					json.NewEncoder(w).Encode(Error{Status: statusError, Reason: "invalid amount"})
+					return
+				}
+				json.NewEncoder(w).Encode(PayResponse{Pr: newTestPaymentRequest(t, tt.invoiceAmount, tt.descriptionHash)})
+			})
+			encoded, err := Encode(server.URL + "/pay")
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := FetchPayParams(encoded)
+			if err != nil {
+				t.Fatalf("FetchPayParams() error = %v", err)
+			}
+			pr, err := params.Invoice(tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Invoice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && pr == "" {
+				t.Errorf("Invoice() returned empty payment request")
+			}
+		})
+	}
+}
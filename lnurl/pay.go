@@ -0,0 +1,104 @@
+package lnurl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/imroc/req"
+)
+
+// TagPayRequest is the tag of lnurl-pay services.
+const TagPayRequest = "payRequest"
+
+// PayParams are the parameters of a lnurl-pay service (LUD-06).
+type PayParams struct {
+	Tag            string `json:"tag"`
+	Callback       string `json:"callback"`
+	MinSendable    uint64 `json:"minSendable"` // minimum amount in milli satoshi
+	MaxSendable    uint64 `json:"maxSendable"` // maximum amount in milli satoshi
+	Metadata       string `json:"metadata"`
+	CommentAllowed int    `json:"commentAllowed,omitempty"`
+}
+
+// PayResponse is returned by the callback of a lnurl-pay service.
+type PayResponse struct {
+	Pr     string        `json:"pr"`
+	Routes []interface{} `json:"routes"`
+}
+
+// IsLightningAddress returns true, if s looks like a lightning address (LUD-16).
+func IsLightningAddress(s string) bool {
+	name, domain, found := strings.Cut(s, "@")
+	return found && name != "" && strings.Contains(domain, ".")
+}
+
+// LightningAddressUrl returns the url of the lnurl-pay service for a lightning address.
+func LightningAddressUrl(address string) (string, error) {
+	if !IsLightningAddress(address) {
+		return "", fmt.Errorf("invalid lightning address: %s", address)
+	}
+	name, domain, _ := strings.Cut(strings.ToLower(address), "@")
+	scheme := "https"
+	if strings.HasSuffix(domain, ".onion") {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/.well-known/lnurlp/%s", scheme, domain, name), nil
+}
+
+// FetchPayParams will resolve a lightning address or lnurl and request the pay parameters from the service.
+func FetchPayParams(target string) (*PayParams, error) {
+	var u string
+	var err error
+	if IsLightningAddress(target) {
+		u, err = LightningAddressUrl(target)
+	} else {
+		u, err = Decode(target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	params := PayParams{}
+	if err = get(u, &params); err != nil {
+		return nil, err
+	}
+	if params.Tag != TagPayRequest {
+		return nil, fmt.Errorf("lnurl is not a pay request: %s", params.Tag)
+	}
+	return &params, nil
+}
+
+// CheckAmount returns an error, if amount (in satoshi) can not be sent.
+func (p PayParams) CheckAmount(amount uint64) error {
+	if amount*1000 < p.MinSendable || amount*1000 > p.MaxSendable {
+		return fmt.Errorf("amount %d sat not sendable. min: %d sat, max: %d sat",
+			amount, p.MinSendable/1000, p.MaxSendable/1000)
+	}
+	return nil
+}
+
+// Invoice requests a payment request for amount (in satoshi) from the callback.
+// The payment request is verified against the requested amount and the metadata hash.
+func (p PayParams) Invoice(amount uint64) (string, error) {
+	if err := p.CheckAmount(amount); err != nil {
+		return "", err
+	}
+	response := PayResponse{}
+	if err := get(p.Callback, &response, req.QueryParam{"amount": amount * 1000}); err != nil {
+		return "", err
+	}
+	bolt, err := lightning.DecodePaymentRequest(response.Pr)
+	if err != nil {
+		return "", err
+	}
+	if uint64(bolt.MSatoshi) != amount*1000 {
+		return "", fmt.Errorf("invoice amount %d msat does not match requested amount %d msat", bolt.MSatoshi, amount*1000)
+	}
+	metadataHash := sha256.Sum256([]byte(p.Metadata))
+	if bolt.DescriptionHash != hex.EncodeToString(metadataHash[:]) {
+		return "", fmt.Errorf("invoice description hash does not match metadata")
+	}
+	return response.Pr, nil
+}
@@ -0,0 +1,50 @@
+package lnurl
+
+import (
+	"fmt"
+
+	"github.com/imroc/req"
+)
+
+const tagWithdrawRequest = "withdrawRequest"
+
+// WithdrawParams are the parameters of a lnurl-withdraw service (LUD-03).
+type WithdrawParams struct {
+	Tag                string `json:"tag"`
+	Callback           string `json:"callback"`
+	K1                 string `json:"k1"`
+	MinWithdrawable    uint64 `json:"minWithdrawable"` // minimum amount in milli satoshi
+	MaxWithdrawable    uint64 `json:"maxWithdrawable"` // maximum amount in milli satoshi
+	DefaultDescription string `json:"defaultDescription"`
+}
+
+// FetchWithdrawParams will decode the lnurl and request the withdraw parameters from the service.
+func FetchWithdrawParams(lnurl string) (*WithdrawParams, error) {
+	u, err := Decode(lnurl)
+	if err != nil {
+		return nil, err
+	}
+	params := WithdrawParams{}
+	if err = get(u, &params); err != nil {
+		return nil, err
+	}
+	if params.Tag != tagWithdrawRequest {
+		return nil, fmt.Errorf("lnurl is not a withdraw request: %s", params.Tag)
+	}
+	return &params, nil
+}
+
+// CheckAmount returns an error, if amount (in satoshi) can not be withdrawn.
+func (w WithdrawParams) CheckAmount(amount uint64) error {
+	if amount*1000 < w.MinWithdrawable || amount*1000 > w.MaxWithdrawable {
+		return fmt.Errorf("amount %d sat not withdrawable. min: %d sat, max: %d sat",
+			amount, w.MinWithdrawable/1000, w.MaxWithdrawable/1000)
+	}
+	return nil
+}
+
+// Withdraw will submit the payment request to the withdraw callback.
+// The service pays the payment request asynchronously.
+func (w WithdrawParams) Withdraw(paymentRequest string) error {
+	return get(w.Callback, nil, req.QueryParam{"k1": w.K1, "pr": paymentRequest})
+}
@@ -0,0 +1,210 @@
+package mint
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// lightningAddressName is the allowed local part of a lightning address (LUD-16).
+var lightningAddressName = regexp.MustCompile(`^[a-z0-9\-_.]{1,64}$`)
+
+// RegisterLightningAddress registers name as lightning address for pubkey.
+// The signature proofs ownership of pubkey and must sign the name.
+func (m *Mint) RegisterLightningAddress(name, pubkey, signature string) error {
+	if !lightningAddressName.MatchString(name) {
+		return cashu.NewError(cashu.ErrCodeBadRequest, "invalid lightning address name: %s", name)
+	}
+	if err := cashu.VerifySchnorrSignature(pubkey, signature, []byte(name)); err != nil {
+		return err
+	}
+	if _, err := m.database.GetLightningAddress(name); err == nil {
+		return cashu.NewError(cashu.ErrCodeBadRequest, "lightning address already registered: %s", name)
+	}
+	err := m.database.StoreLightningAddress(cashu.LightningAddress{Name: name, PublicKey: pubkey, TimeCreated: time.Now()})
+	if err != nil {
+		return err
+	}
+	log.WithField("name", name).Info("registered lightning address")
+	return nil
+}
+
+// LightningAddress returns the registered lightning address name.
+func (m *Mint) LightningAddress(name string) (cashu.LightningAddress, error) {
+	address, err := m.database.GetLightningAddress(name)
+	if err != nil {
+		return address, cashu.NewError(cashu.ErrCodeNotFound, "unknown lightning address: %s", name)
+	}
+	return address, nil
+}
+
+// RequestLightningAddressMint creates the invoice for a payment to the lightning address name.
+// The invoice commits to the lnurl-pay metadata using the description hash.
+func (m *Mint) RequestLightningAddressMint(name string, amount uint64, metadata string) (lightning.Invoicer, error) {
+	if _, err := m.LightningAddress(name); err != nil {
+		return nil, err
+	}
+	if m.client == nil {
+		return nil, cashu.NewError(cashu.ErrCodeMethodNotSupported, "lightning is not enabled")
+	}
+	creator, ok := m.client.(lightning.DescriptionHashInvoiceCreator)
+	if !ok {
+		return nil, cashu.NewError(cashu.ErrCodeMethodNotSupported, "lightning backend does not support lightning addresses")
+	}
+	observe := observeLightning("create_invoice")
+	invoice, err := creator.CreateInvoiceWithDescription(int64(amount), metadata)
+	observe()
+	if err != nil {
+		return nil, err
+	}
+	invoice.SetTimeCreated(time.Now())
+	err = m.database.StoreLightningInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+	err = m.database.StoreLightningAddressPayment(cashu.LightningAddressPayment{Hash: invoice.GetHash(), Name: name, Amount: amount})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// paidLightningAddressPayments returns all paid and unclaimed payments of the lightning address name.
+func (m *Mint) paidLightningAddressPayments(name string) ([]cashu.LightningAddressPayment, error) {
+	payments, err := m.database.GetLightningAddressPayments(name, false)
+	if err != nil {
+		return nil, err
+	}
+	paid := make([]cashu.LightningAddressPayment, 0)
+	for _, payment := range payments {
+		invoice, err := m.database.GetLightningInvoice(payment.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if invoice.IsIssued() {
+			continue
+		}
+		isPaid, err := m.updateInvoicePaid(invoice)
+		if err != nil {
+			log.WithFields(log.Fields{"error.message": err.Error(), "hash": payment.Hash}).Warn("could not check invoice status")
+			continue
+		}
+		if isPaid {
+			paid = append(paid, payment)
+		}
+	}
+	return paid, nil
+}
+
+// LightningAddressClaimable returns the amount that was paid to the lightning address name and not claimed yet.
+func (m *Mint) LightningAddressClaimable(name string) (uint64, error) {
+	payments, err := m.paidLightningAddressPayments(name)
+	if err != nil {
+		return 0, err
+	}
+	var amount uint64
+	for _, payment := range payments {
+		amount += payment.Amount
+	}
+	return amount, nil
+}
+
+// ClaimLightningAddress signs outputs for all paid payments to the lightning address name.
+// The signature must be created by the registered key and sign all concatenated B_ of the outputs.
+// The outputs amount has to match the claimable amount exactly.
+// Claims are authorized by this signature instead of P2PK-locked proofs, because the mint does not support P2PK spending conditions.
+func (m *Mint) ClaimLightningAddress(name string, outputs cashu.BlindedMessages, signature string) ([]cashu.BlindedSignature, error) {
+	address, err := m.LightningAddress(name)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyNoDuplicateOutputs(outputs) {
+		return nil, cashu.ErrDuplicateOutputs
+	}
+	message := make([]byte, 0)
+	amounts := make([]uint64, 0)
+	keys := make([]*secp256k1.PublicKey, 0)
+	var total uint64
+	for _, output := range outputs {
+		if _, err = verifyAmount(output.Amount); err != nil {
+			return nil, err
+		}
+		key, err := parseBlindedMessage(output.B_)
+		if err != nil {
+			return nil, err
+		}
+		message = append(message, []byte(output.B_)...)
+		amounts = append(amounts, output.Amount)
+		keys = append(keys, key)
+		total += output.Amount
+	}
+	if err = cashu.VerifySchnorrSignature(address.PublicKey, signature, message); err != nil {
+		return nil, err
+	}
+	payments, err := m.paidLightningAddressPayments(name)
+	if err != nil {
+		return nil, err
+	}
+	var claimable uint64
+	for _, payment := range payments {
+		claimable += payment.Amount
+	}
+	if claimable == 0 {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "nothing to claim for lightning address %s", name)
+	}
+	if total != claimable {
+		return nil, cashu.NewError(cashu.ErrCodeTransactionUnbalanced, "outputs amount %d does not match claimable amount %d", total, claimable)
+	}
+	if err = m.claimLightningAddressPayments(payments); err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"name": name, "amount": claimable}).Info("claimed lightning address payments")
+	keySet, err := m.ActiveKeySet(crypto.UnitSat)
+	if err != nil {
+		return nil, err
+	}
+	promises, err := m.generatePromises(amounts, keySet, keys)
+	if err != nil {
+		return nil, err
+	}
+	observeMinted(promises)
+	return promises, nil
+}
+
+// claimLightningAddressPayments marks the payments as claimed and their invoices as issued.
+// Payments are claimed and invoices are issued with conditional updates. If a concurrent claim claimed any of the payments first,
+// the payments claimed by this claim are released again and the claim fails.
+func (m *Mint) claimLightningAddressPayments(payments []cashu.LightningAddressPayment) error {
+	claimed := make([]cashu.LightningAddressPayment, 0)
+	for _, payment := range payments {
+		ok, err := m.database.ClaimLightningAddressPayment(payment.Hash)
+		if err == nil && !ok {
+			err = fmt.Errorf("%w for lightning address payment %s", cashu.ErrTokensIssued, payment.Hash)
+		}
+		if err != nil {
+			for _, p := range claimed {
+				if releaseErr := m.database.StoreLightningAddressPayment(p); releaseErr != nil {
+					log.WithFields(log.Fields{"error.message": releaseErr.Error(), "hash": p.Hash}).Error("could not release lightning address payment")
+				}
+			}
+			return err
+		}
+		claimed = append(claimed, payment)
+	}
+	for _, payment := range payments {
+		issued, err := m.database.IssueLightningInvoice(payment.Hash)
+		if err != nil {
+			return err
+		}
+		if !issued {
+			return fmt.Errorf("%w for lightning address payment %s", cashu.ErrTokensIssued, payment.Hash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package mint
+
+import (
+	"context"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// persistKeySets stores all keysets of the mint as active keysets.
+// Stored keysets, which are not loaded anymore, are retired and expire after the grace period.
+func (m *Mint) persistKeySets() error {
+	stored, err := m.database.GetKeySet()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	now := time.Now()
+	for _, k := range stored {
+		known[k.Id] = true
+		if _, ok := m.keySets[k.Id]; ok || !k.Active {
+			continue
+		}
+		log.WithField("keyset", k.Id).Info("retiring keyset")
+		err = m.database.UpdateKeySet(k.Id, db.UpdateKeySetActive(false), db.UpdateKeySetValidTo(now.Add(m.keySetGracePeriod)))
+		if err != nil {
+			return err
+		}
+	}
+	for id, k := range m.keySets {
+		if known[id] {
+			continue
+		}
+		keySet := *k
+		keySet.Active = true
+		keySet.ValidFrom = now
+		if err = m.database.StoreKeySet(keySet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRetiredKeySets loads the stored keysets, which are retired but did not expire yet, so that their proofs can still be redeemed.
+// The keys are derived from the stored derivation path. It is only called by New, before the keysets are shared with requests.
+func (m *Mint) loadRetiredKeySets() error {
+	stored, err := m.database.GetKeySet()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, k := range stored {
+		if _, ok := m.keySets[k.Id]; ok || k.Active || !k.ValidTo.After(now) {
+			continue
+		}
+		keySet := crypto.NewKeySetWithUnit(m.masterKey, k.DerivationPath, k.GetUnit())
+		if keySet.Id != k.Id {
+			log.WithFields(log.Fields{"keyset": k.Id, "derivation_path": k.DerivationPath}).Warn("could not derive retired keyset")
+			continue
+		}
+		keySet.ValidFrom, keySet.ValidTo = k.ValidFrom, k.ValidTo
+		m.keySets[k.Id] = keySet
+		log.WithFields(log.Fields{"keyset": k.Id, "valid_to": k.ValidTo}).Info("loaded retired keyset")
+	}
+	return nil
+}
+
+// expiredKeySetIds returns the ids of all retired keysets, which are past their ValidTo
+func (m *Mint) expiredKeySetIds() ([]string, error) {
+	stored, err := m.database.GetKeySet()
+	if err != nil {
+		return nil, err
+	}
+	_, activeKeySets := m.loadedKeySets()
+	active := make(map[string]bool)
+	for _, id := range activeKeySets {
+		active[id] = true
+	}
+	ids := make([]string, 0)
+	now := time.Now()
+	for _, k := range stored {
+		if active[k.Id] || k.Active {
+			continue
+		}
+		if !k.ValidTo.IsZero() && k.ValidTo.Before(now) {
+			ids = append(ids, k.Id)
+		}
+	}
+	return ids, nil
+}
+
+// ArchiveSpentProofs unloads all expired keysets and moves their spent proofs into the archive.
+func (m *Mint) ArchiveSpentProofs() (int64, error) {
+	ids, err := m.expiredKeySetIds()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	m.unloadKeySets(ids...)
+	archived, err := m.database.ArchiveProofs(ids...)
+	if err != nil {
+		return 0, err
+	}
+	if archived > 0 {
+		log.WithField("keysets", ids).Infof("archived %d spent proofs", archived)
+	}
+	return archived, nil
+}
+
+// WatchExpiredKeySets will archive spent proofs of expired keysets in the given interval.
+// WatchExpiredKeySets blocks until ctx is done.
+func (m *Mint) WatchExpiredKeySets(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	log.WithField("interval", interval.String()).Info("starting proof archiver")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stopping proof archiver")
+			return
+		case <-ticker.C:
+			if _, err := m.ArchiveSpentProofs(); err != nil {
+				log.WithFields(log.Fields{"error.message": err.Error()}).Errorf("could not archive spent proofs")
+			}
+		}
+	}
+}
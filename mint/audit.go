@@ -0,0 +1,144 @@
+package mint
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/exchange"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/onchain"
+	log "github.com/sirupsen/logrus"
+)
+
+// KeySetLiability is the outstanding ecash of a keyset in the unit of the keyset.
+// Outstanding can be negative for keysets, whose promises were issued before promises had a keyset id.
+type KeySetLiability struct {
+	Id          string `json:"id"`
+	Unit        string `json:"unit"`
+	Issued      uint64 `json:"issued"`
+	Redeemed    uint64 `json:"redeemed"`
+	Outstanding int64  `json:"outstanding"`
+}
+
+// AuditReport compares the outstanding ecash of all keysets with the balances of the lightning and on-chain backends.
+type AuditReport struct {
+	Time    time.Time         `json:"time"`
+	KeySets []KeySetLiability `json:"keysets"`
+	// Outstanding ecash of all keysets in satoshi
+	Outstanding uint64 `json:"outstanding"`
+	// BackendBalance of the lightning backend in satoshi. nil, if the backend does not report its balance.
+	BackendBalance *uint64 `json:"backend_balance"`
+	// OnchainBalance of the on-chain wallet in satoshi. nil, if on-chain payments are disabled or the backend does not report its balance.
+	OnchainBalance *uint64 `json:"onchain_balance"`
+	// Insolvent is true, if the reported balances do not cover the outstanding ecash
+	Insolvent bool `json:"insolvent"`
+}
+
+// AuditStorage creates the audit report from database without loading a mint.
+// The database is only read, so that the report can be created while the mint is running.
+func AuditStorage(database db.MintStorage, client lightning.Client, onchainClient onchain.Client, rates exchange.RateProvider) (AuditReport, error) {
+	m := &Mint{database: database, client: client, onchain: onchainClient, rates: rates, ratesMu: &sync.RWMutex{},
+		keySets: make(map[string]*crypto.KeySet), keySetsMu: &sync.RWMutex{}}
+	return m.Audit()
+}
+
+// Audit reports the outstanding ecash (issued minus redeemed) of every keyset and compares it with the backend balances.
+// Amounts of fiat keysets are converted to satoshi using the current exchange rate.
+func (m *Mint) Audit() (AuditReport, error) {
+	report := AuditReport{Time: time.Now(), KeySets: make([]KeySetLiability, 0)}
+	units, err := m.keySetUnits()
+	if err != nil {
+		return report, err
+	}
+	balances, err := m.database.GetKeySetBalances()
+	if err != nil {
+		return report, err
+	}
+	var outstanding int64
+	for _, b := range balances {
+		liability := KeySetLiability{Id: b.Id, Unit: crypto.UnitSat, Issued: b.Issued, Redeemed: b.Redeemed,
+			Outstanding: int64(b.Issued) - int64(b.Redeemed)}
+		if unit, ok := units[b.Id]; ok {
+			liability.Unit = unit
+		}
+		report.KeySets = append(report.KeySets, liability)
+		sat, err := m.ToSat(uint64(abs(liability.Outstanding)), liability.Unit)
+		if err != nil {
+			return report, err
+		}
+		if liability.Outstanding < 0 {
+			outstanding -= int64(sat)
+		} else {
+			outstanding += int64(sat)
+		}
+	}
+	if outstanding > 0 {
+		report.Outstanding = uint64(outstanding)
+	}
+	if reporter, ok := m.client.(lightning.LiquidityReporter); ok {
+		liquidity, err := reporter.Liquidity()
+		if err != nil {
+			return report, err
+		}
+		balance := liquidity.Outbound / 1000
+		report.BackendBalance = &balance
+	}
+	if reporter, ok := m.onchain.(onchain.BalanceReporter); ok {
+		balance, err := reporter.Balance(onchain.Config.Onchain.MinConfirmations)
+		if err != nil {
+			return report, err
+		}
+		report.OnchainBalance = &balance
+	}
+	// ecash can be redeemed with lightning and on-chain payments. both balances are reserves of the mint.
+	if report.BackendBalance != nil || report.OnchainBalance != nil {
+		var reserves uint64
+		for _, balance := range []*uint64{report.BackendBalance, report.OnchainBalance} {
+			if balance != nil {
+				reserves += *balance
+			}
+		}
+		report.Insolvent = reserves < report.Outstanding
+		if report.Insolvent {
+			log.WithFields(log.Fields{"outstanding": report.Outstanding, "reserves": reserves}).
+				Warn("backend balances do not cover the outstanding ecash")
+		}
+	}
+	return report, nil
+}
+
+// Liquidity returns the liquidity of the lightning backend in milli satoshi
+func (m *Mint) Liquidity() (lightning.Liquidity, error) {
+	reporter, ok := m.client.(lightning.LiquidityReporter)
+	if !ok {
+		return lightning.Liquidity{}, cashu.NewError(cashu.ErrCodeMethodNotSupported, "lightning backend does not report its balance")
+	}
+	return reporter.Liquidity()
+}
+
+// keySetUnits returns the units of all stored and loaded keysets by id
+func (m *Mint) keySetUnits() (map[string]string, error) {
+	stored, err := m.database.GetKeySet()
+	if err != nil {
+		return nil, err
+	}
+	units := make(map[string]string)
+	for _, k := range stored {
+		units[k.Id] = k.GetUnit()
+	}
+	keySets, _ := m.loadedKeySets()
+	for id, k := range keySets {
+		units[id] = k.GetUnit()
+	}
+	return units, nil
+}
+
+func abs(i int64) int64 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,122 @@
+package mint
+
+import (
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/exchange"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/cashubtc/cashu-feni/onchain"
+)
+
+// liquidityLightningClient reports outbound liquidity in milli satoshi
+type liquidityLightningClient struct {
+	*testLightningClient
+	outbound uint64
+}
+
+func (c liquidityLightningClient) Liquidity() (lightning.Liquidity, error) {
+	return lightning.Liquidity{Outbound: c.outbound}, nil
+}
+
+// balanceOnchainClient reports the balance of its wallet in satoshi
+type balanceOnchainClient struct {
+	*testOnchainClient
+	balance uint64
+}
+
+func (c balanceOnchainClient) Balance(minConfirmations int) (uint64, error) {
+	return c.balance, nil
+}
+
+func TestMint_Audit(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	tests := []struct {
+		name          string
+		client        lightning.Client
+		onchain       onchain.Client
+		wantBalance   int64
+		wantOnchain   int64
+		wantInsolvent bool
+	}{
+		{name: "solvent", client: liquidityLightningClient{newTestLightningClient(), 240000}, wantBalance: 240, wantOnchain: -1},
+		{name: "insolvent", client: liquidityLightningClient{newTestLightningClient(), 4000}, wantBalance: 4, wantOnchain: -1, wantInsolvent: true},
+		{name: "unknownBalance", client: newTestLightningClient(), wantBalance: -1, wantOnchain: -1},
+		{name: "onchainReserves", client: liquidityLightningClient{newTestLightningClient(), 4000},
+			onchain: balanceOnchainClient{newTestOnchainClient(), 200}, wantBalance: 4, wantOnchain: 200},
+		{name: "onchainInsolvent", onchain: balanceOnchainClient{newTestOnchainClient(), 200}, wantBalance: -1, wantOnchain: 200, wantInsolvent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestStorage(t)
+			m := New("master", WithStorage(storage), WithClient(tt.client), WithOnchainClient(tt.onchain),
+				WithInitialKeySet("0/0/0/0", crypto.UnitUsd), WithRateProvider(exchange.FixedRates{crypto.UnitUsd: 2}))
+			usd, err := m.ActiveKeySet(crypto.UnitUsd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 12 sat and 100 usd cents (200 sat) are issued. 8 sat are melted.
+			proofs := newTestProofs(t, m, 8, 4)
+			newTestKeySetProofs(t, m, usd, 64, 32, 4)
+			pr, hash := newTestPaymentRequest(t, 8)
+			if err = storage.StoreLightningInvoice(&invoice.Invoice{Amount: 8, Hash: hash, Pr: pr}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = m.Melt(proofs[:1], pr); err != nil {
+				t.Fatalf("Melt() error = %v", err)
+			}
+			report, err := m.Audit()
+			if err != nil {
+				t.Fatalf("Audit() error = %v", err)
+			}
+			if report.Outstanding != 204 {
+				t.Errorf("Audit() outstanding = %d, want 204", report.Outstanding)
+			}
+			if report.Insolvent != tt.wantInsolvent {
+				t.Errorf("Audit() insolvent = %v, want %v", report.Insolvent, tt.wantInsolvent)
+			}
+			if (report.BackendBalance == nil) != (tt.wantBalance < 0) ||
+				(report.BackendBalance != nil && int64(*report.BackendBalance) != tt.wantBalance) {
+				t.Errorf("Audit() backend balance = %v, want %d", report.BackendBalance, tt.wantBalance)
+			}
+			if (report.OnchainBalance == nil) != (tt.wantOnchain < 0) ||
+				(report.OnchainBalance != nil && int64(*report.OnchainBalance) != tt.wantOnchain) {
+				t.Errorf("Audit() on-chain balance = %v, want %d", report.OnchainBalance, tt.wantOnchain)
+			}
+			want := map[string]KeySetLiability{
+				m.KeySetId: {Id: m.KeySetId, Unit: crypto.UnitSat, Issued: 12, Redeemed: 8, Outstanding: 4},
+				usd.Id:     {Id: usd.Id, Unit: crypto.UnitUsd, Issued: 100, Outstanding: 100},
+			}
+			if len(report.KeySets) != len(want) {
+				t.Fatalf("Audit() keysets = %v, want %v", report.KeySets, want)
+			}
+			for _, k := range report.KeySets {
+				if k != want[k.Id] {
+					t.Errorf("Audit() keyset = %v, want %v", k, want[k.Id])
+				}
+			}
+		})
+	}
+}
+
+func TestAuditStorage(t *testing.T) {
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithInitialKeySet("0/0/0/0"))
+	newTestProofs(t, m, 8, 4)
+	stored, err := storage.GetKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := AuditStorage(storage, nil, balanceOnchainClient{newTestOnchainClient(), 8}, nil)
+	if err != nil {
+		t.Fatalf("AuditStorage() error = %v", err)
+	}
+	if report.Outstanding != 12 || !report.Insolvent {
+		t.Errorf("AuditStorage() = %+v, want 12 sat outstanding and insolvent", report)
+	}
+	// the audit must not store keysets
+	if keySets, err := storage.GetKeySet(); err != nil || len(keySets) != len(stored) {
+		t.Errorf("GetKeySet() = %v, error = %v, want %d keysets", keySets, err, len(stored))
+	}
+}
@@ -0,0 +1,152 @@
+package mint
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// KeySetStatus describes a keyset of the mint for mint operators.
+type KeySetStatus struct {
+	Id             string `json:"id"`
+	Unit           string `json:"unit"`
+	DerivationPath string `json:"derivation_path"`
+	// Active keysets sign new promises of their unit
+	Active bool `json:"active"`
+	// Loaded keysets accept proofs. Retired keysets stay loaded until they expire.
+	Loaded    bool      `json:"loaded"`
+	ValidFrom time.Time `json:"valid_from"`
+	ValidTo   time.Time `json:"valid_to"`
+}
+
+// KeySets returns the status of all stored and loaded keysets ordered by ValidFrom
+func (m *Mint) KeySets() ([]KeySetStatus, error) {
+	stored := make([]crypto.KeySet, 0)
+	if m.database != nil {
+		var err error
+		if stored, err = m.database.GetKeySet(); err != nil {
+			return nil, err
+		}
+	}
+	keySets := make(map[string]crypto.KeySet)
+	for _, k := range stored {
+		keySets[k.Id] = k
+	}
+	loadedKeySets, activeKeySets := m.loadedKeySets()
+	for id, k := range loadedKeySets {
+		if _, ok := keySets[id]; !ok {
+			keySets[id] = *k
+		}
+	}
+	status := make([]KeySetStatus, 0)
+	for id, k := range keySets {
+		_, loaded := loadedKeySets[id]
+		status = append(status, KeySetStatus{
+			Id:             id,
+			Unit:           k.GetUnit(),
+			DerivationPath: k.DerivationPath,
+			Active:         activeKeySets[k.GetUnit()] == id,
+			Loaded:         loaded,
+			ValidFrom:      k.ValidFrom,
+			ValidTo:        k.ValidTo,
+		})
+	}
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].ValidFrom.Equal(status[j].ValidFrom) {
+			return status[i].Id < status[j].Id
+		}
+		return status[i].ValidFrom.Before(status[j].ValidFrom)
+	})
+	return status, nil
+}
+
+// RotateKeySets derives the keysets of derivationPath for sat and the units and activates them.
+// The previous keysets stay loaded, so that their proofs can be redeemed. They are retired on restart and expire after the grace period.
+// Active keysets are derived from the configuration on startup, so the derivation path has to be configured to keep the keysets active.
+func (m *Mint) RotateKeySets(derivationPath string, units ...string) error {
+	m.keySetsMu.Lock()
+	defer m.keySetsMu.Unlock()
+	// the maps are replaced, because readers use them after releasing the lock
+	keySets := make(map[string]*crypto.KeySet)
+	for id, k := range m.keySets {
+		keySets[id] = k
+	}
+	activeKeySets := make(map[string]string)
+	for _, unit := range append([]string{crypto.UnitSat}, units...) {
+		if _, ok := activeKeySets[unit]; ok {
+			continue
+		}
+		k := crypto.NewKeySetWithUnit(m.masterKey, derivationPath, unit)
+		if loaded, ok := keySets[k.Id]; ok {
+			k = loaded
+		} else if err := m.storeKeySet(k); err != nil {
+			return err
+		}
+		keySets[k.Id] = k
+		activeKeySets[unit] = k.Id
+		if m.activeKeySets[unit] != k.Id {
+			log.WithFields(log.Fields{"keyset": k.Id, "unit": unit, "derivation_path": derivationPath}).Info("rotated keyset")
+		}
+	}
+	m.keySets = keySets
+	m.activeKeySets = activeKeySets
+	m.KeySetId = activeKeySets[crypto.UnitSat]
+	return nil
+}
+
+// loadedKeySets returns the loaded keysets by id and the ids of the active keysets by unit.
+// The maps must not be modified, because they are shared with concurrent requests.
+func (m *Mint) loadedKeySets() (map[string]*crypto.KeySet, map[string]string) {
+	m.keySetsMu.RLock()
+	defer m.keySetsMu.RUnlock()
+	return m.keySets, m.activeKeySets
+}
+
+// storeKeySet stores the keyset as active keyset, unless it is stored already.
+// Retired keysets can not be activated again, because their spent proofs may be archived.
+func (m *Mint) storeKeySet(k *crypto.KeySet) error {
+	if m.database == nil {
+		return nil
+	}
+	stored, err := m.database.GetKeySet(db.KeySetWithId(k.Id))
+	if err != nil {
+		return err
+	}
+	if len(stored) > 0 {
+		if !stored[0].Active {
+			return fmt.Errorf("keyset %s is retired", k.Id)
+		}
+		return nil
+	}
+	keySet := *k
+	keySet.Active = true
+	keySet.ValidFrom = time.Now()
+	return m.database.StoreKeySet(keySet)
+}
+
+// unloadKeySets removes the keysets from the loaded keysets, so that their proofs are not accepted anymore.
+// Active keysets are not unloaded.
+func (m *Mint) unloadKeySets(ids ...string) {
+	m.keySetsMu.Lock()
+	defer m.keySetsMu.Unlock()
+	unload := make(map[string]bool)
+	for _, id := range ids {
+		unload[id] = true
+	}
+	for _, id := range m.activeKeySets {
+		delete(unload, id)
+	}
+	keySets := make(map[string]*crypto.KeySet)
+	for id, k := range m.keySets {
+		if unload[id] {
+			log.WithField("keyset", id).Info("unloaded expired keyset")
+			continue
+		}
+		keySets[id] = k
+	}
+	m.keySets = keySets
+}
@@ -0,0 +1,147 @@
+package mint
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/exchange"
+)
+
+func TestMint_RotateKeySets(t *testing.T) {
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0", crypto.UnitUsd))
+	previous := m.KeySetId
+	proofs := newTestProofs(t, m, 8)
+	if err := m.RotateKeySets("0/0/0/1", crypto.UnitUsd); err != nil {
+		t.Fatalf("RotateKeySets() error = %v", err)
+	}
+	rotated := crypto.NewKeySet("master", "0/0/0/1").Id
+	if m.KeySetId != rotated {
+		t.Errorf("RotateKeySets() keyset = %s, want %s", m.KeySetId, rotated)
+	}
+	if usd, err := m.ActiveKeySet(crypto.UnitUsd); err != nil || usd.Id != crypto.NewKeySetWithUnit("master", "0/0/0/1", crypto.UnitUsd).Id {
+		t.Errorf("ActiveKeySet() = %v, error = %v, want rotated usd keyset", usd, err)
+	}
+	// proofs of the previous keyset can still be redeemed
+	if err := m.verifyProofs(proofs); err != nil {
+		t.Errorf("verifyProofs() error = %v", err)
+	}
+	keySets, err := m.KeySets()
+	if err != nil {
+		t.Fatalf("KeySets() error = %v", err)
+	}
+	want := map[string]KeySetStatus{
+		previous: {Unit: crypto.UnitSat, DerivationPath: "0/0/0/0", Loaded: true},
+		rotated:  {Unit: crypto.UnitSat, DerivationPath: "0/0/0/1", Active: true, Loaded: true},
+	}
+	if len(keySets) != 4 {
+		t.Fatalf("KeySets() = %v, want 4 keysets", keySets)
+	}
+	for _, k := range keySets {
+		w, ok := want[k.Id]
+		if !ok {
+			continue
+		}
+		if k.Unit != w.Unit || k.DerivationPath != w.DerivationPath || k.Active != w.Active || k.Loaded != w.Loaded {
+			t.Errorf("KeySets() keyset = %+v, want %+v", k, w)
+		}
+	}
+	// the restarted mint retires the previous keysets, which can not be activated again
+	m = New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/1", crypto.UnitUsd))
+	if err = m.RotateKeySets("0/0/0/0"); err == nil {
+		t.Errorf("RotateKeySets() activated retired keyset")
+	}
+	if m.KeySetId != rotated {
+		t.Errorf("RotateKeySets() keyset = %s, want %s", m.KeySetId, rotated)
+	}
+}
+
+func TestMint_RotateKeySets_concurrent(t *testing.T) {
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0", crypto.UnitUsd),
+		WithRateProvider(exchange.FixedRates{crypto.UnitUsd: 2}))
+	proofs := newTestProofs(t, m, 8)
+	// requests read the keysets and rates, while they are rotated and replaced
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := m.verifyProofs(proofs); err != nil {
+					t.Errorf("verifyProofs() error = %v", err)
+				}
+				if unit, err := m.ProofsUnit(proofs); err != nil || unit != crypto.UnitSat {
+					t.Errorf("ProofsUnit() = %s, error = %v, want sat", unit, err)
+				}
+				if _, err := m.ActiveKeySet(crypto.UnitUsd); err != nil {
+					t.Errorf("ActiveKeySet() error = %v", err)
+				}
+				if _, err := m.ToSat(4, crypto.UnitUsd); err != nil {
+					t.Errorf("ToSat() error = %v", err)
+				}
+				m.GetKeySetUnits()
+				m.GetPublicKeys()
+			}
+		}()
+	}
+	for i := 1; i <= 5; i++ {
+		if err := m.RotateKeySets(fmt.Sprintf("0/0/0/%d", i), crypto.UnitUsd); err != nil {
+			t.Errorf("RotateKeySets() error = %v", err)
+		}
+		m.SetRateProvider(exchange.FixedRates{crypto.UnitUsd: float64(i)})
+	}
+	wg.Wait()
+}
+
+func TestMint_loadRetiredKeySets(t *testing.T) {
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"))
+	previous := m.KeySetId
+	proofs := newTestProofs(t, m, 8)
+	if err := m.RotateKeySets("0/0/0/1"); err != nil {
+		t.Fatalf("RotateKeySets() error = %v", err)
+	}
+	rotated := m.KeySetId
+	rotatedProofs := newTestProofs(t, m, 2, 8)
+	// the restarted mint is configured with the previous derivation path. the rotated keyset is retired.
+	m = New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"),
+		WithKeySetGracePeriod(time.Hour))
+	if m.KeySetId != previous {
+		t.Errorf("New() keyset = %s, want %s", m.KeySetId, previous)
+	}
+	keySets, err := storage.GetKeySet(db.KeySetWithId(rotated))
+	if err != nil || len(keySets) != 1 || keySets[0].Active {
+		t.Fatalf("GetKeySet() = %v, error = %v, want retired keyset", keySets, err)
+	}
+	if validTo := keySets[0].ValidTo; validTo.Before(time.Now().Add(59*time.Minute)) || validTo.After(time.Now().Add(time.Hour)) {
+		t.Errorf("GetKeySet() valid to = %v, want end of grace period", validTo)
+	}
+	// proofs of the retired keyset can be redeemed in the grace period
+	if err = m.verifyProofs(append(proofs, rotatedProofs...)); err != nil {
+		t.Errorf("verifyProofs() error = %v", err)
+	}
+	if err = m.invalidateProofs(rotatedProofs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	// the retired keyset expires after the grace period
+	if err = storage.UpdateKeySet(rotated, db.UpdateKeySetValidTo(time.Now().Add(-time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	if archived, err := m.ArchiveSpentProofs(); err != nil || archived != 1 {
+		t.Errorf("ArchiveSpentProofs() = %d, error = %v, want 1 proof", archived, err)
+	}
+	if _, ok := m.keySets[rotated]; ok {
+		t.Errorf("ArchiveSpentProofs() did not unload expired keyset %s", rotated)
+	}
+	if err = m.verifyProofs(rotatedProofs[1:]); err == nil {
+		t.Errorf("verifyProofs() accepted proofs of expired keyset")
+	}
+	if err = m.verifyProofs(proofs); err != nil {
+		t.Errorf("verifyProofs() error = %v", err)
+	}
+}
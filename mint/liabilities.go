@@ -0,0 +1,111 @@
+package mint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/cashubtc/cashu-feni/cashu"
+)
+
+// liabilityKeyDerivationPath derives the key, which signs liability reports, from the master key
+const liabilityKeyDerivationPath = "liabilities"
+
+// liabilityReportInterval is the time, in which the same report of a keyset is returned.
+// Reports are public, so they are only created and signed once per interval and keyset.
+const liabilityReportInterval = 5 * time.Minute
+
+// liabilityReports caches the latest report of every keyset
+type liabilityReports struct {
+	mu      sync.Mutex
+	reports map[string]cashu.LiabilityReport
+}
+
+// liabilityKey returns the private key, which signs liability reports
+func (m *Mint) liabilityKey() *btcec.PrivateKey {
+	h := sha256.Sum256([]byte(m.masterKey + liabilityKeyDerivationPath))
+	key, _ := btcec.PrivKeyFromBytes(h[:])
+	return key
+}
+
+// LiabilityPublicKey returns the hex encoded x-only public key, which signs liability reports
+func (m *Mint) LiabilityPublicKey() string {
+	return hex.EncodeToString(schnorr.SerializePubKey(m.liabilityKey().PubKey()))
+}
+
+// LiabilityReport returns the signed report of all blinded signatures issued and all secrets spent in the epoch of the keyset.
+// Promises, which were stored before promises had a keyset id, are not part of any report.
+// The report is cached for liabilityReportInterval.
+func (m *Mint) LiabilityReport(keySetId string) (cashu.LiabilityReport, error) {
+	// concurrent requests wait for the report, instead of creating it again
+	m.liabilityReports.mu.Lock()
+	defer m.liabilityReports.mu.Unlock()
+	if report, ok := m.liabilityReports.reports[keySetId]; ok && time.Since(report.Time) < liabilityReportInterval {
+		return report, nil
+	}
+	report, err := m.createLiabilityReport(keySetId)
+	if err != nil {
+		return report, err
+	}
+	m.liabilityReports.reports[keySetId] = report
+	return report, nil
+}
+
+// createLiabilityReport creates and signs the report of the keyset
+func (m *Mint) createLiabilityReport(keySetId string) (cashu.LiabilityReport, error) {
+	units, err := m.keySetUnits()
+	if err != nil {
+		return cashu.LiabilityReport{}, err
+	}
+	unit, ok := units[keySetId]
+	if !ok {
+		return cashu.LiabilityReport{}, cashu.ErrKeySetUnknown
+	}
+	promises, err := m.database.GetPromises(keySetId)
+	if err != nil {
+		return cashu.LiabilityReport{}, err
+	}
+	proofs, err := m.database.GetRedeemedProofs(keySetId)
+	if err != nil {
+		return cashu.LiabilityReport{}, err
+	}
+	report := cashu.LiabilityReport{
+		KeySetId:  keySetId,
+		Unit:      unit,
+		Time:      time.Now().UTC().Truncate(time.Second),
+		Issued:    make([]cashu.IssuedSignature, 0),
+		Spent:     make([]cashu.SpentSecret, 0),
+		PublicKey: m.LiabilityPublicKey(),
+	}
+	for _, p := range promises {
+		report.Issued = append(report.Issued, cashu.IssuedSignature{B_: p.B_b, C_: p.C_c, Amount: p.Amount})
+		report.IssuedAmount += p.Amount
+	}
+	for _, p := range proofs {
+		report.Spent = append(report.Spent, cashu.SpentSecret{Secret: p.Secret, Amount: p.Amount})
+		report.RedeemedAmount += p.Amount
+	}
+	// the order of the lists must not reveal, when a signature was issued or a secret was spent
+	sort.Slice(report.Issued, func(i, j int) bool {
+		return report.Issued[i].B_ < report.Issued[j].B_
+	})
+	sort.Slice(report.Spent, func(i, j int) bool {
+		return report.Spent[i].Secret < report.Spent[j].Secret
+	})
+	report.Outstanding = int64(report.IssuedAmount) - int64(report.RedeemedAmount)
+	message, err := report.Message()
+	if err != nil {
+		return cashu.LiabilityReport{}, err
+	}
+	hash := sha256.Sum256(message)
+	signature, err := schnorr.Sign(m.liabilityKey(), hash[:])
+	if err != nil {
+		return cashu.LiabilityReport{}, err
+	}
+	report.Signature = hex.EncodeToString(signature.Serialize())
+	return report, nil
+}
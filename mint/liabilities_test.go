@@ -0,0 +1,74 @@
+package mint
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+)
+
+func TestMint_LiabilityReport(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"))
+	proofs := newTestProofs(t, m, 8, 4)
+	pr, hash := newTestPaymentRequest(t, 8)
+	if err := storage.StoreLightningInvoice(&invoice.Invoice{Amount: 8, Hash: hash, Pr: pr}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Melt(proofs[:1], pr); err != nil {
+		t.Fatalf("Melt() error = %v", err)
+	}
+	if _, err := m.LiabilityReport("unknown"); !errors.Is(err, cashu.ErrKeySetUnknown) {
+		t.Fatalf("LiabilityReport() error = %v, want %v", err, cashu.ErrKeySetUnknown)
+	}
+	report, err := m.LiabilityReport(m.KeySetId)
+	if err != nil {
+		t.Fatalf("LiabilityReport() error = %v", err)
+	}
+	if report.Unit != crypto.UnitSat || report.IssuedAmount != 12 || report.RedeemedAmount != 8 || report.Outstanding != 4 {
+		t.Errorf("LiabilityReport() = %+v, want 12 sat issued and 8 sat redeemed", report)
+	}
+	if len(report.Issued) != 2 || len(report.Spent) != 1 || report.Spent[0].Secret != proofs[0].Secret {
+		t.Errorf("LiabilityReport() issued = %v, spent = %v", report.Issued, report.Spent)
+	}
+	if report.PublicKey != m.LiabilityPublicKey() {
+		t.Errorf("LiabilityReport() pubkey = %s, want %s", report.PublicKey, m.LiabilityPublicKey())
+	}
+	// the report is cached, so that signatures issued afterwards are reported in the next interval
+	newTestProofs(t, m, 16)
+	if cached, err := m.LiabilityReport(m.KeySetId); err != nil || cached.Signature != report.Signature || cached.IssuedAmount != 12 {
+		t.Errorf("LiabilityReport() = %+v, error = %v, want cached report", cached, err)
+	}
+	tests := []struct {
+		name    string
+		modify  func(r *cashu.LiabilityReport)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(r *cashu.LiabilityReport) {}},
+		{name: "omittedSignature", modify: func(r *cashu.LiabilityReport) { r.Issued = r.Issued[1:] }, wantErr: true},
+		{name: "adjustedAmounts", modify: func(r *cashu.LiabilityReport) {
+			r.Issued = r.Issued[1:]
+			r.IssuedAmount = r.Issued[0].Amount
+			r.Outstanding = int64(r.IssuedAmount) - int64(r.RedeemedAmount)
+		}, wantErr: true},
+		{name: "duplicateSecret", modify: func(r *cashu.LiabilityReport) { r.Spent = append(r.Spent, r.Spent[0]) }, wantErr: true},
+		{name: "otherKey", modify: func(r *cashu.LiabilityReport) {
+			r.PublicKey = New("other").LiabilityPublicKey()
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := report
+			r.Issued = append([]cashu.IssuedSignature{}, report.Issued...)
+			r.Spent = append([]cashu.SpentSecret{}, report.Spent...)
+			tt.modify(&r)
+			if err := r.Verify(m.LiabilityPublicKey()); (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package mint
+
+import (
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// beginMelt stores the pending melt of the proofs, before the payment of request is sent.
+// The pending melt is kept, if the mint stops before the melt is finished.
+func (m *Mint) beginMelt(method, request string, amount uint64, unit string, proofs []cashu.Proof) (cashu.PendingMelt, error) {
+	melt := cashu.PendingMelt{
+		Id:          uuid.New().String(),
+		Method:      method,
+		Request:     request,
+		Amount:      amount,
+		Unit:        unit,
+		Secrets:     make([]string, 0),
+		TimeCreated: time.Now(),
+	}
+	for _, p := range proofs {
+		melt.Secrets = append(melt.Secrets, p.Secret)
+	}
+	m.meltsMu.Lock()
+	defer m.meltsMu.Unlock()
+	if err := m.database.StorePendingMelt(melt); err != nil {
+		return melt, err
+	}
+	m.meltsInFlight[melt.Id] = struct{}{}
+	return melt, nil
+}
+
+// finishMelt removes the pending melt, after the payment succeeded or failed
+func (m *Mint) finishMelt(melt cashu.PendingMelt) {
+	m.meltsMu.Lock()
+	defer m.meltsMu.Unlock()
+	delete(m.meltsInFlight, melt.Id)
+	if err := m.database.DeletePendingMelt(melt.Id); err != nil {
+		log.WithFields(log.Fields{"melt": melt.Id, "error.message": err.Error()}).Errorf("could not delete pending melt")
+	}
+}
+
+// interruptMelt keeps the pending melt and its pending proofs, after the payment failed with an unknown status.
+// The proofs are used like the proofs of melts, which were interrupted by a restart, until the melt is resolved.
+func (m *Mint) interruptMelt(melt cashu.PendingMelt) {
+	m.meltsMu.Lock()
+	defer m.meltsMu.Unlock()
+	delete(m.meltsInFlight, melt.Id)
+	m.setProofsUsed(melt.Secrets...)
+	// interrupted melts are not counted as pending proofs, like after a restart
+	pendingProofs.Sub(float64(len(melt.Secrets)))
+}
+
+// PendingMelts returns all pending melts. Melts, which are not in flight, were interrupted and need to be resolved.
+func (m *Mint) PendingMelts() ([]cashu.PendingMelt, error) {
+	melts, err := m.database.GetPendingMelts()
+	if err != nil {
+		return nil, err
+	}
+	m.meltsMu.Lock()
+	defer m.meltsMu.Unlock()
+	for i := range melts {
+		_, melts[i].InFlight = m.meltsInFlight[melts[i].Id]
+	}
+	return melts, nil
+}
+
+// ResolveMelt resolves the interrupted melt with id.
+// The pending proofs of the melt are spent, if the payment was paid. Otherwise, the proofs are released.
+// Mint operators have to check the status of the payment with the backend, before the melt is resolved.
+func (m *Mint) ResolveMelt(id string, paid bool) error {
+	m.meltsMu.Lock()
+	defer m.meltsMu.Unlock()
+	if _, ok := m.meltsInFlight[id]; ok {
+		return cashu.NewError(cashu.ErrCodeTokenPending, "payment of melt %s is in flight", id)
+	}
+	melts, err := m.database.GetPendingMelts(id)
+	if err != nil {
+		return err
+	}
+	if len(melts) == 0 {
+		return cashu.NewError(cashu.ErrCodeNotFound, "pending melt %s not found", id)
+	}
+	proofs, err := m.database.GetUsedProofs(melts[0].Secrets...)
+	if err != nil {
+		return err
+	}
+	pending := make([]cashu.Proof, 0)
+	for _, p := range proofs {
+		if p.Status == cashu.ProofStatusPending {
+			p.Status = cashu.ProofStatusSpent
+			pending = append(pending, p)
+		}
+	}
+	if paid {
+		if err = m.invalidateProofs(pending); err != nil {
+			return err
+		}
+		observeMelted(pending)
+	} else {
+		for _, p := range pending {
+			if err = m.database.DeleteProof(p); err != nil {
+				return err
+			}
+			// pending proofs are loaded as used proofs on startup
+			m.unsetProofsUsed(p.Secret)
+		}
+	}
+	log.WithFields(log.Fields{"melt": id, "paid": paid, "proofs": len(pending)}).Info("resolved pending melt")
+	return m.database.DeletePendingMelt(id)
+}
@@ -0,0 +1,136 @@
+package mint
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+)
+
+// failingLightningClient fails every payment with err
+type failingLightningClient struct {
+	*testLightningClient
+	err error
+}
+
+func (c failingLightningClient) Pay(paymentRequest string) (lightning.Invoicer, error) {
+	return nil, c.err
+}
+
+func TestMint_MeltWithMethod_failedPayment(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(failingLightningClient{newTestLightningClient(), lightning.PaymentNotSent(fmt.Errorf("connection refused"))}), WithInitialKeySet("0/0/0/0"))
+	proofs := newTestProofs(t, m, 8, 4)
+	// the payment was never sent, so the proofs are released and can be melted again
+	for i := 0; i < 2; i++ {
+		pr, _ := newTestPaymentRequest(t, 8)
+		if _, err := m.Melt(proofs, pr); !errors.Is(err, lightning.ErrPaymentNotSent) {
+			t.Fatalf("Melt() error = %v, want payment not sent", err)
+		}
+	}
+	if stored, err := storage.GetUsedProofs(proofs[0].Secret, proofs[1].Secret); err != nil || len(stored) != 0 {
+		t.Errorf("GetUsedProofs() = %v, error = %v, want released proofs", stored, err)
+	}
+	if melts, err := m.PendingMelts(); err != nil || len(melts) != 0 {
+		t.Errorf("PendingMelts() = %v, error = %v, want no pending melts", melts, err)
+	}
+}
+
+func TestMint_MeltWithMethod_unknownPayment(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	tests := []struct {
+		name      string
+		paid      bool
+		wantSpent bool
+	}{
+		{name: "paid", paid: true, wantSpent: true},
+		{name: "failed", paid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestStorage(t)
+			m := New("master", WithStorage(storage), WithClient(failingLightningClient{newTestLightningClient(), fmt.Errorf("timeout")}), WithInitialKeySet("0/0/0/0"))
+			proofs := newTestProofs(t, m, 8, 4)
+			pr, _ := newTestPaymentRequest(t, 8)
+			// the payment may still be in flight, so the melt stays pending
+			if _, err := m.Melt(proofs, pr); cashu.ErrorCode(err) != cashu.ErrCodeTokenPending {
+				t.Fatalf("Melt() error = %v, want pending melt", err)
+			}
+			pr, _ = newTestPaymentRequest(t, 8)
+			if _, err := m.Melt(proofs, pr); err == nil || errors.Is(err, lightning.ErrPaymentNotSent) {
+				t.Fatalf("Melt() error = %v, want pending proofs", err)
+			}
+			melts, err := m.PendingMelts()
+			if err != nil || len(melts) != 1 || melts[0].InFlight {
+				t.Fatalf("PendingMelts() = %v, error = %v, want one interrupted melt", melts, err)
+			}
+			if err = m.ResolveMelt(melts[0].Id, tt.paid); err != nil {
+				t.Fatalf("ResolveMelt() error = %v", err)
+			}
+			if spendable := m.CheckSpendables(proofs).Spendable; spendable[0] == tt.wantSpent || spendable[1] == tt.wantSpent {
+				t.Errorf("CheckSpendables() = %v, want spent %v", spendable, tt.wantSpent)
+			}
+		})
+	}
+}
+
+func TestMint_ResolveMelt(t *testing.T) {
+	tests := []struct {
+		name      string
+		paid      bool
+		wantSpent bool
+	}{
+		{name: "paid", paid: true, wantSpent: true},
+		{name: "failed", paid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestStorage(t)
+			m := New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"))
+			proofs := newTestProofs(t, m, 8, 4)
+			pr, _ := newTestPaymentRequest(t, 12)
+			// the melt is interrupted while its payment is in flight
+			if _, err := m.setProofsPending(proofs); err != nil {
+				t.Fatal(err)
+			}
+			melt, err := m.beginMelt(cashu.MethodBolt11, pr, 12, crypto.UnitSat, proofs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = m.ResolveMelt(melt.Id, tt.paid); !errors.Is(err, cashu.ErrTokenPending) {
+				t.Fatalf("ResolveMelt() error = %v, want melt in flight", err)
+			}
+			// the restarted mint resolves the melt
+			m = New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"))
+			melts, err := m.PendingMelts()
+			if err != nil || len(melts) != 1 || melts[0].Id != melt.Id || melts[0].Amount != 12 {
+				t.Fatalf("PendingMelts() = %v, error = %v, want melt %s", melts, err, melt.Id)
+			}
+			if err = m.ResolveMelt(melt.Id, tt.paid); err != nil {
+				t.Fatalf("ResolveMelt() error = %v", err)
+			}
+			if err = m.ResolveMelt(melt.Id, tt.paid); cashu.ErrorCode(err) != cashu.ErrCodeNotFound {
+				t.Errorf("ResolveMelt() error = %v, want not found", err)
+			}
+			if spendable := m.CheckSpendables(proofs).Spendable; spendable[0] == tt.wantSpent || spendable[1] == tt.wantSpent {
+				t.Errorf("CheckSpendables() = %v, want spent %v", spendable, tt.wantSpent)
+			}
+			stored, err := storage.GetUsedProofs(proofs[0].Secret, proofs[1].Secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(stored) == 2) != tt.wantSpent {
+				t.Errorf("GetUsedProofs() = %v, want spent %v", stored, tt.wantSpent)
+			}
+			for _, p := range stored {
+				if p.Status != cashu.ProofStatusSpent {
+					t.Errorf("GetUsedProofs() status = %v, want spent", p.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package mint
+
+import (
+	"math"
+	"strings"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/lightning"
+)
+
+// meltQuote is the amount and fee reserve of a melt request. pay will pay the request.
+type meltQuote struct {
+	amount uint64 // amount in satoshi
+	fee    uint64 // fee reserve in milli satoshi
+	pay    func() (lightning.Payment, error)
+	// internal quotes are settled by the mint itself. Their payment errors are never ambiguous.
+	internal bool
+}
+
+// meltMethod returns the melt quote for request.
+// amount (in satoshi) is only used by payment methods, whose requests do not carry an amount.
+type meltMethod func(m *Mint, request string, amount uint64) (*meltQuote, error)
+
+// meltMethods are all payment methods supported by melt.
+var meltMethods = map[string]meltMethod{
+	cashu.MethodBolt11:  (*Mint).bolt11MeltQuote,
+	cashu.MethodBolt12:  (*Mint).bolt12MeltQuote,
+	cashu.MethodBitcoin: (*Mint).bitcoinMeltQuote,
+}
+
+// meltQuote returns the melt quote for request using the payment method.
+func (m *Mint) meltQuote(method, request string, amount uint64) (*meltQuote, error) {
+	if method == "" {
+		method = cashu.MethodBolt11
+	}
+	quote, ok := meltMethods[method]
+	if !ok {
+		return nil, cashu.NewError(cashu.ErrCodeMethodNotSupported, "unsupported melt method: %s", method)
+	}
+	return quote(m, request, amount)
+}
+
+// bolt11MeltQuote will decode the BOLT11 payment request and use its amount.
+// Invoices of this mint are settled internally without any fees.
+func (m *Mint) bolt11MeltQuote(request string, _ uint64) (*meltQuote, error) {
+	bolt, err := lightning.DecodePaymentRequest(request)
+	if err != nil {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "invalid payment request: %v", err)
+	}
+	amount := uint64(math.Ceil(float64(bolt.MSatoshi / 1000)))
+	internalInvoice, internal := m.getInternalInvoice(bolt.PaymentHash)
+	quote := &meltQuote{amount: amount, fee: lightning.FeeReserve(amount*1000, internal), internal: internal}
+	if internal {
+		quote.pay = func() (lightning.Payment, error) {
+			return m.settleInternalInvoice(internalInvoice)
+		}
+	} else {
+		quote.pay = func() (lightning.Payment, error) {
+			return m.payLightningInvoice(request, quote.fee)
+		}
+	}
+	return quote, nil
+}
+
+// bolt12MeltQuote will pay the BOLT12 offer with amount using a lightning backend, that supports offers.
+func (m *Mint) bolt12MeltQuote(request string, amount uint64) (*meltQuote, error) {
+	if !strings.HasPrefix(strings.ToLower(request), "lno1") {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "invalid BOLT12 offer")
+	}
+	if amount == 0 {
+		return nil, cashu.NewError(cashu.ErrCodeInvalidAmount, "amount is required to pay a BOLT12 offer")
+	}
+	payer, ok := m.client.(lightning.OfferPayer)
+	if !ok {
+		return nil, cashu.NewError(cashu.ErrCodeMethodNotSupported, "lightning backend does not support BOLT12 offers")
+	}
+	quote := &meltQuote{amount: amount, fee: lightning.FeeReserve(amount*1000, false)}
+	// the routing fees are limited to the fee reserve, that was charged for the melt
+	quote.pay = func() (lightning.Payment, error) {
+		defer observeLightning("pay_offer")()
+		return payer.PayOffer(request, amount*1000, quote.fee)
+	}
+	return quote, nil
+}
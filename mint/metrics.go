@@ -0,0 +1,105 @@
+package mint
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+const metricsNamespace = "feni"
+
+var (
+	// mintedAmount is the amount of ecash issued by mint requests and lightning address claims in the unit of the keyset
+	mintedAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "minted_amount_total",
+		Help:      "Amount of minted ecash in the unit of the keyset.",
+	}, []string{"keyset"})
+	// meltedAmount is the amount of ecash redeemed by paid melt requests in the unit of the keyset
+	meltedAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "melted_amount_total",
+		Help:      "Amount of melted ecash in the unit of the keyset.",
+	}, []string{"keyset"})
+	// verificationFailures counts proofs, which could not be verified, by error code
+	verificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "proof_verification_failures_total",
+		Help:      "Number of failed proof verifications by error code.",
+	}, []string{"code"})
+	// lightningDuration is the latency of lightning backend requests by operation
+	lightningDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "lightning_request_duration_seconds",
+		Help:      "Latency of lightning backend requests.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+	// pendingProofs is the number of proofs, which are pending in a melt or split
+	pendingProofs = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "pending_proofs",
+		Help:      "Number of proofs pending in a melt or split.",
+	})
+)
+
+// observeLightning returns a function, which will observe the latency of the lightning operation when called
+func observeLightning(operation string) func() {
+	start := time.Now()
+	return func() {
+		lightningDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+// observeMinted adds the amounts of the promises to the minted amount of their keysets
+func observeMinted(promises []cashu.BlindedSignature) {
+	for _, p := range promises {
+		mintedAmount.WithLabelValues(p.Id).Add(float64(p.Amount))
+	}
+}
+
+// observeMelted adds the amounts of the proofs to the melted amount of their keysets
+func observeMelted(proofs []cashu.Proof) {
+	for _, p := range proofs {
+		meltedAmount.WithLabelValues(p.Id).Add(float64(p.Amount))
+	}
+}
+
+// observeVerificationFailure counts the failed verification by the code of err
+func observeVerificationFailure(err error) {
+	verificationFailures.WithLabelValues(strconv.Itoa(cashu.ErrorCode(err))).Inc()
+}
+
+// outstandingEcashCollector reads the outstanding ecash (issued minus redeemed) of every keyset on every scrape
+type outstandingEcashCollector struct {
+	mint *Mint
+	desc *prometheus.Desc
+}
+
+// OutstandingEcashCollector returns a collector of the outstanding ecash of every keyset in the unit of the keyset.
+func (m *Mint) OutstandingEcashCollector() prometheus.Collector {
+	return outstandingEcashCollector{mint: m, desc: prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "outstanding_ecash"),
+		"Amount of issued ecash, which was not redeemed yet, in the unit of the keyset.",
+		[]string{"keyset"}, nil,
+	)}
+}
+
+func (c outstandingEcashCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c outstandingEcashCollector) Collect(ch chan<- prometheus.Metric) {
+	balances, err := c.mint.database.GetKeySetBalances()
+	if err != nil {
+		log.WithFields(log.Fields{"error.message": err.Error()}).Errorf("could not read outstanding ecash")
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for _, b := range balances {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(b.Issued)-float64(b.Redeemed), b.Id)
+	}
+}
@@ -0,0 +1,65 @@
+package mint
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMint_metrics(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	storage := newTestStorage(t)
+	client := newTestLightningClient()
+	m := New("master", WithStorage(storage), WithClient(client), WithInitialKeySet("0/0/0/0"))
+	id := m.KeySetId
+	tokenSpent := strconv.Itoa(cashu.ErrCodeTokenSpent)
+	minted := testutil.ToFloat64(mintedAmount.WithLabelValues(id))
+	melted := testutil.ToFloat64(meltedAmount.WithLabelValues(id))
+	spent := testutil.ToFloat64(verificationFailures.WithLabelValues(tokenSpent))
+	pending := testutil.ToFloat64(pendingProofs)
+
+	// mint 16 by a paid invoice
+	i, err := m.RequestMint(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.invoices[i.GetHash()].Paid = true
+	if _, err = m.Mint(newTestOutputs(t, 16), i.GetHash(), m.keySets[id]); err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+	// melt 8 to an internal invoice
+	pr, hash := newTestPaymentRequest(t, 8)
+	if err = storage.StoreLightningInvoice(&invoice.Invoice{Amount: 8, Hash: hash, Pr: pr}); err != nil {
+		t.Fatal(err)
+	}
+	proofs := newTestProofs(t, m, 8, 4)
+	if _, err = m.Melt(proofs[:1], pr); err != nil {
+		t.Fatalf("Melt() error = %v", err)
+	}
+	if _, err = m.Melt(proofs[:1], pr); err == nil {
+		t.Fatalf("Melt() spent proofs twice")
+	}
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "minted", got: testutil.ToFloat64(mintedAmount.WithLabelValues(id)) - minted, want: 16},
+		{name: "melted", got: testutil.ToFloat64(meltedAmount.WithLabelValues(id)) - melted, want: 8},
+		{name: "tokenSpent", got: testutil.ToFloat64(verificationFailures.WithLabelValues(tokenSpent)) - spent, want: 1},
+		{name: "pending", got: testutil.ToFloat64(pendingProofs) - pending, want: 0},
+		// 16 minted and 12 issued as test proofs, 8 melted
+		{name: "outstanding", got: testutil.ToFloat64(m.OutstandingEcashCollector()), want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
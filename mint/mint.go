@@ -4,36 +4,67 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
 	"math/bits"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/cashubtc/cashu-feni/bitcoin"
 	"github.com/cashubtc/cashu-feni/cashu"
 	"github.com/cashubtc/cashu-feni/crypto"
 	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/exchange"
 	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/cln"
+	"github.com/cashubtc/cashu-feni/lightning/composite"
 	"github.com/cashubtc/cashu-feni/lightning/lnbits"
+	"github.com/cashubtc/cashu-feni/onchain"
+	"github.com/cashubtc/cashu-feni/onchain/bitcoind"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
-	decodepay "github.com/nbd-wtf/ln-decodepay"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // Mint implements all functions for a cashu ledger.
 type Mint struct {
-	// proofsUsed list of all proofs ever used
-	proofsUsed []string
+	// proofsUsed secrets of all used proofs, except the archived proofs of expired keysets
+	proofsUsed map[string]struct{}
+	// proofsUsedMu guards proofsUsed, which is read and written by concurrent requests
+	proofsUsedMu *sync.RWMutex
 	// masterKey used to derive mints private key
 	masterKey    string
 	MasterSha526 string
 	keySets      map[string]*crypto.KeySet
 	KeySetId     string
-	database     db.MintStorage
-	client       lightning.Client
+	// activeKeySets maps units to the id of their active keyset
+	activeKeySets map[string]string
+	// rates prices mints and melts of units other than satoshi
+	rates    exchange.RateProvider
+	ratesMu  *sync.RWMutex
+	database db.MintStorage
+	client   lightning.Client
+	// onchain is the on-chain backend for the bitcoin payment method
+	onchain onchain.Client
+	// onchainMu serializes the derivation of new receive addresses
+	onchainMu *sync.Mutex
+	// keySetsMu guards keySets, activeKeySets and KeySetId. Rotations replace the keyset maps instead of modifying them,
+	// so that readers can use the maps after releasing the lock.
+	keySetsMu *sync.RWMutex
+	// keySetGracePeriod is the time, in which proofs of retired keysets can still be redeemed
+	keySetGracePeriod time.Duration
+	// meltsInFlight are the ids of pending melts, whose payment is in flight
+	meltsInFlight map[string]struct{}
+	meltsMu       *sync.Mutex
+	// operations are the splits and melts in progress
+	operations *operations
+	// liabilityReports are the cached liability reports of the keysets
+	liabilityReports *liabilityReports
 }
 
 // New creates a new ledger and derives keys
@@ -42,68 +73,127 @@ func New(masterKey string, opt ...Options) *Mint {
 	h.Write([]byte(masterKey))
 
 	l := &Mint{
-		masterKey:    masterKey,
-		MasterSha526: fmt.Sprintf("%x", h.Sum(nil)),
-		proofsUsed:   make([]string, 0),
-		keySets:      make(map[string]*crypto.KeySet, 0),
+		masterKey:        masterKey,
+		MasterSha526:     fmt.Sprintf("%x", h.Sum(nil)),
+		proofsUsed:       make(map[string]struct{}),
+		proofsUsedMu:     &sync.RWMutex{},
+		keySets:          make(map[string]*crypto.KeySet, 0),
+		activeKeySets:    make(map[string]string),
+		onchainMu:        &sync.Mutex{},
+		keySetsMu:        &sync.RWMutex{},
+		ratesMu:          &sync.RWMutex{},
+		meltsInFlight:    make(map[string]struct{}),
+		meltsMu:          &sync.Mutex{},
+		operations:       &operations{},
+		liabilityReports: &liabilityReports{reports: make(map[string]cashu.LiabilityReport)},
 	}
 	// apply ledger options
 	for _, o := range opt {
 		o(l)
 	}
 	if l.database != nil {
-		p, err := l.database.GetUsedProofs()
+		if err := l.persistKeySets(); err != nil {
+			log.Warnf("could not persist keysets: %v", err)
+		}
+		if err := l.loadRetiredKeySets(); err != nil {
+			log.Warnf("could not load retired keysets: %v", err)
+		}
+		// spent proofs of expired keysets are archived before startup, so that they do not need to be loaded
+		expired, err := l.expiredKeySetIds()
+		if err == nil {
+			_, err = l.database.ArchiveProofs(expired...)
+		}
+		if err != nil {
+			log.Warnf("could not archive spent proofs: %v", err)
+			expired = nil
+		}
+		secrets, err := l.database.GetProofSecrets(expired...)
 		if err != nil {
 			log.Warnf("could not load used proofs")
 			return l
 		}
-		lo.ForEach[cashu.Proof](p, func(proof cashu.Proof, i int) {
-			l.proofsUsed = append(l.proofsUsed, proof.Secret)
-		})
+		l.setProofsUsed(secrets...)
 	}
 
 	return l
 }
-func (m Mint) setProofsPending(proofs []cashu.Proof) error {
+
+// isProofUsed returns true, if secret is the secret of a used proof
+func (m *Mint) isProofUsed(secret string) bool {
+	m.proofsUsedMu.RLock()
+	defer m.proofsUsedMu.RUnlock()
+	_, used := m.proofsUsed[secret]
+	return used
+}
+
+// setProofsUsed adds the secrets to the used proofs
+func (m *Mint) setProofsUsed(secrets ...string) {
+	m.proofsUsedMu.Lock()
+	defer m.proofsUsedMu.Unlock()
+	for _, secret := range secrets {
+		m.proofsUsed[secret] = struct{}{}
+	}
+}
+
+// unsetProofsUsed removes the secrets from the used proofs
+func (m *Mint) unsetProofsUsed(secrets ...string) {
+	m.proofsUsedMu.Lock()
+	defer m.proofsUsedMu.Unlock()
+	for _, secret := range secrets {
+		delete(m.proofsUsed, secret)
+	}
+}
+
+// setProofsPending marks the proofs as pending and returns the marked proofs.
+// Spent proofs are not marked, so that they stay spent.
+func (m *Mint) setProofsPending(proofs []cashu.Proof) ([]cashu.Proof, error) {
+	pending := make([]cashu.Proof, 0)
 	for _, proof := range proofs {
 		p, err := m.database.GetUsedProofs(proof.Secret)
-		if err != nil {
-			return err
-		}
-		if len(p) == 1 {
-			if p[0].Status == cashu.ProofStatusPending {
-				return fmt.Errorf("proofs already pending.")
+		if err == nil && len(p) == 1 {
+			switch p[0].Status {
+			case cashu.ProofStatusPending:
+				err = cashu.ErrTokenPending
+			case cashu.ProofStatusSpent:
+				continue
 			}
 		}
-		proof.Status = cashu.ProofStatusPending
-		err = m.database.StoreProof(proof)
+		if err == nil {
+			proof.Status = cashu.ProofStatusPending
+			err = m.database.StoreProof(proof)
+		}
 		if err != nil {
-			return err
+			m.unsetProofsPending(pending)
+			return nil, err
 		}
+		pendingProofs.Inc()
+		pending = append(pending, proof)
 	}
-	return nil
+	return pending, nil
 }
-func (m Mint) unsetProofsPending(proofs []cashu.Proof, transactionError *error) error {
+
+// unsetProofsPending releases the pending proofs after a transaction.
+// Proofs, which were invalidated by the transaction, stay spent. All other proofs can be used again.
+func (m *Mint) unsetProofsPending(proofs []cashu.Proof) error {
 	for _, proof := range proofs {
-		if transactionError != nil {
-			err := m.database.DeleteProof(proof)
-			if err != nil {
-				return err
-			}
+		pendingProofs.Dec()
+		if m.isProofUsed(proof.Secret) {
+			continue
 		}
-		proof.Status = cashu.ProofStatusSpent
-		err := m.database.StoreProof(proof)
+		err := m.database.DeleteProof(proof)
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
-func (m Mint) LoadKeySet(id string) (*crypto.KeySet, error) {
-	if m.keySets[id] == nil {
-		return nil, fmt.Errorf("keyset does not exist")
+
+func (m *Mint) LoadKeySet(id string) (*crypto.KeySet, error) {
+	keySets, _ := m.loadedKeySets()
+	if keySets[id] == nil {
+		return nil, cashu.ErrKeySetUnknown
 	}
-	return m.keySets[id], nil
+	return keySets[id], nil
 }
 
 var couldNotCreateClient = fmt.Errorf("could not create lightning client. Please check your configuration")
@@ -114,19 +204,73 @@ func NewLightningClient() (lightning.Client, error) {
 	if !cfg.Enabled {
 		return nil, nil
 	}
-	if cfg.Lnbits != nil {
-		return lnbits.NewClient(cfg.Lnbits.AdminKey, cfg.Lnbits.Url), nil
+	backends := make([]lightning.Client, 0)
+	for _, backend := range append([]*lightning.BackendConfig{{Lnbits: cfg.Lnbits, Cln: cfg.Cln}}, cfg.Backends...) {
+		if backend == nil {
+			continue
+		}
+		if backend.Lnbits != nil {
+			backends = append(backends, newLnbitsClient(backend.Lnbits))
+		}
+		if backend.Cln != nil {
+			backends = append(backends, cln.NewClient(backend.Cln.Rune, backend.Cln.Url))
+		}
+	}
+	switch len(backends) {
+	case 0:
+		return nil, couldNotCreateClient
+	case 1:
+		return backends[0], nil
+	}
+	return composite.NewClient(composite.Strategy(cfg.Routing), backends...), nil
+}
+
+// NewOnchainClient will create a new on-chain client implementation based on the on-chain config
+func NewOnchainClient() (onchain.Client, error) {
+	cfg := onchain.Config.Onchain
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Bitcoind == nil || cfg.Bitcoind.Descriptor == "" {
+		return nil, fmt.Errorf("could not create on-chain client. Please check your configuration")
+	}
+	return bitcoind.NewClient(cfg.Bitcoind.Url, cfg.Bitcoind.User, cfg.Bitcoind.Password,
+		cfg.Bitcoind.Wallet, cfg.Bitcoind.Descriptor, cfg.ConfTarget), nil
+}
+
+func newLnbitsClient(cfg *lightning.LnbitsConfig) lightning.Client {
+	options := make([]lnbits.ClientOptions, 0)
+	if cfg.WebhookUrl != "" {
+		options = append(options, lnbits.WithWebhook(cfg.WebhookUrl))
 	}
-	return nil, couldNotCreateClient
+	return lnbits.NewClient(cfg.AdminKey, cfg.Url, options...)
 }
 
 type Options func(l *Mint)
 
-func WithInitialKeySet(derivationPath string) Options {
+// WithInitialKeySet derives the active sat keyset and an active keyset for every additional unit.
+func WithInitialKeySet(derivationPath string, units ...string) Options {
 	return func(l *Mint) {
 		k := crypto.NewKeySet(l.masterKey, derivationPath)
 		l.keySets[k.Id] = k
 		l.KeySetId = k.Id
+		l.activeKeySets[crypto.UnitSat] = k.Id
+		for _, unit := range units {
+			if unit == crypto.UnitSat {
+				continue
+			}
+			k := crypto.NewKeySetWithUnit(l.masterKey, derivationPath, unit)
+			l.keySets[k.Id] = k
+			l.activeKeySets[unit] = k.Id
+		}
+	}
+}
+
+// WithKeySetGracePeriod keeps retired keysets loaded for the grace period, so that their proofs can still be redeemed.
+// Without a grace period, retired keysets expire immediately.
+func WithKeySetGracePeriod(gracePeriod time.Duration) Options {
+	return func(l *Mint) {
+		l.keySetGracePeriod = gracePeriod
 	}
 }
 
@@ -136,32 +280,65 @@ func WithClient(client lightning.Client) Options {
 	}
 }
 
+// WithOnchainClient enables the bitcoin payment method using client.
+func WithOnchainClient(client onchain.Client) Options {
+	return func(l *Mint) {
+		l.onchain = client
+	}
+}
+
 func WithStorage(database db.MintStorage) Options {
 	return func(l *Mint) {
 		l.database = database
 	}
 }
-func (m Mint) GetKeySetIds() []string {
-	return lo.Keys(m.keySets)
+func (m *Mint) GetKeySetIds() []string {
+	keySets, _ := m.loadedKeySets()
+	return lo.Keys(keySets)
 }
-func (m Mint) GetKeySet() []string {
-	return lo.Keys(m.keySets)
+func (m *Mint) GetKeySet() []string {
+	keySets, _ := m.loadedKeySets()
+	return lo.Keys(keySets)
 }
 
 // requestMint will create and return the lightning invoice for a mint
 func (m *Mint) RequestMint(amount uint64) (lightning.Invoicer, error) {
+	return m.RequestMintWithUnit(amount, crypto.UnitSat)
+}
+
+// RequestMintWithUnit will create and return the lightning invoice for a mint of amount in unit.
+// The invoice amount is converted to satoshi.
+func (m *Mint) RequestMintWithUnit(amount uint64, unit string) (lightning.Invoicer, error) {
+	if unit == "" {
+		unit = crypto.UnitSat
+	}
+	if unit != crypto.UnitSat {
+		if _, err := m.ActiveKeySet(unit); err != nil {
+			return nil, err
+		}
+	}
+	satAmount, err := m.ToSat(amount, unit)
+	if err != nil {
+		return nil, err
+	}
 	// signed amount is int64 (arm intel compatibility)
-	signedAmount := int64(amount)
+	signedAmount := int64(satAmount)
 	if m.client == nil {
 		invoice := lnbits.NewInvoice()
 		invoice.SetAmount(signedAmount)
 		invoice.SetHash("invalid")
 		return invoice, nil
 	}
+	observe := observeLightning("create_invoice")
 	invoice, err := m.client.CreateInvoice(signedAmount, "requested feni mint")
+	observe()
 	if err != nil {
 		return invoice, err
 	}
+	if unit != crypto.UnitSat {
+		invoice.SetUnit(unit, amount)
+	}
+	invoice.SetTimeCreated(time.Now())
 	err = m.database.StoreLightningInvoice(invoice)
 	if err != nil {
 		return invoice, err
@@ -169,87 +346,188 @@ func (m *Mint) RequestMint(amount uint64) (lightning.Invoicer, error) {
 	return invoice, nil
 }
 func (m *Mint) CheckFees(pr string) (uint64, error) {
-	decodedInvoice, err := decodepay.Decodepay(pr)
+	return m.CheckMeltFees(cashu.MethodBolt11, pr, 0)
+}
+
+// CheckMeltFees returns the fee reserve in milli satoshi to melt request using the payment method.
+func (m *Mint) CheckMeltFees(method, request string, amount uint64) (uint64, error) {
+	quote, err := m.meltQuote(method, request, amount)
 	if err != nil {
 		return 0, err
 	}
-	amount := uint64(math.Ceil(float64(decodedInvoice.MSatoshi / 1000)))
-	// hack: check if it's internal, if it exists, it will return paid = False,
-	// if id does not exist (not internal), it returns paid = None
-	invoice, err := m.client.InvoiceStatus(decodedInvoice.PaymentHash)
+	return quote.fee, nil
+}
+
+// getInternalInvoice returns the invoice for paymentHash, if it was created by this mint.
+func (m *Mint) getInternalInvoice(paymentHash string) (lightning.Invoicer, bool) {
+	if m.database == nil {
+		return nil, false
+	}
+	invoice, err := m.database.GetLightningInvoice(paymentHash)
+	if err != nil || invoice == nil {
+		return nil, false
+	}
+	return invoice, true
+}
+
+// getLightningInvoice returns the invoice for paymentHash. Invoices, which were not created by this mint, are not found.
+func (m *Mint) getLightningInvoice(paymentHash string) (lightning.Invoicer, error) {
+	invoice, err := m.database.GetLightningInvoice(paymentHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, cashu.NewError(cashu.ErrCodeNotFound, "unknown invoice")
+	}
+	return invoice, err
+}
+
+// parseBlindedMessage returns the public key of the blinded message B_
+func parseBlindedMessage(B_ string) (*secp256k1.PublicKey, error) {
+	b, err := hex.DecodeString(B_)
+	if err != nil {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "invalid blinded message: %s", B_)
+	}
+	key, err := secp256k1.ParsePubKey(b)
+	if err != nil {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "invalid blinded message: %s", B_)
+	}
+	return key, nil
+}
+
+// internalPayment is the payment of an invoice, that was settled by the mint itself.
+type internalPayment struct {
+	preimage string
+}
+
+func (p internalPayment) IsPaid() bool {
+	return true
+}
+func (p internalPayment) GetPreimage() string {
+	return p.preimage
+}
+
+// settleInternalInvoice will mark a mint invoice as paid, without sending a lightning payment.
+// This is used, when one user melts tokens to pay the mint invoice of another user.
+func (m *Mint) settleInternalInvoice(invoice lightning.Invoicer) (lightning.Payment, error) {
+	// the invoice is marked as paid with a conditional update, so that concurrent melts can not pay it twice
+	settled, err := m.database.SetLightningInvoicePaid(invoice.GetHash(), time.Now())
 	if err != nil {
-		// invoice was not found. pay fees
-		return lightning.FeeReserve(amount*1000, false), nil
+		return nil, err
 	}
-	internal := invoice.IsPaid() == false
-	return lightning.FeeReserve(amount*1000, internal), nil
+	if !settled {
+		return nil, cashu.ErrInvoiceAlreadyPaid
+	}
+	log.WithFields(invoice.Log()).Info("settled internal lightning invoice")
+	return internalPayment{}, nil
 }
 
 // checkLightningInvoice will check the lightning invoice amount matches the outputs amount.
-func (m *Mint) checkLightningInvoice(amounts []uint64, paymentHash string) (bool, error) {
-	invoice, err := m.database.GetLightningInvoice(paymentHash)
+// The outputs must be signed with a keyset of the invoices unit.
+// Invoices of lightning address payments can only be claimed by the owner of the lightning address.
+func (m *Mint) checkLightningInvoice(amounts []uint64, paymentHash string, unit string) (bool, error) {
+	invoice, err := m.getLightningInvoice(paymentHash)
 	if err != nil {
 		return false, err
 	}
+	if _, err = m.database.GetLightningAddressPayment(paymentHash); err == nil {
+		return false, cashu.NewError(cashu.ErrCodeBadRequest, "invoice of a lightning address payment has to be claimed")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
 	if invoice.IsIssued() {
-		return false, fmt.Errorf("tokens already issued for this invoice.")
+		return false, fmt.Errorf("%w for this invoice.", cashu.ErrTokensIssued)
 	}
-	payment, err := m.client.InvoiceStatus(paymentHash)
-	if err != nil {
-		return false, err
+	if invoice.GetUnit() != unit {
+		return false, cashu.NewError(cashu.ErrCodeUnitNotSupported, "invoice unit %s does not match keyset unit %s", invoice.GetUnit(), unit)
+	}
+	// the invoice watcher may already have marked this invoice as paid
+	paid := invoice.IsPaid()
+	if !paid {
+		observe := observeLightning("invoice_status")
+		payment, err := m.client.InvoiceStatus(paymentHash)
+		observe()
+		if err != nil {
+			return false, err
+		}
+		paid = payment.IsPaid()
 	}
 	// sum all amounts
 	total := lo.SumBy[uint64](amounts, func(amount uint64) uint64 {
 		return amount
 	})
 	// validate total and invoice amount
-	if total > uint64(invoice.GetAmount()) {
-		return false, fmt.Errorf("requested amount too high: %d. Invoice amount: %d", total, invoice.GetAmount())
+	if total > invoice.GetUnitAmount() {
+		return false, cashu.NewError(cashu.ErrCodeInvalidAmount, "requested amount too high: %d. Invoice amount: %d", total, invoice.GetUnitAmount())
 	}
-	if err != nil {
-		return false, err
-	}
-	if payment.IsPaid() {
-		err = m.database.UpdateLightningInvoice(paymentHash, db.UpdateInvoicePaid(true), db.UpdateInvoiceWithIssued(true))
+	if paid {
+		if !invoice.IsPaid() {
+			if _, err = m.database.SetLightningInvoicePaid(paymentHash, time.Now()); err != nil {
+				return false, err
+			}
+		}
+		// the invoice is marked as issued with a conditional update, so that concurrent mints can not both succeed
+		issued, err := m.database.IssueLightningInvoice(paymentHash)
 		if err != nil {
-			// todo -- check if we rly want to return false here!
 			return false, err
 		}
+		if !issued {
+			return false, fmt.Errorf("%w for this invoice.", cashu.ErrTokensIssued)
+		}
 	}
-	return payment.IsPaid(), err
+	return paid, nil
 }
 
-// payLightningInvoice will pay pr using master wallet
+// payLightningInvoice will pay pr using master wallet.
+// The routing fees are limited to feeLimitMSat, if the lightning backend supports fee limits.
 func (m *Mint) payLightningInvoice(pr string, feeLimitMSat uint64) (lightning.Payment, error) {
-	invoice, err := m.client.Pay(pr)
+	observe := observeLightning("pay")
+	var invoice lightning.Invoicer
+	var err error
+	if payer, ok := m.client.(lightning.FeeLimitedPayer); ok {
+		invoice, err = payer.PayWithFeeLimit(pr, feeLimitMSat)
+	} else {
+		invoice, err = m.client.Pay(pr)
+	}
+	observe()
 	if err != nil {
 		return lnbits.LNbitsPayment{}, err
 	}
+	defer observeLightning("invoice_status")()
 	return m.client.InvoiceStatus(invoice.GetHash())
 }
 
-func (m Mint) mint(messages cashu.BlindedMessages, pr string, keySet *crypto.KeySet) ([]cashu.BlindedSignature, error) {
+func (m *Mint) mint(messages cashu.BlindedMessages, pr string, keySet *crypto.KeySet) ([]cashu.BlindedSignature, error) {
 	publicKeys := make([]*secp256k1.PublicKey, 0)
 	var amounts []uint64
 	for _, msg := range messages {
 		amounts = append(amounts, msg.Amount)
-		hkey := make([]byte, 0)
-		hkey, err := hex.DecodeString(msg.B_)
-		publicKey, err := secp256k1.ParsePubKey(hkey)
+		publicKey, err := parseBlindedMessage(msg.B_)
 		if err != nil {
 			return nil, err
 		}
 		publicKeys = append(publicKeys, publicKey)
 	}
-	// if the client is not nil, ledger is running on lightning
-	if m.client != nil {
-		paid, err := m.checkLightningInvoice(amounts, pr)
+	if quote, ok := m.getOnchainQuote(pr); ok {
+		if keySet.GetUnit() != crypto.UnitSat {
+			return nil, cashu.NewError(cashu.ErrCodeUnitNotSupported, "on-chain quotes can only be minted in %s", crypto.UnitSat)
+		}
+		paid, err := m.checkOnchainQuote(amounts, quote)
+		if err != nil {
+			return nil, err
+		}
+		if !paid {
+			return nil, cashu.NewError(cashu.ErrCodeQuoteNotPaid, "on-chain payment not confirmed yet.")
+		}
+	} else if m.client != nil {
+		// if the client is not nil, ledger is running on lightning
+		paid, err := m.checkLightningInvoice(amounts, pr, keySet.GetUnit())
 		if err != nil {
 			return nil, err
 		}
 		if !paid {
-			return nil, fmt.Errorf("Lightning invoice not paid yet.")
+			return nil, cashu.ErrQuoteNotPaid
 		}
+	} else if m.onchain != nil {
+		// tokens are only minted without payment, if the mint has no payment method at all
+		return nil, cashu.NewError(cashu.ErrCodeNotFound, "quote not found")
 	}
 	promises := make([]cashu.BlindedSignature, 0)
 	for i, key := range publicKeys {
@@ -259,16 +537,22 @@ func (m Mint) mint(messages cashu.BlindedMessages, pr string, keySet *crypto.Key
 		}
 		promises = append(promises, sig)
 	}
+	observeMinted(promises)
 	return promises, nil
 }
 
-func (m Mint) Mint(messages cashu.BlindedMessages, pr string, keySet *crypto.KeySet) ([]cashu.BlindedSignature, error) {
+func (m *Mint) Mint(messages cashu.BlindedMessages, pr string, keySet *crypto.KeySet) ([]cashu.BlindedSignature, error) {
 	// mint generates promises for keys. checks lightning invoice before creating promise.
 	return m.mint(messages, pr, keySet)
 }
-func (m Mint) MintWithoutKeySet(messages cashu.BlindedMessages, pr string) ([]cashu.BlindedSignature, error) {
+func (m *Mint) MintWithoutKeySet(messages cashu.BlindedMessages, pr string) ([]cashu.BlindedSignature, error) {
 	// mint generates promises for keys. checks lightning invoice before creating promise.
-	keyset, err := m.LoadKeySet(m.KeySetId)
+	// the promises are signed with the active keyset of the invoices unit.
+	unit := crypto.UnitSat
+	if invoice, ok := m.getInternalInvoice(pr); ok {
+		unit = invoice.GetUnit()
+	}
+	keyset, err := m.ActiveKeySet(unit)
 	if err != nil {
 		return nil, err
 	}
@@ -277,9 +561,13 @@ func (m Mint) MintWithoutKeySet(messages cashu.BlindedMessages, pr string) ([]ca
 
 // generatePromise will generate promise and signature for given amount using public key
 func (m *Mint) generatePromise(amount uint64, keySet *crypto.KeySet, B_ *secp256k1.PublicKey) (cashu.BlindedSignature, error) {
-	C_ := crypto.SecondStepBob(*B_, *m.keySets[keySet.Id].PrivateKeys.GetKeyByAmount(uint64(amount)).Key)
+	key := keySet.PrivateKeys.GetKeyByAmount(amount)
+	if key == nil {
+		return cashu.BlindedSignature{}, cashu.NewError(cashu.ErrCodeInvalidAmount, "invalid amount: %d", amount)
+	}
+	C_ := crypto.SecondStepBob(*B_, *key.Key)
 	if m.database != nil {
-		err := m.database.StorePromise(cashu.Promise{Amount: amount, B_b: hex.EncodeToString(B_.SerializeCompressed()), C_c: hex.EncodeToString(C_.SerializeCompressed())})
+		err := m.database.StorePromise(cashu.Promise{Amount: amount, Id: keySet.Id, B_b: hex.EncodeToString(B_.SerializeCompressed()), C_c: hex.EncodeToString(C_.SerializeCompressed())})
 		if err != nil {
 			return cashu.BlindedSignature{}, err
 		}
@@ -304,22 +592,32 @@ func (m *Mint) generatePromises(amounts []uint64, keySet *crypto.KeySet, keys []
 // verifyProofBdhke will verify proof
 func (m *Mint) verifyProofBdhke(proof cashu.Proof) error {
 	if !m.checkSpendable(proof) {
-		return fmt.Errorf("tokens already spent. Secret: %s", proof.Secret)
+		return fmt.Errorf("%w. Secret: %s", cashu.ErrTokenSpent, proof.Secret)
+	}
+	keySets, activeKeySets := m.loadedKeySets()
+	keySet, ok := keySets[proof.Id]
+	if !ok {
+		// proofs without known keyset id are verified with the current keyset
+		keySet = keySets[activeKeySets[crypto.UnitSat]]
 	}
-	secretKey := m.keySets[m.KeySetId].PrivateKeys.GetKeyByAmount(uint64(proof.Amount)).Key
+	key := keySet.PrivateKeys.GetKeyByAmount(proof.Amount)
+	if key == nil {
+		return cashu.NewError(cashu.ErrCodeInvalidAmount, "invalid proof amount: %d", proof.Amount)
+	}
+	secretKey := key.Key
 	pubKey, err := hex.DecodeString(proof.C)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w invalid signature: %v", cashu.ErrProofVerification, err)
 	}
 	C, err := secp256k1.ParsePubKey(pubKey)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w invalid signature: %v", cashu.ErrProofVerification, err)
 	}
 	if crypto.Verify(*secretKey, *C, proof.Secret, crypto.HashToCurve) ||
 		crypto.Verify(*secretKey, *C, proof.Secret, crypto.LegacyHashToCurve) {
 		return nil
 	}
-	return fmt.Errorf("could not verify proofs.")
+	return cashu.ErrProofVerification
 }
 
 func verifyScript(proofs []cashu.Proof) (addr *btcutil.AddressScriptHash, err error) {
@@ -328,7 +626,7 @@ func verifyScript(proofs []cashu.Proof) (addr *btcutil.AddressScriptHash, err er
 	for _, proof := range proofs {
 		if proof.Script == nil || proof.Script.Script == "" || proof.Script.Signature == "" {
 			if cashu.IsPay2ScriptHash(proof.Secret) {
-				return nil, fmt.Errorf("secret indicates a script but no script is present")
+				return nil, cashu.NewError(cashu.ErrCodeProofVerification, "secret indicates a script but no script is present")
 			} else {
 				// secret indicates no script, so treat script as valid
 				return nil, nil
@@ -337,11 +635,11 @@ func verifyScript(proofs []cashu.Proof) (addr *btcutil.AddressScriptHash, err er
 		// decode payloads
 		pubScriptKey, err := base64.URLEncoding.DecodeString(proof.Script.Script)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w invalid script: %v", cashu.ErrScriptVerification, err)
 		}
 		sig, err := base64.URLEncoding.DecodeString(proof.Script.Signature)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w invalid script signature: %v", cashu.ErrScriptVerification, err)
 		}
 		addr, err := bitcoin.VerifyScript(pubScriptKey, sig)
 		if err != nil {
@@ -349,20 +647,20 @@ func verifyScript(proofs []cashu.Proof) (addr *btcutil.AddressScriptHash, err er
 			// this should be removed in future versions
 			switch err.Error() {
 			case "pay to script hash is not push only":
-				return nil, fmt.Errorf("('%v', EvalScriptError('EvalScript: OP_RETURN called'))", fmt.Errorf("Script evaluation failed:"))
+				return nil, cashu.NewError(cashu.ErrCodeProofVerification, "('%v', EvalScriptError('EvalScript: OP_RETURN called'))", fmt.Errorf("Script evaluation failed:"))
 			case "false stack entry at end of script execution":
-				return nil, fmt.Errorf("('%v', VerifyScriptError('scriptPubKey returned false'))", fmt.Errorf("Script verification failed:"))
+				return nil, cashu.NewError(cashu.ErrCodeProofVerification, "('%v', VerifyScriptError('scriptPubKey returned false'))", fmt.Errorf("Script verification failed:"))
 			}
-			return nil, err
+			return nil, fmt.Errorf("%w %v", cashu.ErrScriptVerification, err)
 		}
 		if addr != nil {
 			ss := strings.Split(proof.Secret, ":")
 			if len(ss) != 3 {
-				return nil, fmt.Errorf("script verification failed.")
+				return nil, cashu.ErrScriptVerification
 			}
 			addrs := addr.String()
 			if ss[1] != addrs {
-				return nil, fmt.Errorf("script verification failed.")
+				return nil, cashu.ErrScriptVerification
 			}
 		}
 	}
@@ -423,10 +721,20 @@ func (m *Mint) CheckSpendables(proofs []cashu.Proof) cashu.CheckSpendableRespons
 
 // checkSpendable returns true if proof was not used before
 func (m *Mint) checkSpendable(proof cashu.Proof) bool {
-	_, found := lo.Find[string](m.proofsUsed, func(p string) bool {
-		return p == proof.Secret
-	})
-	return !found
+	if m.isProofUsed(proof.Secret) {
+		return false
+	}
+	// proofs of active keysets are never archived
+	keySets, _ := m.loadedKeySets()
+	if _, ok := keySets[proof.Id]; ok || m.database == nil {
+		return true
+	}
+	archived, err := m.database.GetArchivedProofs(proof.Secret)
+	if err != nil {
+		log.WithFields(log.Fields{"error.message": err.Error()}).Errorf("could not check archived proofs")
+		return false
+	}
+	return len(archived) == 0
 }
 
 // AmountSplit will return an array with all decimal binary values (i.e. powers
@@ -449,19 +757,24 @@ func verifySplitAmount(amount uint64) (uint64, error) {
 // verifyAmount make sure that amount is bigger than zero and smaller than 2^MaxOrder
 func verifyAmount(amount uint64) (uint64, error) {
 	if amount < 0 || amount > uint64(math.Pow(2, crypto.MaxOrder)) {
-		return 0, fmt.Errorf("invalid split amount: %d", amount)
+		return 0, cashu.NewError(cashu.ErrCodeInvalidAmount, "invalid split amount: %d", amount)
 	}
 	return amount, nil
 }
 
-func (m *Mint) verifyProofs(proofs []cashu.Proof) error {
+func (m *Mint) verifyProofs(proofs []cashu.Proof) (err error) {
+	defer func() {
+		if err != nil {
+			observeVerificationFailure(err)
+		}
+	}()
 	// _verify_secret_criteria
 	if err := verifySecretCriteria(proofs); err != nil {
 		return err
 	}
 	// check for duplicates
 	if !verifyNoDuplicateProofs(proofs) {
-		return fmt.Errorf("duplicate proofs.")
+		return cashu.ErrDuplicateProofs
 	}
 	// verify proofs
 	for _, proof := range proofs {
@@ -499,17 +812,12 @@ func verifyEquationBalanced(proofs []cashu.Proof, outs []cashu.BlindedSignature)
 
 // invalidateProofs will invalidate multiple proofs at once by persisting them into proof table
 func (m *Mint) invalidateProofs(proofs []cashu.Proof) error {
-	proofMsgs := make(map[string]struct{})
-	// get unique proofs
+	secrets := make([]string, 0)
 	for _, proof := range proofs {
-		proofMsgs[proof.Secret] = struct{}{}
+		secrets = append(secrets, proof.Secret)
 	}
 	// append to proofs used
-	for pm := range proofMsgs {
-		m.proofsUsed = append(m.proofsUsed, pm)
-	}
-	// make proofs used unique again...
-	m.proofsUsed = lo.Uniq[string](m.proofsUsed)
+	m.setProofsUsed(secrets...)
 	// invalidate all proofs
 	for _, proof := range proofs {
 		err := m.database.StoreProof(proof)
@@ -522,7 +830,8 @@ func (m *Mint) invalidateProofs(proofs []cashu.Proof) error {
 
 // GetPublicKeys will return current public keys for all amounts
 func (m *Mint) GetPublicKeys() map[uint64]string {
-	return crypto.GetKeySetPublicKeys(m.keySets[m.KeySetId])
+	keySets, activeKeySets := m.loadedKeySets()
+	return crypto.GetKeySetPublicKeys(keySets[activeKeySets[crypto.UnitSat]])
 }
 
 /*
@@ -531,11 +840,27 @@ raise Exception ("could not verify proofs.")
 */
 // melt will meld proofs
 func (m *Mint) Melt(proofs []cashu.Proof, invoice string) (payment lightning.Payment, err error) {
-	err = m.setProofsPending(proofs)
+	return m.MeltWithMethod(proofs, cashu.MethodBolt11, invoice, 0)
+}
+
+// MeltWithMethod will meld proofs and pay request using the payment method.
+// amount (in satoshi) is only required for payment requests without amount.
+func (m *Mint) MeltWithMethod(proofs []cashu.Proof, method, request string, amount uint64) (payment lightning.Payment, err error) {
+	if err = m.beginOperation(); err != nil {
+		return nil, err
+	}
+	defer m.endOperation()
+	pending, err := m.setProofsPending(proofs)
 	if err != nil {
 		return
 	}
-	defer m.unsetProofsPending(proofs, &err)
+	// interrupted melts keep their proofs pending, until the melt is resolved
+	interrupted := false
+	defer func() {
+		if !interrupted {
+			m.unsetProofsPending(pending)
+		}
+	}()
 	var total uint64
 
 	if err = m.verifyProofs(proofs); err != nil {
@@ -545,17 +870,36 @@ func (m *Mint) Melt(proofs []cashu.Proof, invoice string) (payment lightning.Pay
 	for _, proof := range proofs {
 		total += proof.Amount
 	}
-	// decode invoice and use this amount instead of melt amount
-	bolt, err := decodepay.Decodepay(invoice)
-	amount := uint64(math.Ceil(float64(bolt.MSatoshi / 1000)))
-	fee, err := m.CheckFees(invoice)
+	quote, err := m.meltQuote(method, request, amount)
 	if err != nil {
 		return nil, err
 	}
-	if !(total >= amount+(fee/1000)) {
-		return nil, fmt.Errorf("provided proofs not enough for Lightning payment")
+	// the payment is priced in satoshi. proofs of other units are converted using the exchange rate.
+	unit, err := m.ProofsUnit(proofs)
+	if err != nil {
+		return nil, err
 	}
-	payment, err = m.payLightningInvoice(invoice, fee)
+	required, err := m.FromSat(quote.amount+(quote.fee/1000), unit)
+	if err != nil {
+		return nil, err
+	}
+	if !(total >= required) {
+		return nil, cashu.NewError(cashu.ErrCodeTransactionUnbalanced, "provided proofs not enough for Lightning payment")
+	}
+	melt, err := m.beginMelt(method, request, total, unit, proofs)
+	if err != nil {
+		return nil, err
+	}
+	payment, err = quote.pay()
+	if err != nil && !quote.internal && !errors.Is(err, lightning.ErrPaymentNotSent) {
+		// the payment may have been sent (e.g. after a timeout). releasing the proofs could pay the request twice.
+		interrupted = true
+		m.interruptMelt(melt)
+		log.WithFields(log.Fields{"melt": melt.Id, "error.message": err.Error()}).
+			Warn("payment status of melt is unknown. the melt has to be resolved")
+		return nil, cashu.NewError(cashu.ErrCodeTokenPending, "payment of melt %s is pending: %v", melt.Id, err)
+	}
+	defer m.finishMelt(melt)
 	if err != nil {
 		return nil, err
 	}
@@ -564,22 +908,27 @@ func (m *Mint) Melt(proofs []cashu.Proof, invoice string) (payment lightning.Pay
 		if err != nil {
 			return nil, err
 		}
+		observeMelted(proofs)
 	}
 	return payment, nil
 }
 
 // split will split proofs. creates BlindedSignatures from BlindedMessages.
 func (m *Mint) Split(proofs []cashu.Proof, amount uint64, outputs []cashu.BlindedMessage, keySet *crypto.KeySet) ([]cashu.BlindedSignature, []cashu.BlindedSignature, error) {
-	err := m.setProofsPending(proofs)
+	if err := m.beginOperation(); err != nil {
+		return nil, nil, err
+	}
+	defer m.endOperation()
+	pending, err := m.setProofsPending(proofs)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer m.unsetProofsPending(proofs, &err)
+	defer m.unsetProofsPending(pending)
 	total := lo.SumBy[cashu.Proof](proofs, func(p cashu.Proof) uint64 {
 		return p.Amount
 	})
 	if amount > total {
-		return nil, nil, fmt.Errorf("split amount is higher than the total sum.")
+		return nil, nil, cashu.ErrTransactionUnbalanced
 	}
 	// verifySplitAmount
 	amount, err = verifySplitAmount(amount)
@@ -591,12 +940,20 @@ func (m *Mint) Split(proofs []cashu.Proof, amount uint64, outputs []cashu.Blinde
 	if _, err = verifyScript(proofs); err != nil {
 		return nil, nil, err
 	}
+	// proofs can only be split into outputs of the same unit
+	unit, err := m.ProofsUnit(proofs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if unit != keySet.GetUnit() {
+		return nil, nil, cashu.NewError(cashu.ErrCodeUnitNotSupported, "proofs unit %s does not match keyset unit %s", unit, keySet.GetUnit())
+	}
 
 	if err = m.verifyProofs(proofs); err != nil {
 		return nil, nil, err
 	}
 	if !verifyNoDuplicateOutputs(outputs) {
-		return nil, nil, fmt.Errorf("duplicate outputs.")
+		return nil, nil, cashu.ErrDuplicateOutputs
 	}
 	// check outputs
 	_, err = verifyOutputs(total, amount, outputs)
@@ -614,11 +971,7 @@ func (m *Mint) Split(proofs []cashu.Proof, amount uint64, outputs []cashu.Blinde
 	B_fst := make([]*secp256k1.PublicKey, 0)
 	B_snd := make([]*secp256k1.PublicKey, 0)
 	for _, data := range outputs[:len(outsFts)] {
-		b, err := hex.DecodeString(data.B_)
-		if err != nil {
-			return nil, nil, err
-		}
-		key, err := secp256k1.ParsePubKey(b)
+		key, err := parseBlindedMessage(data.B_)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -626,11 +979,7 @@ func (m *Mint) Split(proofs []cashu.Proof, amount uint64, outputs []cashu.Blinde
 	}
 
 	for _, data := range outputs[len(outsFts):] {
-		b, err := hex.DecodeString(data.B_)
-		if err != nil {
-			return nil, nil, err
-		}
-		key, err := secp256k1.ParsePubKey(b)
+		key, err := parseBlindedMessage(data.B_)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -658,10 +1007,10 @@ func (m *Mint) Split(proofs []cashu.Proof, amount uint64, outputs []cashu.Blinde
 func verifySecretCriteria(proofs []cashu.Proof) error {
 	for _, proof := range proofs {
 		if proof.Secret == "" {
-			return fmt.Errorf("no secret in proof.")
+			return cashu.NewError(cashu.ErrCodeInvalidSecret, "no secret in proof.")
 		}
 		if len(proof.Secret) > 64 {
-			return fmt.Errorf("secret too long.")
+			return cashu.NewError(cashu.ErrCodeInvalidSecret, "secret too long.")
 		}
 	}
 	return nil
@@ -2,18 +2,36 @@ package mint
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/cashubtc/cashu-feni/cashu"
 	"math"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/cashubtc/cashu-feni/crypto"
 	"github.com/cashubtc/cashu-feni/db"
+	"github.com/cashubtc/cashu-feni/exchange"
 	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/lightning/cln"
 	"github.com/cashubtc/cashu-feni/lightning/invoice"
+	"github.com/cashubtc/cashu-feni/lightning/lnbits"
+	"github.com/cashubtc/cashu-feni/onchain"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/google/uuid"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
 )
 
 func Test_amountSplit(t *testing.T) {
@@ -162,7 +180,7 @@ func TestMint_RequestMint(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			os.Setenv("LIGHTNING", "false")
-			m := New("master", WithStorage(db.NewSqlDatabase()))
+			m := New("master", WithStorage(db.NewMemoryDatabase()))
 			got, err := m.RequestMint(tt.args.amount)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RequestMint() error = %v, wantErr %v", err, tt.wantErr)
@@ -204,3 +222,692 @@ func Test_verifyAmount(t *testing.T) {
 		})
 	}
 }
+
+// testLightningClient is a lightning backend that settles invoices in memory.
+type testLightningClient struct {
+	invoices map[string]*invoice.Invoice
+}
+
+func newTestLightningClient() *testLightningClient {
+	return &testLightningClient{invoices: make(map[string]*invoice.Invoice)}
+}
+
+func (c *testLightningClient) InvoiceStatus(paymentHash string) (lightning.Payment, error) {
+	i, ok := c.invoices[paymentHash]
+	if !ok {
+		return nil, fmt.Errorf("invoice not found")
+	}
+	return lnbits.LNbitsPayment{Paid: i.Paid, Preimage: i.Preimage}, nil
+}
+
+func (c *testLightningClient) Pay(paymentRequest string) (lightning.Invoicer, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (c *testLightningClient) CreateInvoice(amount int64, memo string) (lightning.Invoicer, error) {
+	i := &invoice.Invoice{Amount: amount, Hash: uuid.New().String(), Pr: memo}
+	c.invoices[i.Hash] = &invoice.Invoice{Amount: amount, Hash: i.Hash}
+	return i, nil
+}
+
+func (c *testLightningClient) CreateInvoiceWithDescription(amount int64, description string) (lightning.Invoicer, error) {
+	return c.CreateInvoice(amount, description)
+}
+
+// newTestStorage creates a memory storage
+func newTestStorage(t *testing.T) db.MintStorage {
+	lightning.Config.Lightning.Enabled = true
+	return db.NewMemoryDatabase()
+}
+
+func TestMint_checkPendingInvoices(t *testing.T) {
+	client := newTestLightningClient()
+	m := New("master", WithStorage(newTestStorage(t)), WithClient(client), WithInitialKeySet("0/0/0/0"))
+	paid, err := m.RequestMint(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpaid, err := m.RequestMint(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// invoices older than the maximum age are not checked anymore
+	old := &invoice.Invoice{Amount: 30, Hash: "old", Create: time.Now().Add(-2 * invoiceWatcherMaxAge)}
+	if err = m.database.StoreLightningInvoice(old); err != nil {
+		t.Fatal(err)
+	}
+	client.invoices[old.Hash] = &invoice.Invoice{Amount: 30, Hash: old.Hash, Paid: true}
+	client.invoices[paid.GetHash()].Paid = true
+	if err = m.checkPendingInvoices(); err != nil {
+		t.Fatalf("checkPendingInvoices() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "paid", hash: paid.GetHash(), want: true},
+		{name: "unpaid", hash: unpaid.GetHash(), want: false},
+		{name: "old", hash: old.Hash, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := m.database.GetLightningInvoice(tt.hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i.IsPaid() != tt.want {
+				t.Errorf("IsPaid() = %v, want %v", i.IsPaid(), tt.want)
+			}
+			if i.IsIssued() {
+				t.Errorf("IsIssued() = true, want false")
+			}
+		})
+	}
+	// invoice status is answered from storage once the invoice was paid
+	delete(client.invoices, paid.GetHash())
+	i, err := m.InvoiceStatus(paid.GetHash())
+	if err != nil {
+		t.Fatalf("InvoiceStatus() error = %v", err)
+	}
+	if !i.IsPaid() {
+		t.Errorf("InvoiceStatus() paid = false, want true")
+	}
+	// the watcher read the invoice, before it was paid and issued by a mint request
+	stale, err := m.database.GetLightningInvoice(unpaid.GetHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.invoices[unpaid.GetHash()].Paid = true
+	err = m.database.UpdateLightningInvoice(unpaid.GetHash(), db.UpdateInvoicePaid(true), db.UpdateInvoiceWithIssued(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid, err := m.updateInvoicePaid(stale); err != nil || !paid {
+		t.Fatalf("updateInvoicePaid() = %v, error = %v, want paid", paid, err)
+	}
+	if i, err = m.database.GetLightningInvoice(unpaid.GetHash()); err != nil || !i.IsIssued() {
+		t.Errorf("GetLightningInvoice() = %v, error = %v, want issued invoice", i, err)
+	}
+}
+
+func TestMint_SettleInvoice(t *testing.T) {
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"))
+	err := storage.StoreLightningInvoice(&invoice.Invoice{Amount: 10, Hash: "webhook", WebhookToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "emptyToken", token: "", wantErr: true},
+		{name: "invalidToken", token: "invalid", wantErr: true},
+		{name: "validToken", token: "token", wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.SettleInvoice("webhook", tt.token); (err != nil) != tt.wantErr {
+				t.Fatalf("SettleInvoice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			i, err := storage.GetLightningInvoice("webhook")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i.IsPaid() == tt.wantErr {
+				t.Errorf("SettleInvoice() paid = %v, want %v", i.IsPaid(), !tt.wantErr)
+			}
+		})
+	}
+	// a repeated webhook does not reset an invoice, which was issued in the meantime
+	if err = storage.UpdateLightningInvoice("webhook", db.UpdateInvoiceWithIssued(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.SettleInvoice("webhook", "token"); err != nil {
+		t.Fatalf("SettleInvoice() error = %v", err)
+	}
+	if i, err := storage.GetLightningInvoice("webhook"); err != nil || !i.IsPaid() || !i.IsIssued() {
+		t.Errorf("GetLightningInvoice() = %v, error = %v, want paid and issued invoice", i, err)
+	}
+}
+
+// newTestPaymentRequest creates a signed bolt11 payment request for amount sat.
+func newTestPaymentRequest(t *testing.T, amount int64) (pr string, paymentHash string) {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var preimage [32]byte
+	if _, err = rand.Read(preimage[:]); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(preimage[:])
+	i, err := zpay32.NewInvoice(&chaincfg.MainNetParams, hash, time.Now(),
+		zpay32.Amount(lnwire.MilliSatoshi(amount*1000)), zpay32.Description("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err = i.Encode(zpay32.MessageSigner{SignCompact: func(msg []byte) ([]byte, error) {
+		return ecdsa.SignCompact(key, chainhash.HashB(msg), true)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pr, hex.EncodeToString(hash[:])
+}
+
+// newTestProofs creates valid proofs for the current keyset of m
+func newTestProofs(t *testing.T, m *Mint, amounts ...uint64) []cashu.Proof {
+	return newTestKeySetProofs(t, m, m.keySets[m.KeySetId], amounts...)
+}
+
+// newTestKeySetProofs creates valid proofs for keySet
+func newTestKeySetProofs(t *testing.T, m *Mint, keySet *crypto.KeySet, amounts ...uint64) []cashu.Proof {
+	proofs := make([]cashu.Proof, 0)
+	for _, amount := range amounts {
+		secret := uuid.New().String()
+		r, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		B_, r := crypto.FirstStepAlice(secret, r)
+		promise, err := m.generatePromise(amount, keySet, B_)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := hex.DecodeString(promise.C_)
+		if err != nil {
+			t.Fatal(err)
+		}
+		C_, err := secp256k1.ParsePubKey(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		C := crypto.ThirdStepAlice(*C_, *r, *keySet.PublicKeys.GetKeyByAmount(amount).Key)
+		proofs = append(proofs, cashu.Proof{Id: keySet.Id, Amount: amount, Secret: secret, C: hex.EncodeToString(C.SerializeCompressed())})
+	}
+	return proofs
+}
+
+// recordingLightningClient fails the test, if the mint tries to pay through the lightning backend.
+type recordingLightningClient struct {
+	*testLightningClient
+	t *testing.T
+}
+
+func (c recordingLightningClient) Pay(paymentRequest string) (lightning.Invoicer, error) {
+	c.t.Errorf("Pay() called for internal invoice %s", paymentRequest)
+	return nil, fmt.Errorf("not allowed")
+}
+
+func TestMint_MeltInternal(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(recordingLightningClient{newTestLightningClient(), t}), WithInitialKeySet("0/0/0/0"))
+	pr, hash := newTestPaymentRequest(t, 8)
+	err := storage.StoreLightningInvoice(&invoice.Invoice{Amount: 8, Hash: hash, Pr: pr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee, err := m.CheckFees(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee != 0 {
+		t.Errorf("CheckFees() = %d, want 0", fee)
+	}
+	payment, err := m.Melt(newTestProofs(t, m, 8), pr)
+	if err != nil {
+		t.Fatalf("Melt() error = %v", err)
+	}
+	if !payment.IsPaid() {
+		t.Errorf("Melt() paid = false, want true")
+	}
+	i, err := storage.GetLightningInvoice(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !i.IsPaid() {
+		t.Errorf("internal invoice paid = false, want true")
+	}
+	// the same invoice can not be paid twice
+	if _, err = m.Melt(newTestProofs(t, m, 8), pr); err == nil {
+		t.Errorf("Melt() paid internal invoice twice")
+	}
+}
+
+func TestMint_MeltInternal_concurrent(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(recordingLightningClient{newTestLightningClient(), t}), WithInitialKeySet("0/0/0/0"))
+	pr, hash := newTestPaymentRequest(t, 8)
+	if err := storage.StoreLightningInvoice(&invoice.Invoice{Amount: 8, Hash: hash, Pr: pr}); err != nil {
+		t.Fatal(err)
+	}
+	// concurrent melts of the same internal invoice must not both burn their proofs
+	melts := 4
+	proofs := make([][]cashu.Proof, melts)
+	for i := range proofs {
+		proofs[i] = newTestProofs(t, m, 8)
+	}
+	errs := make(chan error, melts)
+	for i := 0; i < melts; i++ {
+		go func(proofs []cashu.Proof) {
+			_, err := m.Melt(proofs, pr)
+			errs <- err
+		}(proofs[i])
+	}
+	paid := 0
+	for i := 0; i < melts; i++ {
+		if err := <-errs; err == nil {
+			paid++
+		} else if !errors.Is(err, cashu.ErrInvoiceAlreadyPaid) {
+			t.Errorf("Melt() error = %v, want %v", err, cashu.ErrInvoiceAlreadyPaid)
+		}
+	}
+	if paid != 1 {
+		t.Errorf("Melt() paid internal invoice %d times, want once", paid)
+	}
+}
+
+// newTestOutputs creates blinded messages for amounts
+func newTestOutputs(t *testing.T, amounts ...uint64) cashu.BlindedMessages {
+	outputs := make(cashu.BlindedMessages, 0)
+	for _, amount := range amounts {
+		r, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		B_, _ := crypto.FirstStepAlice(uuid.New().String(), r)
+		outputs = append(outputs, cashu.BlindedMessage{Amount: amount, B_: hex.EncodeToString(B_.SerializeCompressed())})
+	}
+	return outputs
+}
+
+// signTestMessage creates the hex encoded schnorr signature of sha256(message)
+func signTestMessage(t *testing.T, key *btcec.PrivateKey, message []byte) string {
+	hash := sha256.Sum256(message)
+	sig, err := schnorr.Sign(key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(sig.Serialize())
+}
+
+func TestMint_ClaimLightningAddress(t *testing.T) {
+	client := newTestLightningClient()
+	m := New("master", WithStorage(newTestStorage(t)), WithClient(client), WithInitialKeySet("0/0/0/0"))
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey := hex.EncodeToString(schnorr.SerializePubKey(key.PubKey()))
+	if err = m.RegisterLightningAddress("alice", pubkey, signTestMessage(t, key, []byte("bob"))); err == nil {
+		t.Fatalf("RegisterLightningAddress() accepted invalid signature")
+	}
+	if err = m.RegisterLightningAddress("alice", pubkey, signTestMessage(t, key, []byte("alice"))); err != nil {
+		t.Fatalf("RegisterLightningAddress() error = %v", err)
+	}
+	if err = m.RegisterLightningAddress("alice", pubkey, signTestMessage(t, key, []byte("alice"))); err == nil {
+		t.Fatalf("RegisterLightningAddress() registered name twice")
+	}
+	paid, err := m.RequestLightningAddressMint("alice", 8, "metadata")
+	if err != nil {
+		t.Fatalf("RequestLightningAddressMint() error = %v", err)
+	}
+	if _, err = m.RequestLightningAddressMint("alice", 4, "metadata"); err != nil {
+		t.Fatalf("RequestLightningAddressMint() error = %v", err)
+	}
+	if _, err = m.RequestLightningAddressMint("bob", 4, "metadata"); err == nil {
+		t.Fatalf("RequestLightningAddressMint() created invoice for unknown address")
+	}
+	client.invoices[paid.GetHash()].Paid = true
+	// the invoice of a lightning address payment can not be minted with its payment hash
+	if _, err = m.Mint(newTestOutputs(t, 8), paid.GetHash(), m.keySets[m.KeySetId]); err == nil {
+		t.Fatalf("Mint() minted invoice of lightning address payment")
+	}
+	claimable, err := m.LightningAddressClaimable("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimable != 8 {
+		t.Errorf("LightningAddressClaimable() = %d, want 8", claimable)
+	}
+	other, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		outputs cashu.BlindedMessages
+		key     *btcec.PrivateKey
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{name: "invalidKey", args: args{outputs: newTestOutputs(t, 8), key: other}, wantErr: true},
+		{name: "amountTooHigh", args: args{outputs: newTestOutputs(t, 8, 4), key: key}, wantErr: true},
+		{name: "claim", args: args{outputs: newTestOutputs(t, 8), key: key}, wantErr: false},
+		{name: "claimTwice", args: args{outputs: newTestOutputs(t, 8), key: key}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := make([]byte, 0)
+			for _, output := range tt.args.outputs {
+				message = append(message, []byte(output.B_)...)
+			}
+			promises, err := m.ClaimLightningAddress("alice", tt.args.outputs, signTestMessage(t, tt.args.key, message))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClaimLightningAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(promises) != len(tt.args.outputs) {
+				t.Errorf("ClaimLightningAddress() got %d promises, want %d", len(promises), len(tt.args.outputs))
+			}
+		})
+	}
+}
+
+func TestMint_claimLightningAddressPayments(t *testing.T) {
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"))
+	payments := []cashu.LightningAddressPayment{{Hash: "a", Name: "alice", Amount: 1}, {Hash: "b", Name: "alice", Amount: 2}}
+	for _, p := range payments {
+		if err := storage.StoreLightningAddressPayment(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a concurrent claim claimed payment b first
+	if _, err := storage.ClaimLightningAddressPayment("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.claimLightningAddressPayments(payments); !errors.Is(err, cashu.ErrTokensIssued) {
+		t.Errorf("claimLightningAddressPayments() error = %v, want %v", err, cashu.ErrTokensIssued)
+	}
+	// payment a was released again
+	unclaimed, err := storage.GetLightningAddressPayments("alice", false)
+	if err != nil || len(unclaimed) != 1 || unclaimed[0].Hash != "a" {
+		t.Errorf("GetLightningAddressPayments() = %v, error = %v, want payment a", unclaimed, err)
+	}
+	// the invoice of payment a was issued, before it was claimed
+	if err = storage.StoreLightningInvoice(&invoice.Invoice{Amount: 1, Hash: "a", Paid: true, Issued: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.claimLightningAddressPayments(unclaimed); !errors.Is(err, cashu.ErrTokensIssued) {
+		t.Errorf("claimLightningAddressPayments() error = %v, want %v", err, cashu.ErrTokensIssued)
+	}
+}
+
+// offerLightningClient is a lightning backend, that pays all BOLT12 offers.
+type offerLightningClient struct {
+	*testLightningClient
+	amountMsat uint64
+}
+
+func (c *offerLightningClient) PayOffer(offer string, amountMsat uint64, maxFeeMsat uint64) (lightning.OfferPayment, error) {
+	c.amountMsat = amountMsat
+	return cln.ClnPayment{Paid: true, Preimage: "preimage", Invoice: "lni1invoice"}, nil
+}
+
+func TestMint_MeltWithMethod(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	type args struct {
+		method  string
+		request string
+		amount  uint64
+		proofs  []uint64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{name: "bolt12", args: args{method: cashu.MethodBolt12, request: "lno1offer", amount: 8, proofs: []uint64{8, 4}}},
+		{name: "bolt12WithoutAmount", args: args{method: cashu.MethodBolt12, request: "lno1offer", proofs: []uint64{8, 4}}, wantErr: true},
+		{name: "bolt12InvalidOffer", args: args{method: cashu.MethodBolt12, request: "lnbc1", amount: 8, proofs: []uint64{8, 4}}, wantErr: true},
+		{name: "bolt12FeeNotCovered", args: args{method: cashu.MethodBolt12, request: "lno1offer", amount: 8, proofs: []uint64{8}}, wantErr: true},
+		{name: "unknownMethod", args: args{method: "unknown", request: "lno1offer", amount: 8, proofs: []uint64{8, 4}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &offerLightningClient{testLightningClient: newTestLightningClient()}
+			m := New("master", WithStorage(newTestStorage(t)), WithClient(client), WithInitialKeySet("0/0/0/0"))
+			payment, err := m.MeltWithMethod(newTestProofs(t, m, tt.args.proofs...), tt.args.method, tt.args.request, tt.args.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MeltWithMethod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			offerPayment, ok := payment.(lightning.OfferPayment)
+			if !ok || !offerPayment.IsPaid() || offerPayment.GetInvoice() != "lni1invoice" {
+				t.Errorf("MeltWithMethod() = %v", payment)
+			}
+			if client.amountMsat != tt.args.amount*1000 {
+				t.Errorf("MeltWithMethod() paid %d msat, want %d", client.amountMsat, tt.args.amount*1000)
+			}
+		})
+	}
+}
+
+// testOnchainClient is an on-chain backend, that keeps all addresses in memory.
+type testOnchainClient struct {
+	received map[string]uint64
+	sent     map[string]uint64
+}
+
+func newTestOnchainClient() *testOnchainClient {
+	return &testOnchainClient{received: make(map[string]uint64), sent: make(map[string]uint64)}
+}
+
+func (c *testOnchainClient) DeriveAddress(index uint32) (string, error) {
+	return fmt.Sprintf("address-%d", index), nil
+}
+
+func (c *testOnchainClient) ReceivedByAddress(address string, minConfirmations int) (uint64, error) {
+	return c.received[address], nil
+}
+
+func (c *testOnchainClient) EstimateFee(address string, amount uint64) (uint64, error) {
+	return 2, nil
+}
+
+func (c *testOnchainClient) Send(address string, amount, maxFee uint64) (string, error) {
+	c.sent[address] += amount
+	return "txid", nil
+}
+
+func TestMint_OnchainMint(t *testing.T) {
+	client := newTestOnchainClient()
+	m := New("master", WithStorage(newTestStorage(t)), WithOnchainClient(client), WithInitialKeySet("0/0/0/0"))
+	quote, err := m.RequestOnchainMint(8)
+	if err != nil {
+		t.Fatalf("RequestOnchainMint() error = %v", err)
+	}
+	next, err := m.RequestOnchainMint(8)
+	if err != nil {
+		t.Fatalf("RequestOnchainMint() error = %v", err)
+	}
+	if quote.Address == next.Address || quote.Id == next.Id {
+		t.Fatalf("RequestOnchainMint() reused address %s", quote.Address)
+	}
+	tests := []struct {
+		name     string
+		id       string
+		received uint64
+		outputs  []uint64
+		wantErr  bool
+	}{
+		{name: "unknownQuote", id: "unknown", received: 8, outputs: []uint64{8}, wantErr: true},
+		{name: "notReceived", received: 0, outputs: []uint64{8}, wantErr: true},
+		{name: "partiallyReceived", received: 4, outputs: []uint64{8}, wantErr: true},
+		{name: "amountTooHigh", received: 8, outputs: []uint64{8, 4}, wantErr: true},
+		{name: "mint", received: 8, outputs: []uint64{8}, wantErr: false},
+		{name: "mintTwice", received: 8, outputs: []uint64{8}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.received[quote.Address] = tt.received
+			id := quote.Id
+			if tt.id != "" {
+				id = tt.id
+			}
+			promises, err := m.MintWithoutKeySet(newTestOutputs(t, tt.outputs...), id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MintWithoutKeySet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(promises) != len(tt.outputs) {
+				t.Errorf("MintWithoutKeySet() got %d promises, want %d", len(promises), len(tt.outputs))
+			}
+		})
+	}
+}
+
+func TestMint_OnchainMelt(t *testing.T) {
+	client := newTestOnchainClient()
+	m := New("master", WithStorage(newTestStorage(t)), WithOnchainClient(client), WithInitialKeySet("0/0/0/0"))
+	address, err := btcutil.NewAddressWitnessPubKeyHash(make([]byte, 20), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee, err := m.CheckMeltFees(cashu.MethodBitcoin, address.EncodeAddress(), 8)
+	if err != nil {
+		t.Fatalf("CheckMeltFees() error = %v", err)
+	}
+	if fee != 2000 {
+		t.Errorf("CheckMeltFees() = %d, want 2000", fee)
+	}
+	if _, err = m.MeltWithMethod(newTestProofs(t, m, 8, 1), cashu.MethodBitcoin, address.EncodeAddress(), 8); err == nil {
+		t.Errorf("MeltWithMethod() accepted proofs without fee reserve")
+	}
+	if _, err = m.MeltWithMethod(newTestProofs(t, m, 8, 2), cashu.MethodBitcoin, "invalid", 8); err == nil {
+		t.Errorf("MeltWithMethod() accepted invalid address")
+	}
+	payment, err := m.MeltWithMethod(newTestProofs(t, m, 8, 2), cashu.MethodBitcoin, address.EncodeAddress(), 8)
+	if err != nil {
+		t.Fatalf("MeltWithMethod() error = %v", err)
+	}
+	if p, ok := payment.(onchain.Payment); !ok || p.GetTxid() != "txid" {
+		t.Errorf("MeltWithMethod() = %v, want on-chain payment", payment)
+	}
+	if client.sent[address.EncodeAddress()] != 8 {
+		t.Errorf("MeltWithMethod() sent %d, want 8", client.sent[address.EncodeAddress()])
+	}
+}
+
+func TestMint_Units(t *testing.T) {
+	lightning.Config.Lightning.Lnbits = &lightning.LnbitsConfig{LightningReserveFeeMin: 4000, LightningFeePercent: 1}
+	client := newTestLightningClient()
+	storage := newTestStorage(t)
+	m := New("master", WithStorage(storage), WithClient(client), WithInitialKeySet("0/0/0/0", crypto.UnitMsat, crypto.UnitUsd),
+		WithRateProvider(exchange.FixedRates{crypto.UnitUsd: 2}))
+	if m.KeySetId != crypto.NewKeySet("master", "0/0/0/0").Id {
+		t.Errorf("KeySetId = %s, sat keyset id changed", m.KeySetId)
+	}
+	usd, err := m.ActiveKeySet(crypto.UnitUsd)
+	if err != nil {
+		t.Fatalf("ActiveKeySet() error = %v", err)
+	}
+	if usd.Id == m.KeySetId || m.GetKeySetUnits()[usd.Id] != crypto.UnitUsd {
+		t.Fatalf("ActiveKeySet() = %s, want usd keyset", usd.Id)
+	}
+	if _, err = m.RequestMintWithUnit(10, "eur"); err == nil {
+		t.Errorf("RequestMintWithUnit() accepted unsupported unit")
+	}
+	tests := []struct {
+		name       string
+		unit       string
+		amount     uint64
+		wantSat    int64
+		outputs    []uint64
+		wantErr    bool
+		mintOnSats bool
+	}{
+		{name: "usd", unit: crypto.UnitUsd, amount: 100, wantSat: 200, outputs: []uint64{64, 32, 4}},
+		{name: "msat", unit: crypto.UnitMsat, amount: 1500, wantSat: 2, outputs: []uint64{1024, 256, 128, 64, 16, 8, 4}},
+		{name: "amountTooHigh", unit: crypto.UnitUsd, amount: 100, wantSat: 200, outputs: []uint64{128}, wantErr: true},
+		{name: "satOutputsForUsd", unit: crypto.UnitUsd, amount: 100, wantSat: 200, outputs: []uint64{64}, wantErr: true, mintOnSats: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := m.RequestMintWithUnit(tt.amount, tt.unit)
+			if err != nil {
+				t.Fatalf("RequestMintWithUnit() error = %v", err)
+			}
+			if i.GetAmount() != tt.wantSat {
+				t.Errorf("RequestMintWithUnit() amount = %d, want %d", i.GetAmount(), tt.wantSat)
+			}
+			client.invoices[i.GetHash()].Paid = true
+			if tt.mintOnSats {
+				_, err = m.Mint(newTestOutputs(t, tt.outputs...), i.GetHash(), m.keySets[m.KeySetId])
+			} else {
+				_, err = m.MintWithoutKeySet(newTestOutputs(t, tt.outputs...), i.GetHash())
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	// usd proofs can not be split into sat outputs
+	if _, _, err = m.Split(newTestKeySetProofs(t, m, usd, 8), 4, newTestOutputs(t, 4, 4), m.keySets[m.KeySetId]); err == nil {
+		t.Errorf("Split() accepted outputs of a different unit")
+	}
+	// melting 8 sat requires 4 usd cents
+	pr, hash := newTestPaymentRequest(t, 8)
+	if err = storage.StoreLightningInvoice(&invoice.Invoice{Amount: 8, Hash: hash, Pr: pr}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Melt(newTestKeySetProofs(t, m, usd, 2), pr); err == nil {
+		t.Errorf("Melt() accepted proofs with insufficient usd value")
+	}
+	if _, err = m.Melt(newTestKeySetProofs(t, m, usd, 4), pr); err != nil {
+		t.Errorf("Melt() error = %v", err)
+	}
+}
+
+func TestMint_ArchiveSpentProofs(t *testing.T) {
+	storage := newTestStorage(t)
+	retired := New("master", WithStorage(storage), WithInitialKeySet("0/0/0/0"))
+	spent := newTestProofs(t, retired, 2, 8)
+	if err := retired.invalidateProofs(spent); err != nil {
+		t.Fatal(err)
+	}
+	// changing the derivation path retires the old keyset
+	m := New("master", WithStorage(storage), WithInitialKeySet("0/0/0/1"))
+	if len(m.proofsUsed) != 0 {
+		t.Errorf("proofsUsed = %v, want no proofs of expired keysets", m.proofsUsed)
+	}
+	keySets, err := storage.GetKeySet(db.KeySetWithId(retired.KeySetId))
+	if err != nil || len(keySets) != 1 || keySets[0].Active {
+		t.Fatalf("GetKeySet() = %v, error = %v, want retired keyset", keySets, err)
+	}
+	if archived, err := storage.GetArchivedProofs(spent[0].Secret, spent[1].Secret); err != nil || len(archived) != 2 {
+		t.Fatalf("GetArchivedProofs() = %v, error = %v, want 2 proofs", archived, err)
+	}
+	unspent := newTestProofs(t, m, 4)
+	if err = m.invalidateProofs(unspent); err != nil {
+		t.Fatal(err)
+	}
+	if archived, err := m.ArchiveSpentProofs(); err != nil || archived != 0 {
+		t.Errorf("ArchiveSpentProofs() = %d, error = %v, want no proofs of active keysets", archived, err)
+	}
+	tests := []struct {
+		name  string
+		proof cashu.Proof
+		want  bool
+	}{
+		{name: "archived", proof: spent[0], want: false},
+		{name: "archivedWithoutKeySet", proof: cashu.Proof{Secret: spent[1].Secret}, want: false},
+		{name: "spent", proof: unspent[0], want: false},
+		{name: "unspent", proof: cashu.Proof{Id: retired.KeySetId, Secret: "unspent"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.checkSpendable(tt.proof); got != tt.want {
+				t.Errorf("checkSpendable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
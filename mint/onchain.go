@@ -0,0 +1,123 @@
+package mint
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/onchain"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestOnchainMint creates a mint quote for amount, that is paid to a fresh address of the watched descriptor.
+func (m *Mint) RequestOnchainMint(amount uint64) (cashu.OnchainQuote, error) {
+	if m.onchain == nil {
+		return cashu.OnchainQuote{}, cashu.NewError(cashu.ErrCodeMethodNotSupported, "on-chain payments are not enabled")
+	}
+	if amount == 0 {
+		return cashu.OnchainQuote{}, cashu.NewError(cashu.ErrCodeInvalidAmount, "invalid amount: %d", amount)
+	}
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return cashu.OnchainQuote{}, err
+	}
+	m.onchainMu.Lock()
+	defer m.onchainMu.Unlock()
+	index, err := m.database.NextOnchainDerivationIndex()
+	if err != nil {
+		return cashu.OnchainQuote{}, err
+	}
+	address, err := m.onchain.DeriveAddress(index)
+	if err != nil {
+		return cashu.OnchainQuote{}, err
+	}
+	quote := cashu.OnchainQuote{
+		Id:              hex.EncodeToString(id),
+		Address:         address,
+		DerivationIndex: index,
+		Amount:          amount,
+		TimeCreated:     time.Now(),
+	}
+	if err = m.database.StoreOnchainQuote(quote); err != nil {
+		return cashu.OnchainQuote{}, err
+	}
+	log.WithFields(log.Fields{"address": address, "amount": amount}).Info("created on-chain mint quote")
+	return quote, nil
+}
+
+// getOnchainQuote returns the on-chain quote with id, if it was created by this mint.
+func (m *Mint) getOnchainQuote(id string) (cashu.OnchainQuote, bool) {
+	if m.onchain == nil || m.database == nil {
+		return cashu.OnchainQuote{}, false
+	}
+	quote, err := m.database.GetOnchainQuote(id)
+	if err != nil {
+		return cashu.OnchainQuote{}, false
+	}
+	return quote, true
+}
+
+// checkOnchainQuote will check the amount received by the quote address with enough confirmations.
+// Returns true and marks the quote as issued, if the quote is paid.
+func (m *Mint) checkOnchainQuote(amounts []uint64, quote cashu.OnchainQuote) (bool, error) {
+	if quote.Issued {
+		return false, fmt.Errorf("%w for this quote.", cashu.ErrTokensIssued)
+	}
+	total := lo.SumBy[uint64](amounts, func(amount uint64) uint64 {
+		return amount
+	})
+	if total > quote.Amount {
+		return false, cashu.NewError(cashu.ErrCodeInvalidAmount, "requested amount too high: %d. Quote amount: %d", total, quote.Amount)
+	}
+	received, err := m.onchain.ReceivedByAddress(quote.Address, onchain.Config.Onchain.MinConfirmations)
+	if err != nil {
+		return false, err
+	}
+	if received < quote.Amount {
+		return false, nil
+	}
+	// the quote is issued with a conditional update, so that concurrent mints of the same quote can not both succeed
+	issued, err := m.database.IssueOnchainQuote(quote.Id, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if !issued {
+		return false, fmt.Errorf("%w for this quote.", cashu.ErrTokensIssued)
+	}
+	return true, nil
+}
+
+// bitcoinMeltQuote will pay amount to the on-chain address request. The fee reserve is estimated by the backend.
+// The transaction fee is limited to the fee reserve.
+func (m *Mint) bitcoinMeltQuote(request string, amount uint64) (*meltQuote, error) {
+	if m.onchain == nil {
+		return nil, cashu.NewError(cashu.ErrCodeMethodNotSupported, "on-chain payments are not enabled")
+	}
+	if _, err := btcutil.DecodeAddress(request, onchain.NetParams()); err != nil {
+		return nil, cashu.NewError(cashu.ErrCodeBadRequest, "invalid bitcoin address: %s", request)
+	}
+	if amount == 0 {
+		return nil, cashu.NewError(cashu.ErrCodeInvalidAmount, "amount is required to pay a bitcoin address")
+	}
+	fee, err := m.onchain.EstimateFee(request, amount)
+	if err != nil {
+		return nil, err
+	}
+	return &meltQuote{
+		amount: amount,
+		fee:    fee * 1000,
+		pay: func() (lightning.Payment, error) {
+			txid, err := m.onchain.Send(request, amount, fee)
+			if err != nil {
+				return nil, err
+			}
+			log.WithFields(log.Fields{"address": request, "amount": amount, "txid": txid}).Info("sent on-chain payment")
+			return onchain.Payment{Txid: txid}, nil
+		},
+	}, nil
+}
@@ -0,0 +1,122 @@
+package mint
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/lightning"
+	log "github.com/sirupsen/logrus"
+)
+
+// operations counts the splits and melts in progress, so that the mint can wait for them on shutdown
+type operations struct {
+	mu      sync.Mutex
+	count   int
+	closing bool
+	// idle is closed, when the last operation finished after the mint started closing
+	idle chan struct{}
+}
+
+// beginOperation registers a split or melt. New operations are rejected, after the mint started closing.
+func (m *Mint) beginOperation() error {
+	m.operations.mu.Lock()
+	defer m.operations.mu.Unlock()
+	if m.operations.closing {
+		return cashu.ErrShuttingDown
+	}
+	m.operations.count++
+	return nil
+}
+
+// endOperation removes a finished split or melt
+func (m *Mint) endOperation() {
+	m.operations.mu.Lock()
+	defer m.operations.mu.Unlock()
+	m.operations.count--
+	if m.operations.count == 0 && m.operations.idle != nil {
+		close(m.operations.idle)
+		m.operations.idle = nil
+	}
+}
+
+// Closing returns true, if the mint started closing and rejects new splits and melts
+func (m *Mint) Closing() bool {
+	m.operations.mu.Lock()
+	defer m.operations.mu.Unlock()
+	return m.operations.closing
+}
+
+// Close rejects new splits and melts, waits until the splits and melts in progress are finished and closes the database.
+// If ctx is done first, the database is closed anyway. Melts, whose payment was still in flight, stay pending and have to be resolved.
+func (m *Mint) Close(ctx context.Context) error {
+	m.operations.mu.Lock()
+	m.operations.closing = true
+	idle := make(chan struct{})
+	if m.operations.count == 0 {
+		close(idle)
+	} else {
+		m.operations.idle = idle
+	}
+	m.operations.mu.Unlock()
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+		m.meltsMu.Lock()
+		log.WithFields(log.Fields{"error.message": err.Error(), "melts": len(m.meltsInFlight)}).
+			Warn("stopped waiting for operations in progress. interrupted melts have to be resolved")
+		m.meltsMu.Unlock()
+	}
+	if m.database == nil {
+		return err
+	}
+	if closeErr := m.database.Close(); closeErr != nil {
+		return closeErr
+	}
+	log.Info("closed mint database")
+	return err
+}
+
+const (
+	HealthOk          = "ok"
+	HealthUnavailable = "unavailable"
+	// HealthUnknown is the status of lightning backends, which can not report their health
+	HealthUnknown = "unknown"
+	// HealthDisabled is the status of the lightning backend, if lightning is disabled
+	HealthDisabled = "disabled"
+)
+
+// Health is the status of the mint and its backends
+type Health struct {
+	// Ready is true, if the mint accepts requests and all backends are available
+	Ready     bool   `json:"ready"`
+	Closing   bool   `json:"closing"`
+	Database  string `json:"database"`
+	Lightning string `json:"lightning"`
+}
+
+// Health checks the database and the lightning backend. Backend errors are logged and not returned,
+// because the health is public.
+func (m *Mint) Health() Health {
+	health := Health{Closing: m.Closing(), Database: HealthOk, Lightning: HealthUnknown}
+	if m.database == nil {
+		health.Database = HealthUnavailable
+	} else if err := m.database.Ping(); err != nil {
+		log.WithFields(log.Fields{"error.message": err.Error()}).Warn("database is unavailable")
+		health.Database = HealthUnavailable
+	}
+	// lightning backends are checked by requesting their liquidity
+	if reporter, ok := m.client.(lightning.LiquidityReporter); ok {
+		health.Lightning = HealthOk
+		if _, err := reporter.Liquidity(); err != nil {
+			log.WithFields(log.Fields{"error.message": err.Error()}).Warn("lightning backend is unavailable")
+			health.Lightning = HealthUnavailable
+		}
+	} else if m.client == nil {
+		health.Lightning = HealthDisabled
+	}
+	health.Ready = !health.Closing && health.Database != HealthUnavailable && health.Lightning != HealthUnavailable
+	return health
+}
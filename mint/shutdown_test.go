@@ -0,0 +1,94 @@
+package mint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/lightning"
+)
+
+// unavailableLightningClient can not report its liquidity
+type unavailableLightningClient struct {
+	*testLightningClient
+}
+
+func (c unavailableLightningClient) Liquidity() (lightning.Liquidity, error) {
+	return lightning.Liquidity{}, fmt.Errorf("connection refused")
+}
+
+func TestMint_Close(t *testing.T) {
+	tests := []struct {
+		name      string
+		finish    bool
+		wantError error
+	}{
+		{name: "finished", finish: true},
+		{name: "timeout", wantError: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New("master", WithStorage(newTestStorage(t)), WithClient(newTestLightningClient()), WithInitialKeySet("0/0/0/0"))
+			proofs := newTestProofs(t, m, 8)
+			// an operation is in progress, while the mint is closed
+			if err := m.beginOperation(); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			closed := make(chan error)
+			go func() {
+				closed <- m.Close(ctx)
+			}()
+			for !m.Closing() {
+				time.Sleep(time.Millisecond)
+			}
+			if _, _, err := m.Split(proofs, 0, nil, m.keySets[m.KeySetId]); !errors.Is(err, cashu.ErrShuttingDown) {
+				t.Errorf("Split() error = %v, want %v", err, cashu.ErrShuttingDown)
+			}
+			if tt.finish {
+				m.endOperation()
+			}
+			if err := <-closed; !errors.Is(err, tt.wantError) {
+				t.Errorf("Close() error = %v, want %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestMint_Health(t *testing.T) {
+	tests := []struct {
+		name   string
+		client lightning.Client
+		close  bool
+		want   Health
+	}{
+		{name: "ready", client: liquidityLightningClient{newTestLightningClient(), 1000},
+			want: Health{Ready: true, Database: HealthOk, Lightning: HealthOk}},
+		{name: "unknownLightning", client: newTestLightningClient(),
+			want: Health{Ready: true, Database: HealthOk, Lightning: HealthUnknown}},
+		{name: "disabledLightning",
+			want: Health{Ready: true, Database: HealthOk, Lightning: HealthDisabled}},
+		{name: "unavailableLightning", client: unavailableLightningClient{newTestLightningClient()},
+			want: Health{Database: HealthOk, Lightning: HealthUnavailable}},
+		{name: "closing", client: newTestLightningClient(), close: true,
+			want: Health{Closing: true, Database: HealthOk, Lightning: HealthUnknown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New("master", WithStorage(newTestStorage(t)), WithClient(tt.client), WithInitialKeySet("0/0/0/0", crypto.UnitUsd))
+			if tt.close {
+				if err := m.Close(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := m.Health(); got != tt.want {
+				t.Errorf("Health() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
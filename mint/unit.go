@@ -0,0 +1,108 @@
+package mint
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/crypto"
+	"github.com/cashubtc/cashu-feni/exchange"
+)
+
+// WithRateProvider sets the exchange rate provider used to price mints and melts of non satoshi units.
+func WithRateProvider(rates exchange.RateProvider) Options {
+	return func(l *Mint) {
+		l.rates = rates
+	}
+}
+
+// SetRateProvider replaces the exchange rate provider of the running mint.
+func (m *Mint) SetRateProvider(rates exchange.RateProvider) {
+	m.ratesMu.Lock()
+	defer m.ratesMu.Unlock()
+	m.rates = rates
+}
+
+// ActiveKeySet returns the active keyset for unit.
+func (m *Mint) ActiveKeySet(unit string) (*crypto.KeySet, error) {
+	if unit == "" {
+		unit = crypto.UnitSat
+	}
+	_, activeKeySets := m.loadedKeySets()
+	id, ok := activeKeySets[unit]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", cashu.ErrUnitNotSupported, unit)
+	}
+	return m.LoadKeySet(id)
+}
+
+// GetKeySetUnits returns the unit of every keyset id.
+func (m *Mint) GetKeySetUnits() map[string]string {
+	keySets, _ := m.loadedKeySets()
+	units := make(map[string]string)
+	for id, keySet := range keySets {
+		units[id] = keySet.GetUnit()
+	}
+	return units
+}
+
+// ProofsUnit returns the unit of proofs. All proofs must have the same unit.
+func (m *Mint) ProofsUnit(proofs []cashu.Proof) (string, error) {
+	keySets, _ := m.loadedKeySets()
+	unit := ""
+	for _, proof := range proofs {
+		proofUnit := crypto.UnitSat
+		if keySet, ok := keySets[proof.Id]; ok {
+			proofUnit = keySet.GetUnit()
+		}
+		if unit != "" && unit != proofUnit {
+			return "", cashu.NewError(cashu.ErrCodeUnitNotSupported, "proofs have different units: %s and %s", unit, proofUnit)
+		}
+		unit = proofUnit
+	}
+	if unit == "" {
+		return crypto.UnitSat, nil
+	}
+	return unit, nil
+}
+
+// satPerUnit returns the value of one unit in satoshi.
+func (m *Mint) satPerUnit(unit string) (float64, error) {
+	m.ratesMu.RLock()
+	rates := m.rates
+	m.ratesMu.RUnlock()
+	if rates == nil {
+		return 0, fmt.Errorf("%w: no exchange rate provider for unit %s", cashu.ErrUnitNotSupported, unit)
+	}
+	return rates.SatPerUnit(unit)
+}
+
+// ToSat converts amount in unit to satoshi. Fractions of satoshi are rounded up.
+func (m *Mint) ToSat(amount uint64, unit string) (uint64, error) {
+	switch unit {
+	case "", crypto.UnitSat:
+		return amount, nil
+	case crypto.UnitMsat:
+		return (amount + 999) / 1000, nil
+	}
+	rate, err := m.satPerUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Ceil(float64(amount) * rate)), nil
+}
+
+// FromSat converts amount in satoshi to unit. Fractions of unit are rounded up.
+func (m *Mint) FromSat(amount uint64, unit string) (uint64, error) {
+	switch unit {
+	case "", crypto.UnitSat:
+		return amount, nil
+	case crypto.UnitMsat:
+		return amount * 1000, nil
+	}
+	rate, err := m.satPerUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Ceil(float64(amount) / rate)), nil
+}
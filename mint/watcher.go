@@ -0,0 +1,114 @@
+package mint
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/cashubtc/cashu-feni/cashu"
+	"github.com/cashubtc/cashu-feni/lightning"
+	log "github.com/sirupsen/logrus"
+)
+
+// invoiceWatcherMaxAge invoices older than this are not checked by the invoice watcher anymore.
+const invoiceWatcherMaxAge = 24 * time.Hour
+
+// WatchInvoices will check all unpaid lightning invoices in the given interval and mark settled invoices as paid.
+// WatchInvoices blocks until ctx is done.
+func (m *Mint) WatchInvoices(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	log.WithField("interval", interval.String()).Info("starting lightning invoice watcher")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stopping lightning invoice watcher")
+			return
+		case <-ticker.C:
+			if err := m.checkPendingInvoices(); err != nil {
+				log.WithFields(log.Fields{"error.message": err.Error()}).Errorf("could not check pending invoices")
+			}
+		}
+	}
+}
+
+// checkPendingInvoices asks the lightning backend for the status of every unpaid invoice.
+func (m *Mint) checkPendingInvoices() error {
+	invoices, err := m.database.GetLightningInvoices(false, time.Now().Add(-invoiceWatcherMaxAge))
+	if err != nil {
+		return err
+	}
+	for i := range invoices {
+		invoice := &invoices[i]
+		if _, err = m.updateInvoicePaid(invoice); err != nil {
+			log.WithFields(log.Fields{"error.message": err.Error(), "hash": invoice.GetHash()}).Warn("could not check invoice status")
+		}
+	}
+	return nil
+}
+
+// updateInvoicePaid checks the invoice status with the lightning backend and persists the paid state.
+// The invoice is marked as paid with a conditional update, so that a concurrent mint of the invoice is not overwritten.
+// Returns true, if the invoice is paid.
+func (m *Mint) updateInvoicePaid(invoice lightning.Invoicer) (bool, error) {
+	if invoice.IsPaid() {
+		return true, nil
+	}
+	observe := observeLightning("invoice_status")
+	payment, err := m.client.InvoiceStatus(invoice.GetHash())
+	observe()
+	if err != nil {
+		return false, err
+	}
+	if !payment.IsPaid() {
+		return false, nil
+	}
+	now := time.Now()
+	settled, err := m.database.SetLightningInvoicePaid(invoice.GetHash(), now)
+	if err != nil {
+		return false, err
+	}
+	invoice.SetPaid(true)
+	if settled {
+		invoice.SetTimePaid(now)
+		log.WithFields(invoice.Log()).Info("lightning invoice paid")
+	}
+	return true, nil
+}
+
+// InvoiceStatus returns the stored lightning invoice for paymentHash.
+// If the invoice is not paid yet, the lightning backend is asked once for its status.
+func (m *Mint) InvoiceStatus(paymentHash string) (lightning.Invoicer, error) {
+	if m.client == nil {
+		return nil, cashu.NewError(cashu.ErrCodeMethodNotSupported, "lightning is not enabled")
+	}
+	invoice, err := m.getLightningInvoice(paymentHash)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = m.updateInvoicePaid(invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// SettleInvoice marks the invoice for paymentHash as paid without asking the lightning backend.
+// It is called by the payment webhook of the backend, which is authenticated with the invoices webhook token.
+func (m *Mint) SettleInvoice(paymentHash, token string) error {
+	invoice, err := m.getLightningInvoice(paymentHash)
+	if err != nil {
+		return err
+	}
+	webhookInvoice, ok := invoice.(lightning.WebhookInvoicer)
+	if !ok || webhookInvoice.GetWebhookToken() == "" ||
+		subtle.ConstantTimeCompare([]byte(webhookInvoice.GetWebhookToken()), []byte(token)) != 1 {
+		return cashu.NewError(cashu.ErrCodeUnauthorized, "invalid webhook token")
+	}
+	// the conditional update does not overwrite invoices, which were paid and issued in the meantime
+	settled, err := m.database.SetLightningInvoicePaid(paymentHash, time.Now())
+	if err != nil || !settled {
+		return err
+	}
+	log.WithFields(invoice.Log()).Info("lightning invoice settled by webhook")
+	return nil
+}
@@ -0,0 +1,171 @@
+package bitcoind
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/onchain"
+	"github.com/imroc/req"
+)
+
+const (
+	satPerBitcoin = 100_000_000
+	// estimatedTxVsize is the virtual size of a payment transaction used for fee estimation.
+	// It is large enough for two segwit inputs, a payment and a change output.
+	estimatedTxVsize  = 250
+	defaultConfTarget = 6
+	// errCodeAlreadyInChain is returned by sendrawtransaction for transactions, which were already broadcast
+	errCodeAlreadyInChain = -27
+)
+
+// NewClient returns a new bitcoind json-rpc client. Pass the rpc url, credentials and the watched descriptor here.
+// If wallet is set, all wallet rpc calls are sent to this wallet.
+func NewClient(url, user, password, wallet, descriptor string, confTarget int) onchain.Client {
+	if wallet != "" {
+		url = fmt.Sprintf("%s/wallet/%s", strings.TrimSuffix(url, "/"), wallet)
+	}
+	if confTarget <= 0 {
+		confTarget = defaultConfTarget
+	}
+	return &Client{
+		url:        url,
+		descriptor: descriptor,
+		confTarget: confTarget,
+		header: req.Header{
+			"Content-Type":  "application/json",
+			"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password)),
+		},
+	}
+}
+
+// call will call the rpc method with params and decode the result into v
+func (c *Client) call(method string, v interface{}, params ...interface{}) error {
+	if params == nil {
+		params = make([]interface{}, 0)
+	}
+	resp, err := req.Post(c.url, c.header, req.BodyJSON(Request{JsonRpc: "1.0", Id: "cashu-feni", Method: method, Params: params}))
+	if err != nil {
+		return err
+	}
+	response := Response{}
+	if err = resp.ToJSON(&response); err != nil {
+		return fmt.Errorf("bitcoind returned status %d", resp.Response().StatusCode)
+	}
+	if response.Error != nil {
+		return *response.Error
+	}
+	return json.Unmarshal(response.Result, v)
+}
+
+// toBitcoin formats amount in satoshi as exact bitcoin amount
+func toBitcoin(amount uint64) json.Number {
+	return json.Number(fmt.Sprintf("%d.%08d", amount/satPerBitcoin, amount%satPerBitcoin))
+}
+
+// toSatoshi converts a bitcoin amount to satoshi
+func toSatoshi(amount float64) uint64 {
+	return uint64(math.Round(amount * satPerBitcoin))
+}
+
+// descriptorWithChecksum returns the descriptor including its checksum, which is required by deriveaddresses
+func (c *Client) descriptorWithChecksum() (string, error) {
+	if strings.Contains(c.descriptor, "#") {
+		return c.descriptor, nil
+	}
+	info := DescriptorInfo{}
+	if err := c.call("getdescriptorinfo", &info, c.descriptor); err != nil {
+		return "", err
+	}
+	c.descriptor = fmt.Sprintf("%s#%s", c.descriptor, info.Checksum)
+	return c.descriptor, nil
+}
+
+// DeriveAddress returns the address at index of the watched descriptor.
+func (c *Client) DeriveAddress(index uint32) (string, error) {
+	descriptor, err := c.descriptorWithChecksum()
+	if err != nil {
+		return "", err
+	}
+	addresses := make([]string, 0)
+	if err = c.call("deriveaddresses", &addresses, descriptor, []uint32{index, index}); err != nil {
+		return "", err
+	}
+	if len(addresses) != 1 {
+		return "", fmt.Errorf("could not derive address %d", index)
+	}
+	return addresses[0], nil
+}
+
+// ReceivedByAddress returns the amount in satoshi received by address with at least minConfirmations.
+func (c *Client) ReceivedByAddress(address string, minConfirmations int) (uint64, error) {
+	var amount float64
+	if err := c.call("getreceivedbyaddress", &amount, address, minConfirmations); err != nil {
+		return 0, err
+	}
+	return toSatoshi(amount), nil
+}
+
+// Balance returns the balance in satoshi of the wallet, that was confirmed at least minConfirmations times.
+func (c *Client) Balance(minConfirmations int) (uint64, error) {
+	var amount float64
+	if err := c.call("getbalance", &amount, "*", minConfirmations); err != nil {
+		return 0, err
+	}
+	return toSatoshi(amount), nil
+}
+
+// EstimateFee returns the estimated fee in satoshi for a payment transaction confirming within the conf target.
+func (c *Client) EstimateFee(address string, amount uint64) (uint64, error) {
+	fee := SmartFee{}
+	if err := c.call("estimatesmartfee", &fee, c.confTarget); err != nil {
+		return 0, err
+	}
+	if len(fee.Errors) > 0 || fee.FeeRate <= 0 {
+		return 0, fmt.Errorf("could not estimate fee: %s", strings.Join(fee.Errors, ", "))
+	}
+	return uint64(math.Ceil(fee.FeeRate * satPerBitcoin * estimatedTxVsize / 1000)), nil
+}
+
+// Send sends amount satoshi to address and returns the transaction id.
+// The transaction is funded and signed by the wallet and its fee is paid in addition to amount.
+// The transaction is only broadcast, if its fee does not exceed maxFee satoshi.
+// Errors before the broadcast are marked with lightning.ErrPaymentNotSent.
+func (c *Client) Send(address string, amount, maxFee uint64) (string, error) {
+	funded := FundedPsbt{}
+	// walletcreatefundedpsbt inputs outputs locktime options
+	outputs := []map[string]json.Number{{address: toBitcoin(amount)}}
+	options := map[string]interface{}{"conf_target": c.confTarget, "replaceable": true}
+	if err := c.call("walletcreatefundedpsbt", &funded, []interface{}{}, outputs, 0, options); err != nil {
+		return "", lightning.PaymentNotSent(err)
+	}
+	if fee := toSatoshi(funded.Fee); fee > maxFee {
+		return "", lightning.PaymentNotSent(fmt.Errorf("transaction fee %d exceeds fee reserve %d", fee, maxFee))
+	}
+	signed := ProcessedPsbt{}
+	if err := c.call("walletprocesspsbt", &signed, funded.Psbt); err != nil {
+		return "", lightning.PaymentNotSent(err)
+	}
+	if !signed.Complete {
+		return "", lightning.PaymentNotSent(fmt.Errorf("wallet could not sign the transaction. the wallet needs the private keys of its descriptors"))
+	}
+	finalized := FinalizedPsbt{}
+	if err := c.call("finalizepsbt", &finalized, signed.Psbt); err != nil {
+		return "", lightning.PaymentNotSent(err)
+	}
+	if !finalized.Complete {
+		return "", lightning.PaymentNotSent(fmt.Errorf("could not finalize the transaction"))
+	}
+	var txid string
+	err := c.call("sendrawtransaction", &txid, finalized.Hex)
+	// rejected transactions and unreachable nodes were not broadcast. other errors (e.g. timeouts) are ambiguous.
+	var rpcErr Error
+	if lightning.IsConnectionError(err) || (errors.As(err, &rpcErr) && rpcErr.Code != errCodeAlreadyInChain) {
+		return "", lightning.PaymentNotSent(err)
+	}
+	return txid, err
+}
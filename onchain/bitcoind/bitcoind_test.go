@@ -0,0 +1,199 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cashubtc/cashu-feni/lightning"
+	"github.com/cashubtc/cashu-feni/onchain"
+)
+
+// fakeBitcoind is a bitcoind json-rpc server, that answers with fixed results.
+type fakeBitcoind struct {
+	t       *testing.T
+	results map[string]string
+	// errors are the json-rpc errors of methods, which fail
+	errors map[string]string
+	calls  map[string][]interface{}
+	path   string
+}
+
+func (f *fakeBitcoind) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user != "user" || password != "password" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	request := Request{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		f.t.Fatal(err)
+	}
+	f.calls[request.Method] = request.Params
+	f.path = r.URL.Path
+	if rpcErr, ok := f.errors[request.Method]; ok {
+		w.Write([]byte(`{"result":null,"error":` + rpcErr + `,"id":"cashu-feni"}`))
+		return
+	}
+	result, ok := f.results[request.Method]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":"cashu-feni"}`))
+		return
+	}
+	w.Write([]byte(`{"result":` + result + `,"error":null,"id":"cashu-feni"}`))
+}
+
+func newFakeBitcoind(t *testing.T, results map[string]string) (*fakeBitcoind, *httptest.Server) {
+	f := &fakeBitcoind{t: t, results: results, calls: make(map[string][]interface{})}
+	return f, httptest.NewServer(f)
+}
+
+func TestClient_DeriveAddress(t *testing.T) {
+	f, server := newFakeBitcoind(t, map[string]string{
+		"getdescriptorinfo": `{"descriptor":"wpkh(xpub/0/*)#abcdefgh","checksum":"abcdefgh","isrange":true}`,
+		"deriveaddresses":   `["bcrt1qaddress"]`,
+	})
+	defer server.Close()
+	client := NewClient(server.URL, "user", "password", "mint", "wpkh(xpub/0/*)", 0)
+	address, err := client.DeriveAddress(5)
+	if err != nil {
+		t.Fatalf("DeriveAddress() error = %v", err)
+	}
+	if address != "bcrt1qaddress" {
+		t.Errorf("DeriveAddress() = %s, want bcrt1qaddress", address)
+	}
+	params := f.calls["deriveaddresses"]
+	if params[0] != "wpkh(xpub/0/*)#abcdefgh" {
+		t.Errorf("DeriveAddress() descriptor = %v", params[0])
+	}
+	if r := params[1].([]interface{}); r[0] != float64(5) || r[1] != float64(5) {
+		t.Errorf("DeriveAddress() range = %v", r)
+	}
+	if f.path != "/wallet/mint" {
+		t.Errorf("DeriveAddress() path = %s, want /wallet/mint", f.path)
+	}
+}
+
+func TestClient_ReceivedByAddress(t *testing.T) {
+	f, server := newFakeBitcoind(t, map[string]string{"getreceivedbyaddress": `0.00012345`})
+	defer server.Close()
+	client := NewClient(server.URL, "user", "password", "", "", 0)
+	amount, err := client.ReceivedByAddress("bcrt1qaddress", 3)
+	if err != nil {
+		t.Fatalf("ReceivedByAddress() error = %v", err)
+	}
+	if amount != 12345 {
+		t.Errorf("ReceivedByAddress() = %d, want 12345", amount)
+	}
+	if params := f.calls["getreceivedbyaddress"]; params[0] != "bcrt1qaddress" || params[1] != float64(3) {
+		t.Errorf("ReceivedByAddress() params = %v", params)
+	}
+}
+
+func TestClient_Balance(t *testing.T) {
+	f, server := newFakeBitcoind(t, map[string]string{"getbalance": `1.5`})
+	defer server.Close()
+	client := NewClient(server.URL, "user", "password", "mint", "", 0)
+	balance, err := client.(onchain.BalanceReporter).Balance(2)
+	if err != nil {
+		t.Fatalf("Balance() error = %v", err)
+	}
+	if balance != 150000000 {
+		t.Errorf("Balance() = %d, want 150000000", balance)
+	}
+	if params := f.calls["getbalance"]; params[0] != "*" || params[1] != float64(2) {
+		t.Errorf("Balance() params = %v", params)
+	}
+}
+
+func TestClient_EstimateFee(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		want    uint64
+		wantErr bool
+	}{
+		{name: "estimate", result: `{"feerate":0.0001,"blocks":6}`, want: 2500},
+		{name: "insufficientData", result: `{"errors":["Insufficient data or no feerate found"],"blocks":0}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newFakeBitcoind(t, map[string]string{"estimatesmartfee": tt.result})
+			defer server.Close()
+			fee, err := NewClient(server.URL, "user", "password", "", "", 6).EstimateFee("bcrt1qaddress", 1000)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EstimateFee() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fee != tt.want {
+				t.Errorf("EstimateFee() = %d, want %d", fee, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Send(t *testing.T) {
+	results := map[string]string{
+		"walletcreatefundedpsbt": `{"psbt":"funded","fee":0.00002,"changepos":1}`,
+		"walletprocesspsbt":      `{"psbt":"signed","complete":true}`,
+		"finalizepsbt":           `{"hex":"rawtx","complete":true}`,
+		"sendrawtransaction":     `"txid"`,
+	}
+	tests := []struct {
+		name        string
+		results     map[string]string
+		errors      map[string]string
+		maxFee      uint64
+		want        string
+		wantErr     bool
+		wantNotSent bool
+		// wantBroadcast is true, if the transaction should be passed to sendrawtransaction
+		wantBroadcast bool
+	}{
+		{name: "sent", maxFee: 2000, want: "txid", wantBroadcast: true},
+		{name: "feeExceeded", maxFee: 1999, wantErr: true, wantNotSent: true},
+		{name: "watchOnly", results: map[string]string{"walletprocesspsbt": `{"psbt":"funded","complete":false}`}, maxFee: 2000, wantErr: true, wantNotSent: true},
+		{name: "rejected", errors: map[string]string{"sendrawtransaction": `{"code":-26,"message":"min relay fee not met"}`}, maxFee: 2000, wantErr: true, wantNotSent: true, wantBroadcast: true},
+		{name: "alreadyInChain", errors: map[string]string{"sendrawtransaction": `{"code":-27,"message":"transaction already in block chain"}`}, maxFee: 2000, wantErr: true, wantBroadcast: true},
+		{name: "invalidResponse", results: map[string]string{"sendrawtransaction": `}`}, maxFee: 2000, wantErr: true, wantBroadcast: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := make(map[string]string)
+			for method, result := range results {
+				r[method] = result
+			}
+			for method, result := range tt.results {
+				r[method] = result
+			}
+			f, server := newFakeBitcoind(t, r)
+			defer server.Close()
+			f.errors = tt.errors
+			txid, err := NewClient(server.URL, "user", "password", "", "", 2).Send("bcrt1qaddress", 123456789, tt.maxFee)
+			if (err != nil) != tt.wantErr || errors.Is(err, lightning.ErrPaymentNotSent) != tt.wantNotSent {
+				t.Fatalf("Send() error = %v, wantErr %v, wantNotSent %v", err, tt.wantErr, tt.wantNotSent)
+			}
+			if txid != tt.want {
+				t.Errorf("Send() = %s, want %s", txid, tt.want)
+			}
+			params := f.calls["walletcreatefundedpsbt"]
+			outputs, ok := params[1].([]interface{})
+			if !ok || len(outputs) != 1 || outputs[0].(map[string]interface{})["bcrt1qaddress"] != 1.23456789 {
+				t.Errorf("Send() outputs = %v", params[1])
+			}
+			if options := params[3].(map[string]interface{}); options["conf_target"] != float64(2) {
+				t.Errorf("Send() options = %v", options)
+			}
+			if _, broadcast := f.calls["sendrawtransaction"]; broadcast != tt.wantBroadcast {
+				t.Errorf("Send() broadcast = %v", broadcast)
+			}
+		})
+	}
+	_, server := newFakeBitcoind(t, results)
+	defer server.Close()
+	if _, err := NewClient(server.URL, "user", "password", "", "", 2).DeriveAddress(0); err == nil || err.Error() != "Method not found" {
+		t.Errorf("DeriveAddress() error = %v, want Method not found", err)
+	}
+}
@@ -0,0 +1,64 @@
+package bitcoind
+
+import (
+	"encoding/json"
+
+	"github.com/imroc/req"
+)
+
+type Client struct {
+	url        string
+	header     req.Header
+	descriptor string
+	confTarget int
+}
+
+type Request struct {
+	JsonRpc string        `json:"jsonrpc"`
+	Id      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type Response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+	Id     string          `json:"id"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err Error) Error() string {
+	return err.Message
+}
+
+type DescriptorInfo struct {
+	Descriptor string `json:"descriptor"`
+	Checksum   string `json:"checksum"`
+	IsRange    bool   `json:"isrange"`
+}
+
+type SmartFee struct {
+	FeeRate float64  `json:"feerate"` // fee rate in BTC/kvB
+	Errors  []string `json:"errors"`
+	Blocks  int      `json:"blocks"`
+}
+
+type FundedPsbt struct {
+	Psbt      string  `json:"psbt"`
+	Fee       float64 `json:"fee"` // fee in BTC
+	ChangePos int     `json:"changepos"`
+}
+
+type ProcessedPsbt struct {
+	Psbt     string `json:"psbt"`
+	Complete bool   `json:"complete"`
+}
+
+type FinalizedPsbt struct {
+	Hex      string `json:"hex"`
+	Complete bool   `json:"complete"`
+}
@@ -0,0 +1,65 @@
+package onchain
+
+// Will handle the yaml configuration for the on-chain backend.
+import (
+	"errors"
+	"os"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/jinzhu/configor"
+)
+
+// Configuration for the on-chain backend
+type Configuration struct {
+	Onchain struct {
+		Enabled bool `json:"enabled" yaml:"enabled"`
+		// Network of the addresses (mainnet, testnet, signet or regtest)
+		Network string `json:"network" yaml:"network"`
+		// MinConfirmations a mint payment needs, before tokens are issued
+		MinConfirmations int `json:"min_confirmations" yaml:"min_confirmations"`
+		// ConfTarget in blocks used for fee estimation and melt payments
+		ConfTarget int             `json:"conf_target" yaml:"conf_target"`
+		Bitcoind   *BitcoindConfig `json:"bitcoind" yaml:"bitcoind"`
+	} `json:"onchain" yaml:"onchain"`
+}
+
+type BitcoindConfig struct {
+	Url      string `yaml:"url"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// Wallet is the name of the bitcoind wallet, that receives mint payments and signs melt payments.
+	// The wallet needs the private keys of the descriptor, watch-only wallets can not send melt payments.
+	Wallet string `yaml:"wallet"`
+	// Descriptor is the public ranged output descriptor of the mints receive addresses
+	Descriptor string `yaml:"descriptor"`
+}
+
+var Config Configuration
+
+const name = "config.yaml"
+
+func init() {
+	if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+		// no on-chain configuration found. starting mint without on-chain support.
+		Config.Onchain.Enabled = false
+	} else {
+		c := configor.New(&configor.Config{Silent: true})
+		err = c.Load(&Config, name)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// NetParams returns the chain parameters of the configured network.
+func NetParams() *chaincfg.Params {
+	switch Config.Onchain.Network {
+	case "testnet":
+		return &chaincfg.TestNet3Params
+	case "signet":
+		return &chaincfg.SigNetParams
+	case "regtest":
+		return &chaincfg.RegressionNetParams
+	}
+	return &chaincfg.MainNetParams
+}
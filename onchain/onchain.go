@@ -0,0 +1,35 @@
+package onchain
+
+// Client should be able to receive and send on-chain bitcoin payments.
+// Addresses are derived from a descriptor, that is watched by the backend.
+type Client interface {
+	DeriveAddress(index uint32) (string, error)                             // DeriveAddress should return the receive address at index of the watched descriptor
+	ReceivedByAddress(address string, minConfirmations int) (uint64, error) // ReceivedByAddress should return the amount in satoshi received by address with at least minConfirmations
+	EstimateFee(address string, amount uint64) (uint64, error)              // EstimateFee should return the estimated fee in satoshi to send amount to address
+	Send(address string, amount, maxFee uint64) (string, error)             // Send should send amount satoshi to address, if the fee does not exceed maxFee satoshi, and return the transaction id
+}
+
+// BalanceReporter is implemented by on-chain backends, that can report the balance of their wallet.
+type BalanceReporter interface {
+	Balance(minConfirmations int) (uint64, error) // Balance should return the balance in satoshi of outputs with at least minConfirmations
+}
+
+// Payment is an on-chain melt payment.
+type Payment struct {
+	Txid string `json:"txid"`
+}
+
+// IsPaid returns true, because on-chain payments are paid as soon as the transaction was broadcast.
+func (p Payment) IsPaid() bool {
+	return true
+}
+
+// GetPreimage returns an empty preimage. on-chain payments do not have preimages.
+func (p Payment) GetPreimage() string {
+	return ""
+}
+
+// GetTxid returns the id of the payment transaction.
+func (p Payment) GetTxid() string {
+	return p.Txid
+}